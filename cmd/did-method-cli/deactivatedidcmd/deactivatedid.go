@@ -109,12 +109,18 @@ func deactivateDIDCmd() *cobra.Command {
 				return err
 			}
 
+			var acceptedEndpoint string
+
+			opts = append(opts, deactivate.WithAcceptedEndpointHandler(func(endpointURL string) {
+				acceptedEndpoint = endpointURL
+			}))
+
 			err = client.DeactivateDID(didURI, domain, opts...)
 			if err != nil {
 				return fmt.Errorf("failed to deactivate did: %w", err)
 			}
 
-			fmt.Printf("successfully deactivated DID %s", didURI)
+			fmt.Printf("successfully deactivated DID %s at sidetree endpoint %s", didURI, acceptedEndpoint)
 
 			return nil
 		},
@@ -142,12 +142,18 @@ func recoverDIDCmd() *cobra.Command {
 				return err
 			}
 
+			var acceptedEndpoint string
+
+			opts = append(opts, recovery.WithAcceptedEndpointHandler(func(endpointURL string) {
+				acceptedEndpoint = endpointURL
+			}))
+
 			err = client.RecoverDID(didURI, domain, opts...)
 			if err != nil {
 				return fmt.Errorf("failed to recover did: %w", err)
 			}
 
-			fmt.Printf("successfully recoverd DID %s", didURI)
+			fmt.Printf("successfully recoverd DID %s at sidetree endpoint %s", didURI, acceptedEndpoint)
 
 			return nil
 		},
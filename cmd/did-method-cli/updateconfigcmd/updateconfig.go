@@ -6,8 +6,6 @@ SPDX-License-Identifier: Apache-2.0
 package updateconfigcmd
 
 import (
-	"crypto"
-	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"os"
@@ -19,6 +17,7 @@ import (
 	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
 
 	"github.com/trustbloc/trustbloc-did-method/cmd/did-method-cli/internal/configcommon"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/historyhash"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
@@ -60,7 +59,8 @@ func createUpdateConfigCmd() *cobra.Command {
 				return err
 			}
 
-			hash, err := moveToHistory(parameters.prevConfig, path.Join(parameters.outputDirectory, "did-trustbloc", "history"))
+			hash, err := moveToHistory(parameters.prevConfig, path.Join(parameters.outputDirectory, "did-trustbloc", "history"),
+				parameters.config.ConsortiumData.Policy.HistoryHash)
 			if err != nil {
 				return err
 			}
@@ -95,7 +95,9 @@ func getParameters(cmd *cobra.Command) (*parameters, error) {
 	return parameters, nil
 }
 
-func moveToHistory(filePath, historyDirectory string) (string, error) {
+// moveToHistory moves the given config file into the history directory, naming it by its hash computed using the
+// given history hash algorithm
+func moveToHistory(filePath, historyDirectory, hashAlgorithm string) (string, error) {
 	if historyDirectory != "" {
 		if err := os.MkdirAll(historyDirectory, 0755); err != nil { //nolint: gosec
 			return "", err
@@ -107,16 +109,11 @@ func moveToHistory(filePath, historyDirectory string) (string, error) {
 		return "", err
 	}
 
-	sha := crypto.SHA256.New()
-
-	_, err = sha.Write(fileBytes)
+	hash, err := historyhash.NewRegistry().Hash(hashAlgorithm, fileBytes)
 	if err != nil {
 		return "", err
 	}
 
-	sum := sha.Sum(nil)
-	hash := base64.RawURLEncoding.EncodeToString(sum)
-
 	err = ioutil.WriteFile(filepath.Join(historyDirectory, hash+".json"), fileBytes, 0600)
 
 	if err != nil {
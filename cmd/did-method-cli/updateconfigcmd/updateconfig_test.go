@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/trustbloc-did-method/cmd/did-method-cli/internal/configcommon"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/historyhash"
 )
 
 const (
@@ -229,7 +230,8 @@ func TestUpdateConfigCmd(t *testing.T) {
 		originalFile, err := ioutil.ReadFile(filepath.Clean(dir + "/did-trustbloc/consortium.net.json"))
 		require.NoError(t, err)
 
-		hash, err := moveToHistory(dir+"/did-trustbloc/consortium.net.json", dir+"/history/")
+		hash, err := moveToHistory(dir+"/did-trustbloc/consortium.net.json", dir+"/history/",
+			c.ConsortiumData.Policy.HistoryHash)
 		require.NoError(t, err)
 		_, err = os.Stat(dir + "/history/" + hash + ".json")
 		require.False(t, os.IsNotExist(err))
@@ -238,6 +240,37 @@ func TestUpdateConfigCmd(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, originalFile, historyFile)
+		require.NoError(t, historyhash.NewRegistry().Verify(historyhash.SHA256, hash, historyFile))
+	})
+
+	t.Run("test move config to history dir using policy history hash", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+
+		defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+		require.NoError(t, ioutil.WriteFile(dir+"/config.json", []byte("config data"), 0600))
+
+		hash, err := moveToHistory(dir+"/config.json", dir+"/history/", historyhash.MultihashSHA512)
+		require.NoError(t, err)
+
+		historyFile, err := ioutil.ReadFile(filepath.Clean(dir + "/history/" + hash + ".json"))
+		require.NoError(t, err)
+
+		require.NoError(t, historyhash.NewRegistry().Verify(historyhash.MultihashSHA512, hash, historyFile))
+	})
+
+	t.Run("test move config to history dir with unsupported history hash", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+
+		defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+		require.NoError(t, ioutil.WriteFile(dir+"/config.json", []byte("config data"), 0600))
+
+		_, err = moveToHistory(dir+"/config.json", dir+"/history/", "MD5")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not supported")
 	})
 }
 
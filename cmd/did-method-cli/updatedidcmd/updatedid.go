@@ -140,12 +140,18 @@ func updateDIDCmd() *cobra.Command {
 				return err
 			}
 
+			var acceptedEndpoint string
+
+			opts = append(opts, update.WithAcceptedEndpointHandler(func(endpointURL string) {
+				acceptedEndpoint = endpointURL
+			}))
+
 			err = client.UpdateDID(didURI, domain, opts...)
 			if err != nil {
 				return fmt.Errorf("failed to update did: %w", err)
 			}
 
-			fmt.Printf("successfully updated DID %s", didURI)
+			fmt.Printf("successfully updated DID %s at sidetree endpoint %s", didURI, acceptedEndpoint)
 
 			return nil
 		},
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
@@ -23,6 +24,7 @@ import (
 	"github.com/trustbloc/trustbloc-did-method/pkg/restapi/didmethod/operation"
 	"github.com/trustbloc/trustbloc-did-method/pkg/restapi/healthcheck"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 const (
@@ -74,6 +76,40 @@ const (
 	genesisFileEnvKey    = "GENESIS_FILES"
 	genesisFileFlagUsage = "Comma-separated list of consortium config genesis file paths." +
 		" Alternatively, this can be set with the following environment variable: " + genesisFileEnvKey
+
+	retryMaxAttemptsFlagName  = "retry-max-attempts"
+	retryMaxAttemptsEnvKey    = "RETRY_MAX_ATTEMPTS"
+	retryMaxAttemptsFlagUsage = "Maximum number of attempts for consortium, stakeholder and did-configuration" +
+		" requests, including the first. Set to 1 to disable retries. Defaults to 3 if not set." +
+		" Alternatively, this can be set with the following environment variable: " + retryMaxAttemptsEnvKey
+
+	retryMaxElapsedFlagName  = "retry-max-elapsed"
+	retryMaxElapsedEnvKey    = "RETRY_MAX_ELAPSED"
+	retryMaxElapsedFlagUsage = "Time budget for a request and all its retries, e.g. 10s. Defaults to 10s if not set." +
+		" Alternatively, this can be set with the following environment variable: " + retryMaxElapsedEnvKey
+
+	retryInitialBackoffFlagName  = "retry-initial-backoff"
+	retryInitialBackoffEnvKey    = "RETRY_INITIAL_BACKOFF"
+	retryInitialBackoffFlagUsage = "Backoff before the first retry, doubling for each further retry, e.g. 200ms." +
+		" Defaults to 200ms if not set." +
+		" Alternatively, this can be set with the following environment variable: " + retryInitialBackoffEnvKey
+
+	retryMaxBackoffFlagName  = "retry-max-backoff"
+	retryMaxBackoffEnvKey    = "RETRY_MAX_BACKOFF"
+	retryMaxBackoffFlagUsage = "Maximum backoff between retries, e.g. 2s. Defaults to 2s if not set." +
+		" Alternatively, this can be set with the following environment variable: " + retryMaxBackoffEnvKey
+
+	cacheDirFlagName  = "cache-dir"
+	cacheDirEnvKey    = "DID_METHOD_CACHE_DIR"
+	cacheDirFlagUsage = "Directory caching verified consortium and stakeholder configs across restarts." +
+		" If not set, configs are only cached in memory." +
+		" Alternatively, this can be set with the following environment variable: " + cacheDirEnvKey
+
+	enableCacheAdminFlagName  = "enable-cache-admin"
+	enableCacheAdminEnvKey    = "ENABLE_CACHE_ADMIN"
+	enableCacheAdminFlagUsage = "Enable the /admin/cache endpoints listing and evicting cached configs." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + enableCacheAdminEnvKey
 )
 
 // mode in which to run the did-method service
@@ -108,6 +144,9 @@ type parameters struct {
 	sidetreeWriteToken string
 	enableSignatures   bool
 	genesisFiles       []string
+	retryPolicy        *retry.Policy
+	cacheDir           string
+	enableCacheAdmin   bool
 }
 
 // GetStartCmd returns the Cobra start command.
@@ -168,15 +207,19 @@ func getParameters(cmd *cobra.Command) (*parameters, error) {
 	sidetreeWriteToken := cmdutils.GetUserSetOptionalVarFromString(cmd, sidetreeWriteTokenFlagName,
 		sidetreeWriteTokenEnvKey)
 
-	enableSignaturesString := cmdutils.GetUserSetOptionalVarFromString(cmd, enableSignaturesFlagName,
-		enableSignaturesEnvKey)
+	enableSignatures, err := getBool(cmd, enableSignaturesFlagName, enableSignaturesEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
 
-	enableSignatures := true
-	if enableSignaturesString != "" {
-		enableSignatures, err = strconv.ParseBool(enableSignaturesString)
-		if err != nil {
-			return nil, err
-		}
+	enableCacheAdmin, err := getBool(cmd, enableCacheAdminFlagName, enableCacheAdminEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	retryPolicy, err := getRetryPolicy(cmd)
+	if err != nil {
+		return nil, err
 	}
 
 	return &parameters{
@@ -189,9 +232,72 @@ func getParameters(cmd *cobra.Command) (*parameters, error) {
 		sidetreeWriteToken: sidetreeWriteToken,
 		enableSignatures:   enableSignatures,
 		genesisFiles:       genesisFiles,
+		retryPolicy:        retryPolicy,
+		cacheDir:           cmdutils.GetUserSetOptionalVarFromString(cmd, cacheDirFlagName, cacheDirEnvKey),
+		enableCacheAdmin:   enableCacheAdmin,
 	}, nil
 }
 
+// getBool returns the boolean parameter set by the user, or the default value if it isn't set
+func getBool(cmd *cobra.Command, flagName, envKey string, defaultValue bool) (bool, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, flagName, envKey)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// getRetryPolicy returns the default retry policy updated with the retry parameters set by the user,
+// or nil if none are set
+func getRetryPolicy(cmd *cobra.Command) (*retry.Policy, error) {
+	maxAttempts := cmdutils.GetUserSetOptionalVarFromString(cmd, retryMaxAttemptsFlagName, retryMaxAttemptsEnvKey)
+	maxElapsed := cmdutils.GetUserSetOptionalVarFromString(cmd, retryMaxElapsedFlagName, retryMaxElapsedEnvKey)
+	initialBackoff := cmdutils.GetUserSetOptionalVarFromString(cmd, retryInitialBackoffFlagName,
+		retryInitialBackoffEnvKey)
+	maxBackoff := cmdutils.GetUserSetOptionalVarFromString(cmd, retryMaxBackoffFlagName, retryMaxBackoffEnvKey)
+
+	if maxAttempts == "" && maxElapsed == "" && initialBackoff == "" && maxBackoff == "" {
+		return nil, nil
+	}
+
+	policy := retry.Default()
+
+	if maxAttempts != "" {
+		attempts, err := strconv.Atoi(maxAttempts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", retryMaxAttemptsFlagName, err)
+		}
+
+		policy.MaxAttempts = attempts
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{retryMaxElapsedFlagName, maxElapsed, &policy.MaxElapsed},
+		{retryInitialBackoffFlagName, initialBackoff, &policy.InitialBackoff},
+		{retryMaxBackoffFlagName, maxBackoff, &policy.MaxBackoff},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+
+		*d.dest = duration
+	}
+
+	return policy, nil
+}
+
 func getTLS(cmd *cobra.Command) (bool, []string, error) {
 	tlsSystemCertPoolString := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsSystemCertPoolFlagName,
 		tlsSystemCertPoolEnvKey)
@@ -238,6 +344,12 @@ func createFlags(startCmd *cobra.Command) {
 	startCmd.Flags().StringP(sidetreeWriteTokenFlagName, "", "", sidetreeWriteTokenFlagUsage)
 	startCmd.Flags().StringP(enableSignaturesFlagName, "", "", enableSignaturesFlagUsage)
 	startCmd.Flags().StringArray(genesisFileFlagName, nil, genesisFileFlagUsage)
+	startCmd.Flags().StringP(retryMaxAttemptsFlagName, "", "", retryMaxAttemptsFlagUsage)
+	startCmd.Flags().StringP(retryMaxElapsedFlagName, "", "", retryMaxElapsedFlagUsage)
+	startCmd.Flags().StringP(retryInitialBackoffFlagName, "", "", retryInitialBackoffFlagUsage)
+	startCmd.Flags().StringP(retryMaxBackoffFlagName, "", "", retryMaxBackoffFlagUsage)
+	startCmd.Flags().StringP(cacheDirFlagName, "", "", cacheDirFlagUsage)
+	startCmd.Flags().StringP(enableCacheAdminFlagName, "", "", enableCacheAdminFlagUsage)
 }
 
 func startDidMethod(parameters *parameters) error {
@@ -254,7 +366,9 @@ func startDidMethod(parameters *parameters) error {
 	didMethodService, err := didmethod.New(&operation.Config{TLSConfig: &tls.Config{RootCAs: rootCAs,
 		MinVersion: tls.VersionTLS12}, BlocDomain: parameters.blocDomain, Mode: parameters.mode,
 		SidetreeReadToken: parameters.sidetreeReadToken, SidetreeWriteToken: parameters.sidetreeWriteToken,
-		EnableSignatures: parameters.enableSignatures, GenesisFiles: genesisFiles})
+		EnableSignatures: parameters.enableSignatures, GenesisFiles: genesisFiles,
+		RetryPolicy: parameters.retryPolicy, CacheDir: parameters.cacheDir,
+		EnableCacheAdmin: parameters.enableCacheAdmin})
 	if err != nil {
 		return err
 	}
@@ -11,9 +11,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 const flag = "--"
@@ -79,6 +82,114 @@ func TestStartCmdWithInvalidEnableSignaturesArg(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestStartCmdWithCacheDirArg(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	dir := t.TempDir()
+
+	args := getValidArgs()
+	args = append(args, flag+cacheDirFlagName, dir)
+
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.NoError(t, err)
+
+	parameters, err := getParameters(startCmd)
+	require.NoError(t, err)
+	require.Equal(t, dir, parameters.cacheDir)
+}
+
+func TestStartCmdWithEnableCacheAdminArg(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := getValidArgs()
+		args = append(args, flag+enableCacheAdminFlagName, "true")
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+
+		parameters, err := getParameters(startCmd)
+		require.NoError(t, err)
+		require.True(t, parameters.enableCacheAdmin)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := getValidArgs()
+		args = append(args, flag+enableCacheAdminFlagName, "aaaaaa")
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+	})
+}
+
+func TestStartCmdWithRetryArgs(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := getValidArgs()
+		args = append(args, flag+retryMaxAttemptsFlagName, "5", flag+retryMaxElapsedFlagName, "1m",
+			flag+retryInitialBackoffFlagName, "1s", flag+retryMaxBackoffFlagName, "10s")
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.NoError(t, err)
+	})
+
+	t.Run("policy from args", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		err := startCmd.ParseFlags([]string{flag + retryMaxAttemptsFlagName, "5", flag + retryMaxBackoffFlagName, "10s"})
+		require.NoError(t, err)
+
+		policy, err := getRetryPolicy(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, 5, policy.MaxAttempts)
+		require.Equal(t, 10*time.Second, policy.MaxBackoff)
+		require.Equal(t, retry.Default().InitialBackoff, policy.InitialBackoff)
+
+		startCmd = GetStartCmd(&mockServer{})
+
+		policy, err = getRetryPolicy(startCmd)
+		require.NoError(t, err)
+		require.Nil(t, policy)
+	})
+
+	t.Run("invalid max attempts", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := getValidArgs()
+		args = append(args, flag+retryMaxAttemptsFlagName, "many")
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid retry-max-attempts")
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := getValidArgs()
+		args = append(args, flag+retryMaxBackoffFlagName, "long")
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid retry-max-backoff")
+	})
+}
+
 func TestStartCmdWithGenesisFile(t *testing.T) {
 	const (
 		// nolint: lll
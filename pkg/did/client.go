@@ -7,9 +7,8 @@ package did
 
 import (
 	"bytes"
+	"context"
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/ed25519"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -18,44 +17,60 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
+	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	log "github.com/sirupsen/logrus"
 	"github.com/square/go-jose/v3"
 	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
 	"github.com/trustbloc/sidetree-core-go/pkg/jws"
 	"github.com/trustbloc/sidetree-core-go/pkg/patch"
-	"github.com/trustbloc/sidetree-core-go/pkg/util/ecsigner"
-	"github.com/trustbloc/sidetree-core-go/pkg/util/edsigner"
 	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
 	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/client"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/doc"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/create"
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/deactivate"
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/recovery"
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/update"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/signer"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/httpconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/memorycacheconfig"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/discovery/staticdiscovery"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/endpoint"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/httpclient"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/selection/staticselection"
 )
 
 type endpointService interface {
-	GetEndpoints(domain string) ([]*models.Endpoint, error)
+	GetEndpointsWithContext(ctx context.Context, domain string) ([]*models.Endpoint, error)
+}
+
+type discoveryService interface {
+	GetEndpointsWithContext(ctx context.Context, domain string) ([]*models.Endpoint, error)
 }
 
 type configService interface {
-	GetSidetreeConfig(url string) (*models.SidetreeConfig, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
 }
 
 // Client for did bloc
 type Client struct {
-	endpointService endpointService
-	client          *http.Client
-	tlsConfig       *tls.Config
-	authToken       string
-	configService   configService
+	endpointService  endpointService
+	discoveryService discoveryService
+	client           *http.Client
+	tlsConfig        *tls.Config
+	authToken        string
+	httpClientOpts   []httpclient.Option
+	configService    configService
+	keyLifecycle     *KeyLifecycle
+	consensusPolicy  consensus.Policy
+
+	publishTimeout      time.Duration
+	publishPollInterval time.Duration
 }
 
 type didResolution struct {
@@ -67,32 +82,121 @@ type didResolution struct {
 
 // New return did bloc client
 func New(opts ...Option) *Client {
-	c := &Client{client: &http.Client{}}
+	c := &Client{}
 
 	// Apply options
 	for _, opt := range opts {
 		opt(c)
 	}
 
-	c.client.Transport = &http.Transport{TLSClientConfig: c.tlsConfig}
-	configService := memorycacheconfig.NewService(httpconfig.NewService(httpconfig.WithTLSConfig(c.tlsConfig)))
+	// sidetree operations and config fetches are sent with the same client, so they have the same auth token, headers
+	// and proxy
+	c.client = httpclient.New(append([]httpclient.Option{
+		httpclient.WithTLSConfig(c.tlsConfig), httpclient.WithAuthToken(c.authToken),
+	}, c.httpClientOpts...)...)
+	configService := memorycacheconfig.NewService(httpconfig.NewService(httpconfig.WithHTTPClient(c.client)))
 	c.configService = configService
-	c.endpointService = endpoint.NewService(
-		staticdiscovery.NewService(configService),
-		staticselection.NewService(configService))
+	c.discoveryService = staticdiscovery.NewService(configService)
+	c.endpointService = endpoint.NewService(c.discoveryService, staticselection.NewService(configService))
 
 	return c
 }
 
-// UpdateDID update did doc
+// CreateDID calls CreateDIDWithContext with a background context
+func (c *Client) CreateDID(domain string, opts ...create.Option,
+) (*docdid.DocResolution, *model.SuffixDataModel, error) {
+	return c.CreateDIDWithContext(context.Background(), domain, opts...)
+}
+
+// CreateDIDWithContext create did doc.
+// Returns the resolution of the created DID and the suffix data of the create operation, from which the DID's unique
+// suffix is derived.
+func (c *Client) CreateDIDWithContext(ctx context.Context, domain string, opts ...create.Option,
+) (*docdid.DocResolution, *model.SuffixDataModel, error) {
+	createDIDOpts := &create.Opts{}
+	// Apply options
+	for _, opt := range opts {
+		opt(createDIDOpts)
+	}
+
+	if createDIDOpts.RecoveryPublicKey == nil {
+		return nil, nil, fmt.Errorf("recovery public key is required")
+	}
+
+	if createDIDOpts.UpdatePublicKey == nil {
+		return nil, nil, fmt.Errorf("update public key is required")
+	}
+
+	endpoints, err := c.getEndpoints(ctx, domain, createDIDOpts.SidetreeEndpoints)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var suffixData *model.SuffixDataModel
+
+	accepted, response, err := c.sendOperation(ctx, "create", domain, endpoints,
+		func(sidetreeConfig *models.SidetreeConfig) ([]byte, error) {
+			req, sd, e := buildCreateRequest(sidetreeConfig, createDIDOpts)
+			if e != nil {
+				return nil, fmt.Errorf("failed to build sidetree request: %w", e)
+			}
+
+			suffixData = sd
+
+			return req, nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	docResolution, err := parseDocResolution(response)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if createDIDOpts.AcceptedEndpointHandler != nil {
+		createDIDOpts.AcceptedEndpointHandler(accepted)
+	}
+
+	published, err := c.waitUntilPublished(ctx, domain, accepted, docResolution.DIDDocument.ID, createPublished)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if published != nil {
+		docResolution = published
+	}
+
+	return docResolution, suffixData, nil
+}
+
+// UpdateDID calls UpdateDIDWithContext with a background context
 func (c *Client) UpdateDID(did, domain string, opts ...update.Option) error {
+	return c.UpdateDIDWithContext(context.Background(), did, domain, opts...)
+}
+
+// UpdateDIDWithContext update did doc
+func (c *Client) UpdateDIDWithContext(ctx context.Context, did, domain string, opts ...update.Option) error {
 	updateDIDOpts := &update.Opts{}
 	// Apply options
 	for _, opt := range opts {
 		opt(updateDIDOpts)
 	}
 
-	if updateDIDOpts.SigningKey == nil {
+	rotation, err := c.prepareRotation(ctx, did, domain, updateDIDOpts.SidetreeEndpoints,
+		updateDIDOpts.SigningKey != nil || updateDIDOpts.Signer != nil, (*KeyLifecycle).prepareUpdate)
+	if err != nil {
+		return err
+	}
+
+	defer rotation.release()
+
+	if rotation != nil {
+		updateDIDOpts.SigningKey = rotation.signingKey
+		updateDIDOpts.NextUpdatePublicKey = rotation.nextUpdateKey.Public()
+	}
+
+	if updateDIDOpts.SigningKey == nil && updateDIDOpts.Signer == nil {
 		return fmt.Errorf("signing public key is required")
 	}
 
@@ -100,98 +204,281 @@ func (c *Client) UpdateDID(did, domain string, opts ...update.Option) error {
 		return fmt.Errorf("next update public key is required")
 	}
 
-	sidetreeEndpoint, err := c.getEndpoint(domain, updateDIDOpts.SidetreeEndpoints)
+	endpoints, err := c.getEndpoints(ctx, domain, updateDIDOpts.SidetreeEndpoints)
 	if err != nil {
 		return err
 	}
 
-	sidetreeConfig, err := c.configService.GetSidetreeConfig(sidetreeEndpoint)
-	if err != nil {
-		return err
-	}
+	var lastRequest []byte
 
-	req, err := c.buildUpdateRequest(did, sidetreeConfig, updateDIDOpts)
+	accepted, err := c.sendRotatedOperation(ctx, "update", domain, endpoints, rotation,
+		func(revealValue string) { updateDIDOpts.RevealValue = revealValue },
+		func(sidetreeConfig *models.SidetreeConfig) ([]byte, error) {
+			req, e := c.buildUpdateRequest(did, sidetreeConfig, updateDIDOpts)
+			if e != nil {
+				return nil, fmt.Errorf("failed to build update request: %w", e)
+			}
+
+			lastRequest = req
+
+			return req, nil
+		})
 	if err != nil {
-		return fmt.Errorf("failed to build update request: %w", err)
+		return err
 	}
 
-	_, err = c.sendRequest(req, sidetreeEndpoint)
-	if err != nil {
-		return fmt.Errorf("failed to send create sidetree request: %w", err)
+	if updateDIDOpts.AcceptedEndpointHandler != nil {
+		updateDIDOpts.AcceptedEndpointHandler(accepted)
 	}
 
-	return nil
+	return c.waitUntilCommitmentPublished(ctx, domain, accepted, did, lastRequest)
 }
 
-// RecoverDID recover did doc
+// RecoverDID calls RecoverDIDWithContext with a background context
 func (c *Client) RecoverDID(did, domain string, opts ...recovery.Option) error {
+	return c.RecoverDIDWithContext(context.Background(), did, domain, opts...)
+}
+
+// RecoverDIDWithContext recover did doc
+func (c *Client) RecoverDIDWithContext(ctx context.Context, did, domain string, opts ...recovery.Option) error {
 	recoverDIDOpts := &recovery.Opts{}
 	// Apply options
 	for _, opt := range opts {
 		opt(recoverDIDOpts)
 	}
 
-	err := validateRecoverReq(recoverDIDOpts)
+	rotation, err := c.prepareRotation(ctx, did, domain, recoverDIDOpts.SidetreeEndpoints,
+		recoverDIDOpts.SigningKey != nil || recoverDIDOpts.Signer != nil, (*KeyLifecycle).prepareRecover)
 	if err != nil {
 		return err
 	}
 
-	sidetreeEndpoint, err := c.getEndpoint(domain, recoverDIDOpts.SidetreeEndpoints)
+	defer rotation.release()
+
+	if rotation != nil {
+		recoverDIDOpts.SigningKey = rotation.signingKey
+		recoverDIDOpts.NextUpdatePublicKey = rotation.nextUpdateKey.Public()
+		recoverDIDOpts.NextRecoveryPublicKey = rotation.nextRecoveryKey.Public()
+	}
+
+	err = validateRecoverReq(recoverDIDOpts)
 	if err != nil {
 		return err
 	}
 
-	sidetreeConfig, err := c.configService.GetSidetreeConfig(sidetreeEndpoint)
+	endpoints, err := c.getEndpoints(ctx, domain, recoverDIDOpts.SidetreeEndpoints)
 	if err != nil {
 		return err
 	}
 
-	req, err := buildRecoverRequest(did, sidetreeConfig, recoverDIDOpts)
+	var lastRequest []byte
+
+	accepted, err := c.sendRotatedOperation(ctx, "recover", domain, endpoints, rotation,
+		func(revealValue string) { recoverDIDOpts.RevealValue = revealValue },
+		func(sidetreeConfig *models.SidetreeConfig) ([]byte, error) {
+			req, e := buildRecoverRequest(did, sidetreeConfig, recoverDIDOpts)
+			if e != nil {
+				return nil, fmt.Errorf("failed to build sidetree request: %w", e)
+			}
+
+			lastRequest = req
+
+			return req, nil
+		})
 	if err != nil {
-		return fmt.Errorf("failed to build sidetree request: %w", err)
+		return err
 	}
 
-	_, err = c.sendRequest(req, sidetreeEndpoint)
-	if err != nil {
-		return fmt.Errorf("failed to send recover sidetree request: %w", err)
+	if recoverDIDOpts.AcceptedEndpointHandler != nil {
+		recoverDIDOpts.AcceptedEndpointHandler(accepted)
 	}
 
-	return err
+	return c.waitUntilCommitmentPublished(ctx, domain, accepted, did, lastRequest)
 }
 
-// DeactivateDID deactivate did doc
+// DeactivateDID calls DeactivateDIDWithContext with a background context
 func (c *Client) DeactivateDID(did, domain string, opts ...deactivate.Option) error {
+	return c.DeactivateDIDWithContext(context.Background(), did, domain, opts...)
+}
+
+// DeactivateDIDWithContext deactivate did doc
+func (c *Client) DeactivateDIDWithContext(ctx context.Context, did, domain string, opts ...deactivate.Option) error {
 	deactivateDIDOpts := &deactivate.Opts{}
 	// Apply options
 	for _, opt := range opts {
 		opt(deactivateDIDOpts)
 	}
 
-	if deactivateDIDOpts.SigningKey == nil {
+	rotation, err := c.prepareRotation(ctx, did, domain, deactivateDIDOpts.SidetreeEndpoints,
+		deactivateDIDOpts.SigningKey != nil || deactivateDIDOpts.Signer != nil, (*KeyLifecycle).prepareDeactivate)
+	if err != nil {
+		return err
+	}
+
+	defer rotation.release()
+
+	if rotation != nil {
+		deactivateDIDOpts.SigningKey = rotation.signingKey
+	}
+
+	if deactivateDIDOpts.SigningKey == nil && deactivateDIDOpts.Signer == nil {
 		return fmt.Errorf("signing key is required")
 	}
 
-	sidetreeEndpoint, err := c.getEndpoint(domain, deactivateDIDOpts.SidetreeEndpoints)
+	endpoints, err := c.getEndpoints(ctx, domain, deactivateDIDOpts.SidetreeEndpoints)
 	if err != nil {
 		return err
 	}
 
-	sidetreeConfig, err := c.configService.GetSidetreeConfig(sidetreeEndpoint)
+	accepted, err := c.sendRotatedOperation(ctx, "deactivate", domain, endpoints, rotation,
+		func(revealValue string) { deactivateDIDOpts.RevealValue = revealValue },
+		func(sidetreeConfig *models.SidetreeConfig) ([]byte, error) {
+			req, e := buildDeactivateRequest(did, sidetreeConfig, deactivateDIDOpts)
+			if e != nil {
+				return nil, fmt.Errorf("failed to build sidetree request: %w", e)
+			}
+
+			return req, nil
+		})
 	if err != nil {
 		return err
 	}
 
-	req, err := buildDeactivateRequest(did, sidetreeConfig, deactivateDIDOpts)
+	if deactivateDIDOpts.AcceptedEndpointHandler != nil {
+		deactivateDIDOpts.AcceptedEndpointHandler(accepted)
+	}
+
+	_, err = c.waitUntilPublished(ctx, domain, accepted, did, deactivatePublished)
+
+	return err
+}
+
+// prepareRotation prepares the key rotation of an operation on the DID, if the client manages the DID's keys and the
+// operation's signing key or signer wasn't given. Returns nil if the operation's keys aren't managed. Other operations
+// on the DID wait until the returned rotation is released.
+func (c *Client) prepareRotation(ctx context.Context, did, domain string, sidetreeEndpoints []*models.Endpoint,
+	signed bool, prepare func(k *KeyLifecycle, did string, state *KeyState) (*keyRotation, error),
+) (*keyRotation, error) {
+	if c.keyLifecycle == nil || signed {
+		return nil, nil
+	}
+
+	unlock := c.keyLifecycle.lock(did)
+
+	state, err := c.keyLifecycle.store.Get(did)
+	if err != nil {
+		unlock()
+
+		return nil, fmt.Errorf("failed to get managed keys of %s: %w", did, err)
+	}
+
+	if state.PendingUpdateKey != nil || state.PendingRecoveryKey != nil {
+		state, err = c.reconcilePendingKeys(ctx, did, domain, sidetreeEndpoints, state)
+		if err != nil {
+			unlock()
+
+			return nil, fmt.Errorf("failed to reconcile pending keys of %s: %w", did, err)
+		}
+	}
+
+	rotation, err := prepare(c.keyLifecycle, did, state)
 	if err != nil {
-		return fmt.Errorf("failed to build sidetree request: %w", err)
+		unlock()
+
+		return nil, fmt.Errorf("failed to get managed keys of %s: %w", did, err)
 	}
 
-	_, err = c.sendRequest(req, sidetreeEndpoint)
+	rotation.unlock = unlock
+
+	return rotation, nil
+}
+
+// reconcilePendingKeys settles the DID's pending keys, left by an operation whose outcome isn't known, against the
+// commitments the DID resolves to at the first of the operation's endpoints that resolves it
+func (c *Client) reconcilePendingKeys(ctx context.Context, did, domain string, sidetreeEndpoints []*models.Endpoint,
+	state *KeyState) (*KeyState, error) {
+	endpoints, err := c.getEndpoints(ctx, domain, sidetreeEndpoints)
 	if err != nil {
-		return fmt.Errorf("failed to send deactivate sidetree request: %w", err)
+		return nil, err
 	}
 
-	return err
+	for _, e := range endpoints {
+		docResolution, status, err := c.resolveAtEndpoint(ctx, e.URL, did)
+		if err != nil || status != http.StatusOK || docResolution.DocumentMetadata == nil ||
+			docResolution.DocumentMetadata.Method == nil {
+			log.Debugf("endpoint %s didn't resolve the commitments of %s: status %d: %v", e.URL, did, status, err)
+
+			continue
+		}
+
+		sidetreeConfig, err := c.configService.GetSidetreeConfigWithContext(ctx, e.URL)
+		if err != nil {
+			log.Debugf("failed to get sidetree config of %s: %s", e.URL, err)
+
+			continue
+		}
+
+		method := docResolution.DocumentMetadata.Method
+
+		return c.keyLifecycle.reconcile(did, state, method.UpdateCommitment, method.RecoveryCommitment,
+			sidetreeConfig.MultiHashAlgorithm)
+	}
+
+	return nil, errors.New("no endpoint resolved the DID's published commitments")
+}
+
+// sendRotatedOperation sends an operation signed with the rotation's signing key, saving the keys it commits to as
+// pending before it is sent and promoting them once it is accepted. Returns the url of the endpoint that accepted it.
+func (c *Client) sendRotatedOperation(ctx context.Context, operation, domain string, endpoints []*models.Endpoint,
+	rotation *keyRotation, setRevealValue func(revealValue string),
+	build func(sidetreeConfig *models.SidetreeConfig) ([]byte, error)) (string, error) {
+	err := c.savePendingKeys(rotation)
+	if err != nil {
+		return "", err
+	}
+
+	accepted, _, err := c.sendOperation(ctx, operation, domain, endpoints, rotation.builder(setRevealValue, build))
+	if err != nil {
+		return "", err
+	}
+
+	return accepted, c.commitRotation(ctx, rotation, accepted)
+}
+
+// savePendingKeys saves the keys the rotation's operation commits to as pending, before the operation is sent
+func (c *Client) savePendingKeys(rotation *keyRotation) error {
+	if rotation == nil {
+		return nil
+	}
+
+	err := c.keyLifecycle.savePending(rotation)
+	if err != nil {
+		return fmt.Errorf("failed to save pending keys of %s: %w", rotation.did, err)
+	}
+
+	return nil
+}
+
+// commitRotation promotes the pending keys to the current keys after the rotation's operation was accepted by the
+// endpoint at the given url, committing to them with the endpoint's multihash algorithm
+func (c *Client) commitRotation(ctx context.Context, rotation *keyRotation, accepted string) error {
+	if rotation == nil {
+		return nil
+	}
+
+	sidetreeConfig, err := c.configService.GetSidetreeConfigWithContext(ctx, accepted)
+	if err != nil {
+		return fmt.Errorf("operation on %s accepted but failed to promote its pending keys: %w", rotation.did, err)
+	}
+
+	rotation.multihashCode = sidetreeConfig.MultiHashAlgorithm
+
+	err = c.keyLifecycle.commit(rotation)
+	if err != nil {
+		return fmt.Errorf("operation on %s accepted but failed to promote its pending keys: %w", rotation.did, err)
+	}
+
+	return nil
 }
 
 func validateRecoverReq(recoverDIDOpts *recovery.Opts) error {
@@ -203,35 +490,148 @@ func validateRecoverReq(recoverDIDOpts *recovery.Opts) error {
 		return fmt.Errorf("next update public key is required")
 	}
 
-	if recoverDIDOpts.SigningKey == nil {
+	if recoverDIDOpts.SigningKey == nil && recoverDIDOpts.Signer == nil {
 		return fmt.Errorf("signing key is required")
 	}
 
 	return nil
 }
 
-func (c *Client) getEndpoint(domain string, sidetreeEndpoints []*models.Endpoint) (string, error) {
+// getEndpoints returns the sidetree endpoints to send an operation to, in order of preference: the given endpoints,
+// or if a domain is given, the endpoints chosen by the consortium's selection policy
+func (c *Client) getEndpoints(ctx context.Context, domain string, sidetreeEndpoints []*models.Endpoint,
+) ([]*models.Endpoint, error) {
 	if domain == "" && len(sidetreeEndpoints) == 0 {
-		return "", errors.New("domain is empty and sidetree endpoints is empty")
+		return nil, errors.New("domain is empty and sidetree endpoints is empty")
 	}
 
-	endpoints := sidetreeEndpoints
+	if domain == "" {
+		return sidetreeEndpoints, nil
+	}
 
-	if domain != "" {
-		var err error
-		endpoints, err = c.endpointService.GetEndpoints(domain)
+	endpoints, err := c.endpointService.GetEndpointsWithContext(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get endpoints: %w", err)
+	}
 
-		if err != nil {
-			return "", fmt.Errorf("failed to get endpoints: %w", err)
+	if len(endpoints) == 0 {
+		return nil, errors.New("list of endpoints is empty")
+	}
+
+	return endpoints, nil
+}
+
+// failoverEndpoints returns the endpoints of the consortium at the given domain that aren't in the given list of
+// tried endpoints, starting with one endpoint from each stakeholder that has no tried endpoints
+func (c *Client) failoverEndpoints(ctx context.Context, domain string, tried []*models.Endpoint) []*models.Endpoint {
+	all, err := c.discoveryService.GetEndpointsWithContext(ctx, domain)
+	if err != nil {
+		log.Warnf("failed to discover failover endpoints for %s: %s", domain, err)
+
+		return nil
+	}
+
+	triedURLs := map[string]bool{}
+	triedDomains := map[string]bool{}
+
+	for _, ep := range tried {
+		triedURLs[ep.URL] = true
+		triedDomains[ep.Domain] = true
+	}
+
+	var first, rest []*models.Endpoint
+
+	for _, ep := range all {
+		switch {
+		case triedURLs[ep.URL]:
+		case !triedDomains[ep.Domain]:
+			triedDomains[ep.Domain] = true
+
+			first = append(first, ep)
+		default:
+			rest = append(rest, ep)
+		}
+	}
+
+	return append(first, rest...)
+}
+
+// sendOperation sends an operation request to the given endpoints in order, until an endpoint accepts it.
+// The request for each endpoint is built from the endpoint's sidetree config. When an endpoint is unavailable, the
+// operation fails over to the next endpoint; when all are unavailable, to the consortium's other endpoints.
+// Returns the URL of the endpoint that accepted the operation and the endpoint's response.
+func (c *Client) sendOperation(ctx context.Context, operation, domain string, endpoints []*models.Endpoint,
+	build func(sidetreeConfig *models.SidetreeConfig) ([]byte, error)) (string, []byte, error) {
+	discovered := domain == ""
+
+	var err error
+
+	for i := 0; i < len(endpoints); i++ {
+		url := endpoints[i].URL
+
+		var response []byte
+
+		response, err = c.sendToEndpoint(ctx, operation, url, build)
+		if err == nil {
+			log.Debugf("%s operation accepted by sidetree endpoint %s", operation, url)
+
+			return url, response, nil
 		}
 
-		if len(endpoints) == 0 {
-			return "", errors.New("list of endpoints is empty")
+		var unavailable *unavailableError
+		if !errors.As(err, &unavailable) || ctx.Err() != nil {
+			break
 		}
+
+		log.Warnf("sidetree endpoint %s unavailable for %s operation: %s", url, operation, err)
+
+		if i == len(endpoints)-1 && !discovered {
+			discovered = true
+			endpoints = append(endpoints, c.failoverEndpoints(ctx, domain, endpoints)...)
+		}
+	}
+
+	var unavailable *unavailableError
+	if errors.As(err, &unavailable) && len(endpoints) > 1 {
+		return "", nil, fmt.Errorf("all %d sidetree endpoints unavailable, last error: %w", len(endpoints), err)
+	}
+
+	return "", nil, err
+}
+
+// sendToEndpoint builds an operation request for the given endpoint and sends it to the endpoint
+func (c *Client) sendToEndpoint(ctx context.Context, operation, url string,
+	build func(sidetreeConfig *models.SidetreeConfig) ([]byte, error)) ([]byte, error) {
+	sidetreeConfig, err := c.configService.GetSidetreeConfigWithContext(ctx, url)
+	if err != nil {
+		return nil, &unavailableError{err: err}
 	}
 
-	// TODO change the logic of choosing first endpoints
-	return endpoints[0].URL, nil
+	req, err := build(sidetreeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.sendRequest(ctx, req, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s sidetree request: %w", operation, err)
+	}
+
+	return response, nil
+}
+
+// unavailableError is returned when a sidetree endpoint can't be reached or fails with a server error,
+// so an operation can be sent to another endpoint instead
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
 }
 
 // unwrapPubKeyJWK takes a key which may contain a JSON JWK as a public key value
@@ -255,6 +655,75 @@ func unwrapPubKeyJWK(key doc.PublicKey) (*doc.PublicKey, error) { // nolint: goc
 	return &out, nil
 }
 
+// buildCreateRequest request builder for sidetree public DID creation, also returning the request's suffix data
+func buildCreateRequest(sidetreeConfig *models.SidetreeConfig,
+	createDIDOpts *create.Opts) ([]byte, *model.SuffixDataModel, error) {
+	opaque, err := opaqueDocument(createDIDOpts.PublicKeys, createDIDOpts.Services)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recoveryKey, err := pubkey.GetPublicKeyJWK(createDIDOpts.RecoveryPublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get recovery key : %s", err)
+	}
+
+	updateKey, err := pubkey.GetPublicKeyJWK(createDIDOpts.UpdatePublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get update key : %s", err)
+	}
+
+	recoveryCommitment, err := commitment.GetCommitment(recoveryKey, sidetreeConfig.MultiHashAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updateCommitment, err := commitment.GetCommitment(updateKey, sidetreeConfig.MultiHashAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := client.NewCreateRequest(&client.CreateRequestInfo{
+		OpaqueDocument: opaque, RecoveryCommitment: recoveryCommitment, UpdateCommitment: updateCommitment,
+		MultihashCode: sidetreeConfig.MultiHashAlgorithm,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sidetree request: %w", err)
+	}
+
+	createRequest := &model.CreateRequest{}
+
+	err = json.Unmarshal(req, createRequest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get suffix data: %w", err)
+	}
+
+	return req, createRequest.SuffixData, nil
+}
+
+// opaqueDocument returns the sidetree opaque document with the given public keys and services
+func opaqueDocument(publicKeys []doc.PublicKey, services []docdid.Service) (string, error) {
+	var parsedKeys []doc.PublicKey
+
+	for _, key := range publicKeys {
+		parsedKey, err := unwrapPubKeyJWK(key)
+		if err != nil {
+			return "", err
+		}
+
+		parsedKeys = append(parsedKeys, *parsedKey)
+	}
+
+	didDoc := &doc.Doc{PublicKey: parsedKeys, Service: services}
+
+	docBytes, err := didDoc.JSONBytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to get document bytes : %s", err)
+	}
+
+	return string(docBytes), nil
+}
+
 // buildUpdateRequest request builder for sidetree public DID update
 func (c *Client) buildUpdateRequest(did string, sidetreeConfig *models.SidetreeConfig,
 	updateDIDOpts *update.Opts) ([]byte, error) {
@@ -268,7 +737,7 @@ func (c *Client) buildUpdateRequest(did string, sidetreeConfig *models.SidetreeC
 		return nil, err
 	}
 
-	signer, updateKey, err := getSigner(updateDIDOpts.SigningKey, updateDIDOpts.SigningKeyID)
+	opSigner, updateKey, err := getSigner(updateDIDOpts.Signer, updateDIDOpts.SigningKey, updateDIDOpts.SigningKeyID)
 	if err != nil {
 		return nil, err
 	}
@@ -285,7 +754,7 @@ func (c *Client) buildUpdateRequest(did string, sidetreeConfig *models.SidetreeC
 
 	revealValue := updateDIDOpts.RevealValue
 
-	// TODO: client should be managing reveal value, this defaulting here is just temporary convenience (issue-246)
+	// operations on DIDs managed by a KeyLifecycle are given the reveal value; otherwise it's that of the signing key
 	if revealValue == "" {
 		revealValue = defaultRevealValue(updateKey, sidetreeConfig.MultiHashAlgorithm)
 	}
@@ -297,14 +766,15 @@ func (c *Client) buildUpdateRequest(did string, sidetreeConfig *models.SidetreeC
 		UpdateKey:        updateKey,
 		Patches:          patches,
 		MultihashCode:    sidetreeConfig.MultiHashAlgorithm,
-		Signer:           signer,
+		Signer:           opSigner,
 	})
 }
 
 // buildDeactivateRequest request builder for sidetree public DID deactivate
 func buildDeactivateRequest(did string, sidetreeConfig *models.SidetreeConfig,
 	deactivateDIDOpts *deactivate.Opts) ([]byte, error) {
-	signer, publicKey, err := getSigner(deactivateDIDOpts.SigningKey, deactivateDIDOpts.SigningKeyID)
+	opSigner, publicKey, err := getSigner(deactivateDIDOpts.Signer, deactivateDIDOpts.SigningKey,
+		deactivateDIDOpts.SigningKeyID)
 	if err != nil {
 		return nil, err
 	}
@@ -316,7 +786,7 @@ func buildDeactivateRequest(did string, sidetreeConfig *models.SidetreeConfig,
 
 	revealValue := deactivateDIDOpts.RevealValue
 
-	// TODO: client should be managing reveal value, this defaulting here is just temporary convenience (issue-246)
+	// operations on DIDs managed by a KeyLifecycle are given the reveal value; otherwise it's that of the signing key
 	if revealValue == "" {
 		revealValue = defaultRevealValue(publicKey, sidetreeConfig.MultiHashAlgorithm)
 	}
@@ -325,29 +795,43 @@ func buildDeactivateRequest(did string, sidetreeConfig *models.SidetreeConfig,
 		DidSuffix:   didSuffix,
 		RevealValue: revealValue,
 		RecoveryKey: publicKey,
-		Signer:      signer,
+		Signer:      opSigner,
 	})
 }
 
-func getSigner(signingkey crypto.PrivateKey, keyID string) (client.Signer, *jws.JWK, error) {
-	switch key := signingkey.(type) {
-	case *ecdsa.PrivateKey:
-		updateKey, err := pubkey.GetPublicKeyJWK(key.Public())
+// getSigner returns the sidetree signer of an operation and the public key it reveals, using the given signer or,
+// if it's nil, the given signing key
+func getSigner(s signer.Signer, signingKey crypto.PrivateKey, keyID string) (client.Signer, *jws.JWK, error) {
+	if s == nil {
+		keySigner, err := signer.NewPrivateKeySigner(signingKey)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		return ecsigner.New(key, "ES256", keyID), updateKey, nil
-	case ed25519.PrivateKey:
-		updateKey, err := pubkey.GetPublicKeyJWK(key.Public())
-		if err != nil {
-			return nil, nil, err
-		}
+		s = keySigner
+	}
+
+	return &operationSigner{signer: s, keyID: keyID}, s.PublicKeyJWK(), nil
+}
+
+// operationSigner signs sidetree operations, identifying the signing key by its ID in the DID document
+type operationSigner struct {
+	signer signer.Signer
+	keyID  string
+}
+
+func (s *operationSigner) Sign(data []byte) ([]byte, error) {
+	return s.signer.Sign(data)
+}
+
+func (s *operationSigner) Headers() jws.Headers {
+	headers := jws.Headers{jws.HeaderAlgorithm: s.signer.Algorithm()}
 
-		return edsigner.New(key, "EdDSA", keyID), updateKey, nil
-	default:
-		return nil, nil, fmt.Errorf("key not supported")
+	if s.keyID != "" {
+		headers[jws.HeaderKeyID] = s.keyID
 	}
+
+	return headers
 }
 
 func getUniqueSuffix(id string) (string, error) {
@@ -445,24 +929,9 @@ func createAddPublicKeysPatch(updateDIDOpts *update.Opts) (patch.Patch, error) {
 // buildRecoverRequest request builder for sidetree public DID recovery
 func buildRecoverRequest(did string, sidetreeConfig *models.SidetreeConfig,
 	recoverDIDOpts *recovery.Opts) ([]byte, error) {
-	publicKeys := recoverDIDOpts.PublicKeys
-
-	var parsedKeys []doc.PublicKey
-
-	for _, key := range publicKeys {
-		parsedKey, err := unwrapPubKeyJWK(key)
-		if err != nil {
-			return nil, err
-		}
-
-		parsedKeys = append(parsedKeys, *parsedKey)
-	}
-
-	didDoc := &doc.Doc{PublicKey: parsedKeys, Service: recoverDIDOpts.Services}
-
-	docBytes, err := didDoc.JSONBytes()
+	opaque, err := opaqueDocument(recoverDIDOpts.PublicKeys, recoverDIDOpts.Services)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get document bytes : %s", err)
+		return nil, err
 	}
 
 	nextRecoveryCommitment, nextUpdateCommitment, err := getCommitment(sidetreeConfig, recoverDIDOpts)
@@ -470,7 +939,7 @@ func buildRecoverRequest(did string, sidetreeConfig *models.SidetreeConfig,
 		return nil, err
 	}
 
-	signer, recoveryKey, err := getSigner(recoverDIDOpts.SigningKey, recoverDIDOpts.SigningKeyID)
+	opSigner, recoveryKey, err := getSigner(recoverDIDOpts.Signer, recoverDIDOpts.SigningKey, recoverDIDOpts.SigningKeyID)
 	if err != nil {
 		return nil, err
 	}
@@ -482,16 +951,15 @@ func buildRecoverRequest(did string, sidetreeConfig *models.SidetreeConfig,
 
 	revealValue := recoverDIDOpts.RevealValue
 
-	// TODO: client should be managing reveal value, this defaulting here is just temporary convenience (issue-246)
-	// part of estimates for 1.6 milestone
+	// operations on DIDs managed by a KeyLifecycle are given the reveal value; otherwise it's that of the signing key
 	if revealValue == "" {
 		revealValue = defaultRevealValue(recoveryKey, sidetreeConfig.MultiHashAlgorithm)
 	}
 
 	req, err := client.NewRecoverRequest(&client.RecoverRequestInfo{
-		DidSuffix: didSuffix, RevealValue: revealValue, OpaqueDocument: string(docBytes),
+		DidSuffix: didSuffix, RevealValue: revealValue, OpaqueDocument: opaque,
 		RecoveryCommitment: nextRecoveryCommitment, UpdateCommitment: nextUpdateCommitment,
-		MultihashCode: sidetreeConfig.MultiHashAlgorithm, Signer: signer, RecoveryKey: recoveryKey,
+		MultihashCode: sidetreeConfig.MultiHashAlgorithm, Signer: opSigner, RecoveryKey: recoveryKey,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sidetree request: %w", err)
@@ -524,21 +992,17 @@ func getCommitment(sidetreeConfig *models.SidetreeConfig, recoverDIDOpts *recove
 	return nextRecoveryCommitment, nextUpdateCommitment, nil
 }
 
-func (c *Client) sendRequest(req []byte, endpointURL string) ([]byte, error) { //nolint:unparam
-	httpReq, err := http.NewRequest(http.MethodPost, endpointURL+"/operations", bytes.NewReader(req))
+func (c *Client) sendRequest(ctx context.Context, req []byte, endpointURL string) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL+"/operations", bytes.NewReader(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	if c.authToken != "" {
-		httpReq.Header.Add("Authorization", c.authToken)
-	}
-
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, &unavailableError{err: fmt.Errorf("failed to send request: %w", err)}
 	}
 
 	defer closeResponseBody(resp.Body)
@@ -549,8 +1013,14 @@ func (c *Client) sendRequest(req []byte, endpointURL string) ([]byte, error) { /
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("got unexpected response from %s status '%d' body %s",
+		err = fmt.Errorf("got unexpected response from %s status '%d' body %s",
 			endpointURL, resp.StatusCode, responseBytes)
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, &unavailableError{err: err}
+		}
+
+		return nil, err
 	}
 
 	return responseBytes, nil
@@ -6,14 +6,18 @@ SPDX-License-Identifier: Apache-2.0
 package did
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
@@ -21,11 +25,14 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
 	"github.com/trustbloc/sidetree-core-go/pkg/jws"
 	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/doc"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/create"
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/deactivate"
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/recovery"
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/update"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/signer"
 	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
 	mockdiscovery "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/discovery"
 	mockendpoint "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/endpoint"
@@ -34,6 +41,201 @@ import (
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
+func TestClient_CreateDID(t *testing.T) {
+	recoveryKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	newClient := func() *Client {
+		v := New(WithAuthToken("tk1"))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		return v
+	}
+
+	t.Run("test domain is empty", func(t *testing.T) {
+		_, _, err := New().CreateDID("", create.WithRecoveryPublicKey(recoveryKey),
+			create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "domain is empty")
+	})
+
+	t.Run("test recovery key empty", func(t *testing.T) {
+		_, _, err := New().CreateDID("testnet", create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "recovery public key is required")
+	})
+
+	t.Run("test update key empty", func(t *testing.T) {
+		_, _, err := New().CreateDID("testnet", create.WithRecoveryPublicKey(recoveryKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "update public key is required")
+	})
+
+	t.Run("test error from get endpoints", func(t *testing.T) {
+		v := New()
+
+		v.endpointService = endpoint.NewService(
+			discoveryMock([]*models.Endpoint{}, fmt.Errorf("discover error")),
+			selectionMock([]*models.Endpoint{}, nil))
+
+		_, _, err := v.CreateDID("testnet", create.WithRecoveryPublicKey(recoveryKey),
+			create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "discover error")
+	})
+
+	t.Run("test failed to get sidetree config", func(t *testing.T) {
+		v := New()
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return nil, fmt.Errorf("failed to get sidetree config")
+			}}
+
+		_, _, err := v.CreateDID("", create.WithSidetreeEndpoint("url"),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get sidetree config")
+	})
+
+	t.Run("test unsupported recovery key", func(t *testing.T) {
+		_, _, err := newClient().CreateDID("", create.WithSidetreeEndpoint("url"),
+			create.WithRecoveryPublicKey("key"), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get recovery key")
+	})
+
+	t.Run("test reused key", func(t *testing.T) {
+		_, _, err := newClient().CreateDID("", create.WithSidetreeEndpoint("url"),
+			create.WithRecoveryPublicKey(updateKey), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "re-using public keys is not allowed")
+	})
+
+	t.Run("test error from send request", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer serv.Close()
+
+		_, _, err := newClient().CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send create sidetree request")
+	})
+
+	t.Run("test invalid response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := fmt.Fprint(w, "{")
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		_, _, err := newClient().CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse")
+	})
+
+	t.Run("test success", func(t *testing.T) {
+		var suffix string
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "Bearer tk1", r.Header.Get("Authorization"))
+
+			req := &model.CreateRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+
+			var err error
+			suffix, err = model.GetUniqueSuffix(req.SuffixData, []uint{18})
+			require.NoError(t, err)
+
+			bytes, err := (&did.Doc{ID: "did:trustbloc:testnet:" + suffix, Context: []string{did.Context}}).JSONBytes()
+			require.NoError(t, err)
+			b, err := json.Marshal(didResolution{Context: "https://www.w3.org/ns/did-resolution/v1",
+				DIDDocument: bytes})
+			require.NoError(t, err)
+			_, err = fmt.Fprint(w, string(b))
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		v := newClient()
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: serv.URL}}, nil
+			}}
+
+		var accepted string
+
+		docResolution, suffixData, err := v.CreateDID("testnet",
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey),
+			create.WithPublicKey(&doc.PublicKey{ID: "key1", Encoding: doc.PublicKeyEncodingJwk,
+				KeyType: doc.Ed25519KeyType, Purposes: []string{doc.KeyPurposeAuthentication}, Value: updateKey}),
+			create.WithService(&did.Service{ID: "svc1", Type: "type", ServiceEndpoint: "http://example.com"}),
+			create.WithAcceptedEndpointHandler(func(endpointURL string) {
+				accepted = endpointURL
+			}))
+		require.NoError(t, err)
+		require.Equal(t, serv.URL, accepted)
+		require.Equal(t, "did:trustbloc:testnet:"+suffix, docResolution.DIDDocument.ID)
+
+		uniqueSuffix, err := model.GetUniqueSuffix(suffixData, []uint{18})
+		require.NoError(t, err)
+		require.Equal(t, suffix, uniqueSuffix)
+	})
+
+	t.Run("test success with document response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bytes, err := (&did.Doc{ID: "did1", Context: []string{did.Context}}).JSONBytes()
+			require.NoError(t, err)
+			_, err = w.Write(bytes)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		docResolution, suffixData, err := newClient().CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.NoError(t, err)
+		require.Equal(t, "did1", docResolution.DIDDocument.ID)
+		require.NotEmpty(t, suffixData.RecoveryCommitment)
+	})
+
+	t.Run("test success with http headers", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "Bearer tk1", r.Header.Get("Authorization"))
+			require.Equal(t, "cli/1.0", r.Header.Get("User-Agent"))
+			require.Equal(t, "a", r.Header.Get("X-Api-Key"))
+
+			bytes, err := (&did.Doc{ID: "did1", Context: []string{did.Context}}).JSONBytes()
+			require.NoError(t, err)
+			_, err = w.Write(bytes)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		v := New(WithAuthToken("tk1"), WithUserAgent("cli/1.0"), WithHTTPHeader("X-Api-Key", "a"))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		docResolution, _, err := v.CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.NoError(t, err)
+		require.Equal(t, "did1", docResolution.DIDDocument.ID)
+	})
+}
+
 func TestClient_DeactivateDID(t *testing.T) {
 	t.Run("test domain is empty", func(t *testing.T) {
 		v := New()
@@ -188,6 +390,31 @@ func TestClient_DeactivateDID(t *testing.T) {
 			deactivate.WithSidetreeEndpoint(serv.URL), deactivate.WithSigningKeyID("k1"))
 		require.NoError(t, err)
 	})
+
+	t.Run("test context canceled", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer serv.Close()
+
+		v := New()
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = v.DeactivateDIDWithContext(ctx, "did:ex:123", "",
+			deactivate.WithSigningKey(privKey), deactivate.WithSidetreeEndpoint(serv.URL))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
 }
 
 func TestClient_RecoverDID(t *testing.T) {
@@ -675,6 +902,216 @@ func TestClient_UpdateDID(t *testing.T) {
 			update.WithAddService(&did.Service{ID: "svc3"}))
 		require.NoError(t, err)
 	})
+	t.Run("test success with signer", func(t *testing.T) {
+		var header map[string]interface{}
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := struct {
+				SignedData string `json:"signedData"`
+			}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			headerBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(req.SignedData, ".")[0])
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(headerBytes, &header))
+		}))
+		defer serv.Close()
+
+		v := New()
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		keySigner, err := signer.NewPrivateKeySigner(signingKey)
+		require.NoError(t, err)
+
+		s := &countingSigner{Signer: keySigner}
+
+		err = v.UpdateDID("did:ex:123", "", update.WithSidetreeEndpoint(serv.URL), update.WithSigner(s),
+			update.WithSigningKeyID("k1"), update.WithNextUpdatePublicKey(pubKey), update.WithRemoveService("svc1"))
+		require.NoError(t, err)
+		require.Equal(t, 1, s.count)
+		require.Equal(t, map[string]interface{}{"alg": "ES256", "kid": "k1"}, header)
+	})
+}
+
+// countingSigner counts the signatures of a signer
+type countingSigner struct {
+	signer.Signer
+	count int
+}
+
+func (s *countingSigner) Sign(data []byte) ([]byte, error) {
+	s.count++
+
+	return s.Signer.Sign(data)
+}
+
+func TestClient_Failover(t *testing.T) {
+	statusServer := func(status int, requests *int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*requests++
+
+			w.WriteHeader(status)
+		}))
+	}
+
+	newClient := func() *Client {
+		v := New()
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		return v
+	}
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	updateOpts := func(accepted *string, endpoints ...string) []update.Option {
+		opts := []update.Option{
+			update.WithSigningKey(privKey), update.WithNextUpdatePublicKey(pubKey), update.WithRemoveService("svc1"),
+			update.WithAcceptedEndpointHandler(func(endpointURL string) {
+				*accepted = endpointURL
+			}),
+		}
+
+		for _, ep := range endpoints {
+			opts = append(opts, update.WithSidetreeEndpoint(ep))
+		}
+
+		return opts
+	}
+
+	t.Run("fail over on server error and connection error", func(t *testing.T) {
+		var failed, ok int
+
+		failing := statusServer(http.StatusServiceUnavailable, &failed)
+		defer failing.Close()
+
+		working := statusServer(http.StatusOK, &ok)
+		defer working.Close()
+
+		var accepted string
+
+		err := newClient().UpdateDID("did:ex:123", "",
+			updateOpts(&accepted, failing.URL, "http://0.0.0.0:0", working.URL)...)
+		require.NoError(t, err)
+		require.Equal(t, working.URL, accepted)
+		require.Equal(t, 1, failed)
+		require.Equal(t, 1, ok)
+	})
+
+	t.Run("no fail over on client error", func(t *testing.T) {
+		var rejected, ok int
+
+		rejecting := statusServer(http.StatusBadRequest, &rejected)
+		defer rejecting.Close()
+
+		working := statusServer(http.StatusOK, &ok)
+		defer working.Close()
+
+		var accepted string
+
+		err := newClient().UpdateDID("did:ex:123", "", updateOpts(&accepted, rejecting.URL, working.URL)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send update sidetree request")
+		require.Contains(t, err.Error(), "status '400'")
+		require.Empty(t, accepted)
+		require.Equal(t, 0, ok)
+	})
+
+	t.Run("all endpoints unavailable", func(t *testing.T) {
+		var failed int
+
+		failing := statusServer(http.StatusInternalServerError, &failed)
+		defer failing.Close()
+
+		var accepted string
+
+		err := newClient().UpdateDID("did:ex:123", "", updateOpts(&accepted, failing.URL, failing.URL)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "all 2 sidetree endpoints unavailable")
+		require.Contains(t, err.Error(), "failed to send update sidetree request")
+		require.Equal(t, 2, failed)
+	})
+
+	t.Run("fail over to other stakeholders of the consortium", func(t *testing.T) {
+		var failed, ok, other int
+
+		failing := statusServer(http.StatusInternalServerError, &failed)
+		defer failing.Close()
+
+		sameStakeholder := statusServer(http.StatusOK, &other)
+		defer sameStakeholder.Close()
+
+		otherStakeholder := statusServer(http.StatusOK, &ok)
+		defer otherStakeholder.Close()
+
+		v := newClient()
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: failing.URL, Domain: "s1"}}, nil
+			}}
+
+		v.discoveryService = discoveryMock([]*models.Endpoint{
+			{URL: failing.URL, Domain: "s1"},
+			{URL: sameStakeholder.URL, Domain: "s1"},
+			{URL: otherStakeholder.URL, Domain: "s2"},
+		}, nil)
+
+		var accepted string
+
+		err := v.UpdateDID("did:ex:123", "testnet", updateOpts(&accepted)...)
+		require.NoError(t, err)
+		require.Equal(t, otherStakeholder.URL, accepted)
+		require.Equal(t, 1, failed)
+		require.Equal(t, 0, other)
+
+		v.discoveryService = discoveryMock(nil, fmt.Errorf("discovery error"))
+
+		err = v.UpdateDID("did:ex:123", "testnet", updateOpts(&accepted)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send update sidetree request")
+	})
+
+	t.Run("fail over on sidetree config error", func(t *testing.T) {
+		var ok int
+
+		working := statusServer(http.StatusOK, &ok)
+		defer working.Close()
+
+		v := newClient()
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				if s != working.URL {
+					return nil, fmt.Errorf("sidetree config error")
+				}
+
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		var accepted string
+
+		err := v.UpdateDID("did:ex:123", "", updateOpts(&accepted, "http://0.0.0.0:0", working.URL)...)
+		require.NoError(t, err)
+		require.Equal(t, working.URL, accepted)
+	})
 }
 
 func Test_unwrapPubKeyJWK(t *testing.T) {
@@ -0,0 +1,399 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	"github.com/square/go-jose/v3"
+	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+// KeyLifecycle manages the update and recovery keys of DIDs, persisting them in a KeyStore.
+// A Client with a KeyLifecycle signs operations on registered DIDs with the keys the DID's last operation committed
+// to, supplying the reveal values, and generates the next keys to commit to. The stored keys are only rotated after
+// an operation is accepted: the next keys are saved as pending keys before the operation is sent, and promoted to the
+// current keys once it is accepted, so they aren't lost if the operation is accepted but can't be recorded.
+// Pending keys left by an operation whose outcome isn't known are reconciled with the DID's published commitments
+// before the next operation. Operations on the same DID through the same KeyLifecycle are serialized.
+type KeyLifecycle struct {
+	store KeyStore
+
+	mutex sync.Mutex
+	locks map[string]*didLock
+}
+
+// didLock serializes the operations on a DID
+type didLock struct {
+	mutex sync.Mutex
+	// refs is the number of operations holding or waiting for the lock
+	refs int
+}
+
+// NewKeyLifecycle returns a KeyLifecycle persisting keys in the given store
+func NewKeyLifecycle(store KeyStore) *KeyLifecycle {
+	return &KeyLifecycle{store: store, locks: map[string]*didLock{}}
+}
+
+// Register saves the update and recovery keys that the DID's last operation committed to, usually its creation.
+// The keys must be ed25519.PrivateKey or *ecdsa.PrivateKey.
+func (k *KeyLifecycle) Register(did string, updateKey, recoveryKey crypto.PrivateKey) error {
+	updateJWK, err := privateJWK(updateKey)
+	if err != nil {
+		return fmt.Errorf("update key: %w", err)
+	}
+
+	recoveryJWK, err := privateJWK(recoveryKey)
+	if err != nil {
+		return fmt.Errorf("recovery key: %w", err)
+	}
+
+	return k.store.Put(did, &KeyState{UpdateKey: updateJWK, RecoveryKey: recoveryJWK})
+}
+
+// State returns the current key state of the DID, or ErrKeyStateNotFound if the DID isn't registered
+func (k *KeyLifecycle) State(did string) (*KeyState, error) {
+	return k.store.Get(did)
+}
+
+// lock waits until no other operation on the DID is in progress, returning the function ending the operation
+func (k *KeyLifecycle) lock(did string) func() {
+	k.mutex.Lock()
+
+	l, ok := k.locks[did]
+	if !ok {
+		l = &didLock{}
+		k.locks[did] = l
+	}
+
+	l.refs++
+
+	k.mutex.Unlock()
+
+	l.mutex.Lock()
+
+	return func() {
+		l.mutex.Unlock()
+
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		l.refs--
+
+		if l.refs == 0 {
+			delete(k.locks, did)
+		}
+	}
+}
+
+// reconcile settles the pending keys of the DID against the DID's published update and recovery commitments.
+// A pending key that the published commitment commits to was accepted, and is promoted to the current key. A pending
+// key whose current key is still committed to belongs to an operation that may not be published yet, so it is kept,
+// for the next operation to commit to again. Fails if a published commitment commits to neither key.
+func (k *KeyLifecycle) reconcile(did string, state *KeyState, updateCommitment, recoveryCommitment string,
+	multihashCode uint) (*KeyState, error) {
+	reconciled := *state
+
+	var err error
+
+	reconciled.UpdateKey, reconciled.PendingUpdateKey, reconciled.UpdateCommitment, err = reconcileKey(
+		state.UpdateKey, state.PendingUpdateKey, state.UpdateCommitment, updateCommitment, multihashCode)
+	if err != nil {
+		return nil, fmt.Errorf("update key: %w", err)
+	}
+
+	reconciled.RecoveryKey, reconciled.PendingRecoveryKey, reconciled.RecoveryCommitment, err = reconcileKey(
+		state.RecoveryKey, state.PendingRecoveryKey, state.RecoveryCommitment, recoveryCommitment, multihashCode)
+	if err != nil {
+		return nil, fmt.Errorf("recovery key: %w", err)
+	}
+
+	if reconciled == *state {
+		return state, nil
+	}
+
+	err = k.store.Put(did, &reconciled)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reconciled, nil
+}
+
+// reconcileKey returns the current key, pending key and current commitment of a key given its published commitment
+func reconcileKey(current, pending *jose.JSONWebKey, currentCommitment, published string, multihashCode uint,
+) (*jose.JSONWebKey, *jose.JSONWebKey, string, error) {
+	if pending == nil {
+		return current, pending, currentCommitment, nil
+	}
+
+	pendingCommitment, err := commitmentOf(pending, multihashCode)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("pending key: %w", err)
+	}
+
+	if published == pendingCommitment {
+		return pending, nil, published, nil
+	}
+
+	c, err := commitmentOf(current, multihashCode)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if published == c {
+		return current, pending, c, nil
+	}
+
+	return nil, nil, "", fmt.Errorf("published commitment %q commits to neither the current nor the pending key",
+		published)
+}
+
+// keyRotation is the change to a DID's key state made by an operation in progress
+type keyRotation struct {
+	did   string
+	state *KeyState
+	// signingKey signs the operation
+	signingKey crypto.Signer
+	// nextUpdateKey and nextRecoveryKey are the keys the operation commits to, or nil if it doesn't change them
+	nextUpdateKey   crypto.Signer
+	nextRecoveryKey crypto.Signer
+	// deactivate is true if the operation ends the DID's key lifecycle
+	deactivate bool
+	// multihashCode is the multihash algorithm of the sidetree endpoint that accepted the operation
+	multihashCode uint
+	// unlock ends the operation, letting the next operation on the DID proceed
+	unlock func()
+}
+
+// release ends the rotation's operation. A nil rotation does nothing.
+func (r *keyRotation) release() {
+	if r != nil {
+		r.unlock()
+	}
+}
+
+func (k *KeyLifecycle) prepareUpdate(did string, state *KeyState) (*keyRotation, error) {
+	signingKey, err := signerFromJWK(state.UpdateKey)
+	if err != nil {
+		return nil, fmt.Errorf("update key: %w", err)
+	}
+
+	nextUpdateKey, err := nextKey(state.PendingUpdateKey, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyRotation{did: did, state: state, signingKey: signingKey, nextUpdateKey: nextUpdateKey}, nil
+}
+
+func (k *KeyLifecycle) prepareRecover(did string, state *KeyState) (*keyRotation, error) {
+	signingKey, err := signerFromJWK(state.RecoveryKey)
+	if err != nil {
+		return nil, fmt.Errorf("recovery key: %w", err)
+	}
+
+	nextUpdateKey, err := nextKey(state.PendingUpdateKey, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nextRecoveryKey, err := nextKey(state.PendingRecoveryKey, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyRotation{did: did, state: state, signingKey: signingKey,
+		nextUpdateKey: nextUpdateKey, nextRecoveryKey: nextRecoveryKey}, nil
+}
+
+func (k *KeyLifecycle) prepareDeactivate(did string, state *KeyState) (*keyRotation, error) {
+	signingKey, err := signerFromJWK(state.RecoveryKey)
+	if err != nil {
+		return nil, fmt.Errorf("recovery key: %w", err)
+	}
+
+	return &keyRotation{did: did, state: state, signingKey: signingKey, deactivate: true}, nil
+}
+
+// savePending saves the keys the rotation's operation commits to as the DID's pending keys, before the operation is
+// sent. Pending keys are never replaced: an operation commits to the DID's pending keys, if it has any.
+func (k *KeyLifecycle) savePending(r *keyRotation) error {
+	pending := *r.state
+
+	if r.nextUpdateKey != nil && pending.PendingUpdateKey == nil {
+		jwk, err := privateJWK(r.nextUpdateKey)
+		if err != nil {
+			return fmt.Errorf("next update key: %w", err)
+		}
+
+		pending.PendingUpdateKey = jwk
+	}
+
+	if r.nextRecoveryKey != nil && pending.PendingRecoveryKey == nil {
+		jwk, err := privateJWK(r.nextRecoveryKey)
+		if err != nil {
+			return fmt.Errorf("next recovery key: %w", err)
+		}
+
+		pending.PendingRecoveryKey = jwk
+	}
+
+	if pending == *r.state {
+		return nil
+	}
+
+	return k.store.Put(r.did, &pending)
+}
+
+// commit promotes the rotation's pending keys to the DID's current keys after the rotation's operation was accepted.
+// Pending keys of other operations are kept.
+func (k *KeyLifecycle) commit(r *keyRotation) error {
+	if r.deactivate {
+		return k.store.Delete(r.did)
+	}
+
+	next := *r.state
+
+	if r.nextUpdateKey != nil {
+		jwk, c, err := committedKey(r.nextUpdateKey, r.multihashCode)
+		if err != nil {
+			return fmt.Errorf("next update key: %w", err)
+		}
+
+		next.UpdateKey, next.UpdateCommitment, next.PendingUpdateKey = jwk, c, nil
+	}
+
+	if r.nextRecoveryKey != nil {
+		jwk, c, err := committedKey(r.nextRecoveryKey, r.multihashCode)
+		if err != nil {
+			return fmt.Errorf("next recovery key: %w", err)
+		}
+
+		next.RecoveryKey, next.RecoveryCommitment, next.PendingRecoveryKey = jwk, c, nil
+	}
+
+	return k.store.Put(r.did, &next)
+}
+
+// builder wraps the request builder of the rotation's operation, so each request is built with the reveal value of
+// the rotation's signing key for the endpoint's multihash algorithm. A nil rotation returns the builder unchanged.
+func (r *keyRotation) builder(setRevealValue func(revealValue string),
+	build func(sidetreeConfig *models.SidetreeConfig) ([]byte, error),
+) func(sidetreeConfig *models.SidetreeConfig) ([]byte, error) {
+	if r == nil {
+		return build
+	}
+
+	return func(sidetreeConfig *models.SidetreeConfig) ([]byte, error) {
+		jwk, err := pubkey.GetPublicKeyJWK(r.signingKey.Public())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signing key: %w", err)
+		}
+
+		revealValue, err := commitment.GetRevealValue(jwk, sidetreeConfig.MultiHashAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reveal value: %w", err)
+		}
+
+		setRevealValue(revealValue)
+
+		return build(sidetreeConfig)
+	}
+}
+
+// committedKey returns the private JWK of a key and the commitment to its public key
+func committedKey(key crypto.Signer, multihashCode uint) (*jose.JSONWebKey, string, error) {
+	jwk, err := privateJWK(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	publicJWK, err := pubkey.GetPublicKeyJWK(key.Public())
+	if err != nil {
+		return nil, "", err
+	}
+
+	c, err := commitment.GetCommitment(publicJWK, multihashCode)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return jwk, c, nil
+}
+
+// commitmentOf returns the commitment to the public key of a private JWK
+func commitmentOf(jwk *jose.JSONWebKey, multihashCode uint) (string, error) {
+	key, err := signerFromJWK(jwk)
+	if err != nil {
+		return "", err
+	}
+
+	_, c, err := committedKey(key, multihashCode)
+
+	return c, err
+}
+
+func privateJWK(key crypto.PrivateKey) (*jose.JSONWebKey, error) {
+	switch key.(type) {
+	case ed25519.PrivateKey, *ecdsa.PrivateKey:
+		return &jose.JSONWebKey{Key: key}, nil
+	default:
+		return nil, fmt.Errorf("key not supported")
+	}
+}
+
+func signerFromJWK(jwk *jose.JSONWebKey) (crypto.Signer, error) {
+	if jwk == nil {
+		return nil, fmt.Errorf("key missing")
+	}
+
+	switch key := jwk.Key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("key not supported")
+	}
+}
+
+// nextKey returns the pending key, which an operation that may not be published yet commits to, or else a new key of
+// the same type as the given key
+func nextKey(pending *jose.JSONWebKey, key crypto.Signer) (crypto.Signer, error) {
+	if pending != nil {
+		return signerFromJWK(pending)
+	}
+
+	return generateKeyLike(key)
+}
+
+// generateKeyLike generates a new key of the same type as the given key
+func generateKeyLike(key crypto.Signer) (crypto.Signer, error) {
+	if ecKey, ok := key.(*ecdsa.PrivateKey); ok {
+		next, err := ecdsa.GenerateKey(ecKey.Curve, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generating key: %w", err)
+		}
+
+		return next, nil
+	}
+
+	_, next, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	return next, nil
+}
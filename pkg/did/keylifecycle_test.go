@@ -0,0 +1,563 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/deactivate"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/recovery"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/update"
+	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+type operationRequest struct {
+	RevealValue string `json:"revealValue"`
+	Delta       struct {
+		UpdateCommitment string `json:"updateCommitment"`
+	} `json:"delta"`
+	accepted bool
+}
+
+// operationServer returns a sidetree server recording the operation requests it receives, responding with the
+// current value of status. The DID resolves to the update commitment of the last accepted request or, if none was
+// accepted, to the commitment to the key revealed by the first request.
+func operationServer(t *testing.T, status *int, requests *[]operationRequest) *httptest.Server {
+	var mutex sync.Mutex
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if r.Method == http.MethodGet {
+			writeCommitmentResolution(t, w, publishedCommitment(t, *requests))
+
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := operationRequest{accepted: *status == http.StatusOK}
+		require.NoError(t, json.Unmarshal(body, &req))
+
+		*requests = append(*requests, req)
+
+		w.WriteHeader(*status)
+	}))
+}
+
+func publishedCommitment(t *testing.T, requests []operationRequest) string {
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].accepted {
+			return requests[i].Delta.UpdateCommitment
+		}
+	}
+
+	if len(requests) == 0 {
+		return ""
+	}
+
+	c, err := commitment.GetCommitmentFromRevealValue(requests[0].RevealValue)
+	require.NoError(t, err)
+
+	return c
+}
+
+func writeCommitmentResolution(t *testing.T, w http.ResponseWriter, updateCommitment string) {
+	_, err := fmt.Fprintf(w, `{"@context":"https://www.w3.org/ns/did-resolution/v1",`+
+		`"didDocument":{"id":"did:ex:123","@context":"https://www.w3.org/ns/did/v1"},`+
+		`"methodMetadata":{"published":true,"updateCommitment":%q}}`, updateCommitment)
+	require.NoError(t, err)
+}
+
+// failingKeyStore is a KeyStore whose puts fail once the given number of puts succeeded
+type failingKeyStore struct {
+	*MemKeyStore
+	puts int
+}
+
+func (s *failingKeyStore) Put(did string, state *KeyState) error {
+	if s.puts == 0 {
+		return errors.New("put failed")
+	}
+
+	s.puts--
+
+	return s.MemKeyStore.Put(did, state)
+}
+
+func TestKeyLifecycle_Register(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("test success", func(t *testing.T) {
+		keys := NewKeyLifecycle(NewMemKeyStore())
+
+		require.NoError(t, keys.Register("did:ex:123", edKey, ecKey))
+
+		state, err := keys.State("did:ex:123")
+		require.NoError(t, err)
+		require.Equal(t, edKey, state.UpdateKey.Key)
+		require.Equal(t, ecKey, state.RecoveryKey.Key)
+	})
+
+	t.Run("test unsupported update key", func(t *testing.T) {
+		err := NewKeyLifecycle(NewMemKeyStore()).Register("did:ex:123", "key", ecKey)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "update key: key not supported")
+	})
+
+	t.Run("test unsupported recovery key", func(t *testing.T) {
+		err := NewKeyLifecycle(NewMemKeyStore()).Register("did:ex:123", edKey, "key")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "recovery key: key not supported")
+	})
+}
+
+func TestClient_WithKeyLifecycle(t *testing.T) { // nolint: gocyclo
+	const id = "did:ex:123"
+
+	newClient := func(keys *KeyLifecycle) *Client {
+		v := New(WithKeyLifecycle(keys))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		return v
+	}
+
+	register := func(t *testing.T, keys *KeyLifecycle) {
+		_, updateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		require.NoError(t, keys.Register(id, updateKey, recoveryKey))
+	}
+
+	t.Run("test update rotates update key", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		store, err := NewFileKeyStore(t.TempDir())
+		require.NoError(t, err)
+
+		keys := NewKeyLifecycle(store)
+		register(t, keys)
+
+		initial, err := keys.State(id)
+		require.NoError(t, err)
+
+		v := newClient(keys)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+				update.WithRemoveService("svc1")))
+		}
+
+		require.Len(t, requests, 3)
+
+		// each update reveals the key committed to by the previous one
+		for i := 1; i < len(requests); i++ {
+			c, err := commitment.GetCommitmentFromRevealValue(requests[i].RevealValue)
+			require.NoError(t, err)
+			require.Equal(t, requests[i-1].Delta.UpdateCommitment, c)
+		}
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, requests[2].Delta.UpdateCommitment, state.UpdateCommitment)
+		require.IsType(t, ed25519.PrivateKey{}, state.UpdateKey.Key)
+		require.NotEqual(t, initial.UpdateKey.Key, state.UpdateKey.Key)
+		require.Equal(t, initial.RecoveryKey.Key, state.RecoveryKey.Key)
+		require.Nil(t, state.PendingUpdateKey)
+		require.Nil(t, state.PendingRecoveryKey)
+	})
+
+	t.Run("test failed save of pending keys doesn't send update", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(&failingKeyStore{MemKeyStore: NewMemKeyStore(), puts: 1})
+		register(t, keys)
+
+		initial, err := keys.State(id)
+		require.NoError(t, err)
+
+		err = newClient(keys).UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+			update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to save pending keys of "+id+": put failed")
+		require.Empty(t, requests)
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, initial, state)
+	})
+
+	t.Run("test accepted update keeps pending keys when they can't be promoted", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(&failingKeyStore{MemKeyStore: NewMemKeyStore(), puts: 2})
+		register(t, keys)
+
+		initial, err := keys.State(id)
+		require.NoError(t, err)
+
+		err = newClient(keys).UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+			update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "accepted but failed to promote its pending keys")
+		require.Len(t, requests, 1)
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, initial.UpdateKey, state.UpdateKey)
+		require.NotNil(t, state.PendingUpdateKey)
+		require.Nil(t, state.PendingRecoveryKey)
+
+		// the pending update key is the one the accepted update committed to
+		signer, err := signerFromJWK(state.PendingUpdateKey)
+		require.NoError(t, err)
+
+		_, c, err := committedKey(signer, 18)
+		require.NoError(t, err)
+		require.Equal(t, requests[0].Delta.UpdateCommitment, c)
+	})
+
+	t.Run("test failed update keeps keys", func(t *testing.T) {
+		status := http.StatusInternalServerError
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		register(t, keys)
+
+		v := newClient(keys)
+
+		err := v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL), update.WithRemoveService("svc1"))
+		require.Error(t, err)
+
+		status = http.StatusOK
+
+		require.NoError(t, v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+			update.WithRemoveService("svc1")))
+
+		require.Len(t, requests, 2)
+		require.Equal(t, requests[0].RevealValue, requests[1].RevealValue)
+
+		// the second update commits to the pending key of the first, which may still be published
+		require.Equal(t, requests[0].Delta.UpdateCommitment, requests[1].Delta.UpdateCommitment)
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, requests[1].Delta.UpdateCommitment, state.UpdateCommitment)
+		require.Nil(t, state.PendingUpdateKey)
+	})
+
+	t.Run("test accepted update whose keys weren't promoted, then update again", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		store := &failingKeyStore{MemKeyStore: NewMemKeyStore(), puts: 2}
+		keys := NewKeyLifecycle(store)
+		register(t, keys)
+
+		v := newClient(keys)
+
+		err := v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL), update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "accepted but failed to promote its pending keys")
+
+		store.puts = 10
+
+		require.NoError(t, v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+			update.WithRemoveService("svc1")))
+
+		require.Len(t, requests, 2)
+
+		// the second update reveals the key the first committed to
+		c, err := commitment.GetCommitmentFromRevealValue(requests[1].RevealValue)
+		require.NoError(t, err)
+		require.Equal(t, requests[0].Delta.UpdateCommitment, c)
+		require.NotEqual(t, requests[0].Delta.UpdateCommitment, requests[1].Delta.UpdateCommitment)
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, requests[1].Delta.UpdateCommitment, state.UpdateCommitment)
+		require.Nil(t, state.PendingUpdateKey)
+		require.Nil(t, state.PendingRecoveryKey)
+	})
+
+	t.Run("test pending keys not reconciled", func(t *testing.T) {
+		published := "other"
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if published == "" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			writeCommitmentResolution(t, w, published)
+		}))
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		register(t, keys)
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+
+		state.PendingUpdateKey = state.RecoveryKey
+		require.NoError(t, keys.store.Put(id, state))
+
+		v := newClient(keys)
+
+		err = v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL), update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to reconcile pending keys of "+id+": update key: published "+
+			"commitment \"other\" commits to neither the current nor the pending key")
+
+		published = ""
+
+		err = v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL), update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no endpoint resolved the DID's published commitments")
+
+		// the pending key is kept
+		reconciled, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, state, reconciled)
+	})
+
+	t.Run("test concurrent updates are serialized", func(t *testing.T) {
+		const updates = 5
+
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		register(t, keys)
+
+		v := newClient(keys)
+
+		var wg sync.WaitGroup
+
+		errs := make(chan error, updates)
+
+		for i := 0; i < updates; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				errs <- v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL), update.WithRemoveService("svc1"))
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		require.Len(t, requests, updates)
+
+		for i := 1; i < len(requests); i++ {
+			c, err := commitment.GetCommitmentFromRevealValue(requests[i].RevealValue)
+			require.NoError(t, err)
+			require.Equal(t, requests[i-1].Delta.UpdateCommitment, c)
+		}
+
+		require.Empty(t, keys.locks)
+	})
+
+	t.Run("test keys are committed to with the accepting endpoint's multihash algorithm", func(t *testing.T) {
+		failing, accepting := http.StatusInternalServerError, http.StatusOK
+
+		var failed, accepted []operationRequest
+
+		failingServ := operationServer(t, &failing, &failed)
+		defer failingServ.Close()
+
+		acceptingServ := operationServer(t, &accepting, &accepted)
+		defer acceptingServ.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		register(t, keys)
+
+		v := newClient(keys)
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(url string) (*models.SidetreeConfig, error) {
+				if url == acceptingServ.URL {
+					return &models.SidetreeConfig{MultiHashAlgorithm: 19}, nil
+				}
+
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		require.NoError(t, v.UpdateDID(id, "", update.WithSidetreeEndpoint(failingServ.URL),
+			update.WithSidetreeEndpoint(acceptingServ.URL),
+			update.WithRemoveService("svc1")))
+
+		require.Len(t, failed, 1)
+		require.Len(t, accepted, 1)
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.Equal(t, accepted[0].Delta.UpdateCommitment, state.UpdateCommitment)
+		require.NotEqual(t, failed[0].Delta.UpdateCommitment, state.UpdateCommitment)
+	})
+
+	t.Run("test recover rotates both keys", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		register(t, keys)
+
+		initial, err := keys.State(id)
+		require.NoError(t, err)
+
+		v := newClient(keys)
+
+		require.NoError(t, v.RecoverDID(id, "", recovery.WithSidetreeEndpoint(serv.URL)))
+		require.NoError(t, v.RecoverDID(id, "", recovery.WithSidetreeEndpoint(serv.URL)))
+
+		state, err := keys.State(id)
+		require.NoError(t, err)
+		require.IsType(t, &ecdsa.PrivateKey{}, state.RecoveryKey.Key)
+		require.NotEqual(t, initial.RecoveryKey.Key, state.RecoveryKey.Key)
+		require.NotEqual(t, initial.UpdateKey.Key, state.UpdateKey.Key)
+		require.NotEmpty(t, state.RecoveryCommitment)
+		require.Equal(t, requests[1].Delta.UpdateCommitment, state.UpdateCommitment)
+		require.Nil(t, state.PendingUpdateKey)
+		require.Nil(t, state.PendingRecoveryKey)
+
+		c, err := commitment.GetCommitmentFromRevealValue(requests[1].RevealValue)
+		require.NoError(t, err)
+		require.NotEqual(t, state.RecoveryCommitment, c)
+
+		// an update after recovery reveals the update key the recovery committed to
+		require.NoError(t, v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+			update.WithRemoveService("svc1")))
+
+		c, err = commitment.GetCommitmentFromRevealValue(requests[2].RevealValue)
+		require.NoError(t, err)
+		require.Equal(t, requests[1].Delta.UpdateCommitment, c)
+	})
+
+	t.Run("test deactivate removes keys", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		register(t, keys)
+
+		v := newClient(keys)
+
+		require.NoError(t, v.DeactivateDID(id, "", deactivate.WithSidetreeEndpoint(serv.URL)))
+
+		_, err := keys.State(id)
+		require.True(t, errors.Is(err, ErrKeyStateNotFound))
+	})
+
+	t.Run("test DID not registered", func(t *testing.T) {
+		v := newClient(NewKeyLifecycle(NewMemKeyStore()))
+
+		err := v.UpdateDID(id, "", update.WithSidetreeEndpoint("http://localhost"), update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrKeyStateNotFound))
+	})
+
+	t.Run("test signing key given", func(t *testing.T) {
+		status := http.StatusOK
+
+		var requests []operationRequest
+
+		serv := operationServer(t, &status, &requests)
+		defer serv.Close()
+
+		keys := NewKeyLifecycle(NewMemKeyStore())
+		v := newClient(keys)
+
+		pubKey, signingKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		nextPubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		require.NoError(t, v.UpdateDID(id, "", update.WithSidetreeEndpoint(serv.URL),
+			update.WithSigningKey(signingKey), update.WithNextUpdatePublicKey(nextPubKey),
+			update.WithRemoveService("svc1")))
+
+		pubKeyJWK, err := pubkey.GetPublicKeyJWK(pubKey)
+		require.NoError(t, err)
+
+		require.Len(t, requests, 1)
+		require.Equal(t, defaultRevealValue(pubKeyJWK, 18), requests[0].RevealValue)
+
+		_, err = keys.State(id)
+		require.True(t, errors.Is(err, ErrKeyStateNotFound))
+	})
+}
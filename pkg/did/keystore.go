@@ -0,0 +1,187 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/square/go-jose/v3"
+)
+
+// ErrKeyStateNotFound is returned by a KeyStore that holds no key state for a DID
+var ErrKeyStateNotFound = errors.New("key state not found")
+
+// KeyState holds the keys a DID's last operation committed to, which sign the DID's next operations
+type KeyState struct {
+	// UpdateKey is the private key committed to by the update commitment
+	UpdateKey *jose.JSONWebKey `json:"updateKey"`
+	// RecoveryKey is the private key committed to by the recovery commitment
+	RecoveryKey *jose.JSONWebKey `json:"recoveryKey"`
+	// UpdateCommitment is the commitment to UpdateKey, if known
+	UpdateCommitment string `json:"updateCommitment,omitempty"`
+	// RecoveryCommitment is the commitment to RecoveryKey, if known
+	RecoveryCommitment string `json:"recoveryCommitment,omitempty"`
+	// PendingUpdateKey is the update key committed to by an operation that was sent but not yet known to be accepted
+	PendingUpdateKey *jose.JSONWebKey `json:"pendingUpdateKey,omitempty"`
+	// PendingRecoveryKey is the recovery key committed to by an operation that was sent but not yet known to be
+	// accepted
+	PendingRecoveryKey *jose.JSONWebKey `json:"pendingRecoveryKey,omitempty"`
+}
+
+// KeyStore persists the key state of DIDs
+type KeyStore interface {
+	// Get returns the key state of the DID, or ErrKeyStateNotFound
+	Get(did string) (*KeyState, error)
+	// Put saves the key state of the DID, replacing any previous state
+	Put(did string, state *KeyState) error
+	// Delete removes the key state of the DID
+	Delete(did string) error
+}
+
+// MemKeyStore is a KeyStore that holds key states in memory
+type MemKeyStore struct {
+	mutex  sync.RWMutex
+	states map[string]*KeyState
+}
+
+// NewMemKeyStore returns a new in-memory KeyStore
+func NewMemKeyStore() *MemKeyStore {
+	return &MemKeyStore{states: map[string]*KeyState{}}
+}
+
+// Get returns the key state of the DID, or ErrKeyStateNotFound
+func (s *MemKeyStore) Get(did string) (*KeyState, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state, ok := s.states[did]
+	if !ok {
+		return nil, ErrKeyStateNotFound
+	}
+
+	return state, nil
+}
+
+// Put saves the key state of the DID, replacing any previous state
+func (s *MemKeyStore) Put(did string, state *KeyState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.states[did] = state
+
+	return nil
+}
+
+// Delete removes the key state of the DID
+func (s *MemKeyStore) Delete(did string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.states, did)
+
+	return nil
+}
+
+// FileKeyStore is a KeyStore that saves the key state of each DID as a JSON file in a directory.
+// The files hold private keys, so they are only readable by the owner.
+type FileKeyStore struct {
+	mutex sync.Mutex
+	dir   string
+}
+
+// NewFileKeyStore returns a KeyStore saving key states in the given directory, creating the directory if needed
+func NewFileKeyStore(dir string) (*FileKeyStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("creating key store directory: %w", err)
+	}
+
+	return &FileKeyStore{dir: dir}, nil
+}
+
+func (s *FileKeyStore) path(did string) string {
+	return filepath.Join(s.dir, base64.RawURLEncoding.EncodeToString([]byte(did))+".json")
+}
+
+// Get returns the key state of the DID, or ErrKeyStateNotFound
+func (s *FileKeyStore) Get(did string) (*KeyState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(s.path(did))
+	if os.IsNotExist(err) {
+		return nil, ErrKeyStateNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading key state: %w", err)
+	}
+
+	state := &KeyState{}
+
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("parsing key state: %w", err)
+	}
+
+	return state, nil
+}
+
+// Put saves the key state of the DID, replacing any previous state.
+// The state is written to a temporary file first, so a failed write doesn't lose the previous state.
+func (s *FileKeyStore) Put(did string, state *KeyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshaling key state: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tmp, err := ioutil.TempFile(s.dir, "keystate-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating key state file: %w", err)
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close() // nolint: errcheck
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(did))
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name()) // nolint: errcheck
+
+		return fmt.Errorf("writing key state file: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the key state of the DID
+func (s *FileKeyStore) Delete(did string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.Remove(s.path(did))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting key state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyState(t *testing.T) *KeyState {
+	_, updateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return &KeyState{
+		UpdateKey:        &jose.JSONWebKey{Key: updateKey},
+		RecoveryKey:      &jose.JSONWebKey{Key: recoveryKey},
+		UpdateCommitment: "commitment",
+	}
+}
+
+func testKeyStore(t *testing.T, store KeyStore) {
+	_, err := store.Get("did:ex:123")
+	require.True(t, errors.Is(err, ErrKeyStateNotFound))
+
+	state := testKeyState(t)
+
+	require.NoError(t, store.Put("did:ex:123", state))
+
+	got, err := store.Get("did:ex:123")
+	require.NoError(t, err)
+	require.Equal(t, state.UpdateKey.Key, got.UpdateKey.Key)
+	require.Equal(t, state.RecoveryKey.Key, got.RecoveryKey.Key)
+	require.Equal(t, "commitment", got.UpdateCommitment)
+	require.Empty(t, got.RecoveryCommitment)
+
+	next := testKeyState(t)
+	next.RecoveryCommitment = "recovery"
+
+	require.NoError(t, store.Put("did:ex:123", next))
+
+	got, err = store.Get("did:ex:123")
+	require.NoError(t, err)
+	require.Equal(t, next.UpdateKey.Key, got.UpdateKey.Key)
+	require.Equal(t, "recovery", got.RecoveryCommitment)
+
+	_, err = store.Get("did:ex:456")
+	require.True(t, errors.Is(err, ErrKeyStateNotFound))
+
+	require.NoError(t, store.Delete("did:ex:123"))
+	require.NoError(t, store.Delete("did:ex:123"))
+
+	_, err = store.Get("did:ex:123")
+	require.True(t, errors.Is(err, ErrKeyStateNotFound))
+}
+
+func TestMemKeyStore(t *testing.T) {
+	testKeyStore(t, NewMemKeyStore())
+}
+
+func TestFileKeyStore(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		store, err := NewFileKeyStore(filepath.Join(t.TempDir(), "keys"))
+		require.NoError(t, err)
+
+		testKeyStore(t, store)
+	})
+
+	t.Run("test state persisted", func(t *testing.T) {
+		dir := t.TempDir()
+
+		store, err := NewFileKeyStore(dir)
+		require.NoError(t, err)
+
+		state := testKeyState(t)
+		require.NoError(t, store.Put("did:ex:123", state))
+
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+
+		reopened, err := NewFileKeyStore(dir)
+		require.NoError(t, err)
+
+		got, err := reopened.Get("did:ex:123")
+		require.NoError(t, err)
+		require.Equal(t, state.UpdateKey.Key, got.UpdateKey.Key)
+	})
+
+	t.Run("test corrupt state", func(t *testing.T) {
+		store, err := NewFileKeyStore(t.TempDir())
+		require.NoError(t, err)
+
+		require.NoError(t, ioutil.WriteFile(store.path("did:ex:123"), []byte("{"), 0600))
+
+		_, err = store.Get("did:ex:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing key state")
+	})
+
+	t.Run("test error creating directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+
+		_, err := NewFileKeyStore(filepath.Join(file, "keys"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creating key store directory")
+	})
+}
@@ -7,6 +7,11 @@ package did
 
 import (
 	"crypto/tls"
+	"net/url"
+	"time"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/httpclient"
 )
 
 // Option is a DID client instance option
@@ -19,9 +24,59 @@ func WithTLSConfig(tlsConfig *tls.Config) Option {
 	}
 }
 
-// WithAuthToken add auth token
+// WithAuthToken add auth token, sent as a bearer token with every request the client sends: sidetree operations,
+// resolutions and config fetches
 func WithAuthToken(authToken string) Option {
 	return func(opts *Client) {
-		opts.authToken = "Bearer " + authToken
+		opts.authToken = authToken
+	}
+}
+
+// WithHTTPHeader adds a header to every request the client sends
+func WithHTTPHeader(name, value string) Option {
+	return func(opts *Client) {
+		opts.httpClientOpts = append(opts.httpClientOpts, httpclient.WithHeader(name, value))
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request the client sends
+func WithUserAgent(userAgent string) Option {
+	return func(opts *Client) {
+		opts.httpClientOpts = append(opts.httpClientOpts, httpclient.WithUserAgent(userAgent))
+	}
+}
+
+// WithProxy sends every request the client sends through the proxy at the given url
+func WithProxy(proxyURL *url.URL) Option {
+	return func(opts *Client) {
+		opts.httpClientOpts = append(opts.httpClientOpts, httpclient.WithProxy(proxyURL))
+	}
+}
+
+// WithKeyLifecycle option manages the signing keys, next keys and reveal values of operations on the DIDs registered
+// with the given KeyLifecycle
+func WithKeyLifecycle(keyLifecycle *KeyLifecycle) Option {
+	return func(opts *Client) {
+		opts.keyLifecycle = keyLifecycle
+	}
+}
+
+// WithWaitUntilPublished option makes operations wait until their effect is published, by polling the resolution
+// of the DID every pollInterval, until the timeout elapses. Operations on a domain poll the endpoints discovered for
+// the domain's consortium, and are published once the consensus policy accepts the endpoints' responses; operations
+// sent to given sidetree endpoints poll the endpoint that accepted them.
+// Operations that aren't published in time fail with ErrPublishTimeout, although they were accepted.
+func WithWaitUntilPublished(timeout, pollInterval time.Duration) Option {
+	return func(opts *Client) {
+		opts.publishTimeout = timeout
+		opts.publishPollInterval = pollInterval
+	}
+}
+
+// WithConsensusPolicy sets the policy deciding whether the endpoints polled for the publication of an operation
+// agree it is published. By default, all endpoints must report it as published and agree.
+func WithConsensusPolicy(policy consensus.Policy) Option {
+	return func(opts *Client) {
+		opts.consensusPolicy = policy
 	}
 }
@@ -8,15 +8,18 @@ package deactivate
 import (
 	"crypto"
 
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/signer"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 // Opts deactivate did opts
 type Opts struct {
-	SidetreeEndpoints []*models.Endpoint
-	SigningKey        crypto.PrivateKey
-	SigningKeyID      string
-	RevealValue       string
+	SidetreeEndpoints       []*models.Endpoint
+	SigningKey              crypto.PrivateKey
+	Signer                  signer.Signer
+	SigningKeyID            string
+	RevealValue             string
+	AcceptedEndpointHandler func(endpointURL string)
 }
 
 // Option is a deactivate DID option
@@ -37,6 +40,13 @@ func WithSigningKey(signingKey crypto.PrivateKey) Option {
 	}
 }
 
+// WithSigner set a signer to sign with instead of a signing key, e.g. a signer.KMSSigner for a key held by a KMS
+func WithSigner(s signer.Signer) Option {
+	return func(opts *Opts) {
+		opts.Signer = s
+	}
+}
+
 // WithSigningKeyID set signing key id
 func WithSigningKeyID(id string) Option {
 	return func(opts *Opts) {
@@ -50,3 +60,11 @@ func WithRevealValue(rv string) Option {
 		opts.RevealValue = rv
 	}
 }
+
+// WithAcceptedEndpointHandler sets a handler called with the URL of the sidetree endpoint that accepted the deactivate
+// operation, which may not be the first endpoint tried
+func WithAcceptedEndpointHandler(handler func(endpointURL string)) Option {
+	return func(opts *Opts) {
+		opts.AcceptedEndpointHandler = handler
+	}
+}
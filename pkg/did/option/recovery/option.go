@@ -11,19 +11,22 @@ import (
 	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/doc"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/signer"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 // Opts recover did opts
 type Opts struct {
-	PublicKeys            []doc.PublicKey
-	Services              []docdid.Service
-	SidetreeEndpoints     []*models.Endpoint
-	NextRecoveryPublicKey crypto.PublicKey
-	NextUpdatePublicKey   crypto.PublicKey
-	SigningKey            crypto.PrivateKey
-	SigningKeyID          string
-	RevealValue           string
+	PublicKeys              []doc.PublicKey
+	Services                []docdid.Service
+	SidetreeEndpoints       []*models.Endpoint
+	NextRecoveryPublicKey   crypto.PublicKey
+	NextUpdatePublicKey     crypto.PublicKey
+	SigningKey              crypto.PrivateKey
+	Signer                  signer.Signer
+	SigningKeyID            string
+	RevealValue             string
+	AcceptedEndpointHandler func(endpointURL string)
 }
 
 // Option is a recover DID option
@@ -72,6 +75,13 @@ func WithSigningKey(signingKey crypto.PrivateKey) Option {
 	}
 }
 
+// WithSigner set a signer to sign with instead of a signing key, e.g. a signer.KMSSigner for a key held by a KMS
+func WithSigner(s signer.Signer) Option {
+	return func(opts *Opts) {
+		opts.Signer = s
+	}
+}
+
 // WithSigningKeyID set signing key id
 func WithSigningKeyID(id string) Option {
 	return func(opts *Opts) {
@@ -85,3 +95,11 @@ func WithRevealValue(rv string) Option {
 		opts.RevealValue = rv
 	}
 }
+
+// WithAcceptedEndpointHandler sets a handler called with the URL of the sidetree endpoint that accepted the recover
+// operation, which may not be the first endpoint tried
+func WithAcceptedEndpointHandler(handler func(endpointURL string)) Option {
+	return func(opts *Opts) {
+		opts.AcceptedEndpointHandler = handler
+	}
+}
@@ -11,6 +11,7 @@ import (
 	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/doc"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/signer"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
@@ -19,15 +20,17 @@ type Option func(opts *Opts)
 
 // Opts update did opts
 type Opts struct {
-	AddPublicKeys       []doc.PublicKey
-	AddServices         []docdid.Service
-	RemovePublicKeys    []string
-	RemoveServices      []string
-	SidetreeEndpoints   []*models.Endpoint
-	NextUpdatePublicKey crypto.PublicKey
-	SigningKey          crypto.PrivateKey
-	SigningKeyID        string
-	RevealValue         string
+	AddPublicKeys           []doc.PublicKey
+	AddServices             []docdid.Service
+	RemovePublicKeys        []string
+	RemoveServices          []string
+	SidetreeEndpoints       []*models.Endpoint
+	NextUpdatePublicKey     crypto.PublicKey
+	SigningKey              crypto.PrivateKey
+	Signer                  signer.Signer
+	SigningKeyID            string
+	RevealValue             string
+	AcceptedEndpointHandler func(endpointURL string)
 }
 
 // WithAddPublicKey set public key to be added
@@ -72,6 +75,13 @@ func WithSigningKey(signingKey crypto.PrivateKey) Option {
 	}
 }
 
+// WithSigner set a signer to sign with instead of a signing key, e.g. a signer.KMSSigner for a key held by a KMS
+func WithSigner(s signer.Signer) Option {
+	return func(opts *Opts) {
+		opts.Signer = s
+	}
+}
+
 // WithSigningKeyID set signing key id
 func WithSigningKeyID(id string) Option {
 	return func(opts *Opts) {
@@ -93,3 +103,11 @@ func WithRevealValue(rv string) Option {
 		opts.RevealValue = rv
 	}
 }
+
+// WithAcceptedEndpointHandler sets a handler called with the URL of the sidetree endpoint that accepted the update
+// operation, which may not be the first endpoint tried
+func WithAcceptedEndpointHandler(handler func(endpointURL string)) Option {
+	return func(opts *Opts) {
+		opts.AcceptedEndpointHandler = handler
+	}
+}
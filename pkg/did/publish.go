@@ -0,0 +1,307 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+const defaultPublishPollInterval = time.Second
+
+// ErrPublishTimeout is returned when an accepted operation isn't published before the wait timeout elapses
+var ErrPublishTimeout = errors.New("operation not published before timeout")
+
+// publishedFunc returns whether the resolution of a DID shows an operation was published,
+// given the http status of the resolution and the resolution if the status is 200
+type publishedFunc func(docResolution *docdid.DocResolution, status int) bool
+
+// waitUntilPublished polls the resolution of the DID until the endpoints report the operation as published, as
+// decided by the client's consensus policy, or the client's wait timeout elapses. The endpoints polled are those
+// discovered for the consortium at the domain, or the endpoint that accepted the operation if no domain was given.
+// Returns the accepted resolution of the DID, or nil if the client doesn't wait for operations to be published.
+func (c *Client) waitUntilPublished(ctx context.Context, domain, accepted, did string,
+	published publishedFunc) (*docdid.DocResolution, error) {
+	if c.publishTimeout <= 0 {
+		return nil, nil
+	}
+
+	endpoints, err := c.publishEndpoints(ctx, domain, accepted)
+	if err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, c.publishTimeout)
+	defer cancel()
+
+	interval := c.publishPollInterval
+	if interval <= 0 {
+		interval = defaultPublishPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// lastErr is the error of the last poll that wasn't aborted by the timeout
+	var lastErr error
+
+	for {
+		docResolution, err := c.resolvePublished(waitCtx, endpoints, did, published)
+		if err == nil {
+			return docResolution, nil
+		}
+
+		if lastErr == nil || waitCtx.Err() == nil {
+			lastErr = err
+		}
+
+		log.Debugf("%s not published yet: %s", did, err)
+
+		select {
+		case <-ticker.C:
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to wait for publication of %s: %w", did, ctx.Err())
+			}
+
+			return nil, fmt.Errorf("%s: %w: %s", did, ErrPublishTimeout, lastErr)
+		}
+	}
+}
+
+// waitUntilCommitmentPublished waits until the update or recover request accepted by the sidetree endpoint is
+// published, if the client waits for operations to be published
+func (c *Client) waitUntilCommitmentPublished(ctx context.Context, domain, accepted, did string, req []byte) error {
+	if c.publishTimeout <= 0 {
+		return nil
+	}
+
+	updateCommitment, err := updateCommitmentOf(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.waitUntilPublished(ctx, domain, accepted, did, commitmentPublished(updateCommitment))
+
+	return err
+}
+
+// publishEndpoints returns the endpoints to poll for the publication of an operation: the endpoints discovered for
+// the consortium at the domain, or the endpoint that accepted the operation if no domain was given
+func (c *Client) publishEndpoints(ctx context.Context, domain, accepted string) ([]*models.Endpoint, error) {
+	if domain == "" {
+		return []*models.Endpoint{{URL: accepted}}, nil
+	}
+
+	endpoints, err := c.discoveryService.GetEndpointsWithContext(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover endpoints to wait for publication: %w", err)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, errors.New("failed to discover endpoints to wait for publication: list of endpoints is empty")
+	}
+
+	return endpoints, nil
+}
+
+// resolvePublished resolves the DID at the endpoints concurrently, returning the resolution accepted by the client's
+// consensus policy. Endpoints that don't report the operation as published count as failed responses.
+func (c *Client) resolvePublished(ctx context.Context, endpoints []*models.Endpoint, did string,
+	published publishedFunc) (*docdid.DocResolution, error) {
+	resolutions := make([]*docdid.DocResolution, len(endpoints))
+	responses := make([]*consensus.Response, len(endpoints))
+
+	var wg sync.WaitGroup
+
+	for i, e := range endpoints {
+		wg.Add(1)
+
+		go func(i int, e *models.Endpoint) {
+			defer wg.Done()
+
+			resolutions[i], responses[i] = c.resolvePublishedAtEndpoint(ctx, e, did, published)
+		}(i, e)
+	}
+
+	wg.Wait()
+
+	accepted, err := c.consensusPolicy.Decide(responses)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolutions[accepted], nil
+}
+
+// resolvePublishedAtEndpoint resolves the DID at the endpoint, returning the resolution and its consensus response,
+// which fails unless the resolution reports the operation as published
+func (c *Client) resolvePublishedAtEndpoint(ctx context.Context, e *models.Endpoint, did string,
+	published publishedFunc) (*docdid.DocResolution, *consensus.Response) {
+	docResolution, status, err := c.resolveAtEndpoint(ctx, e.URL, did)
+	if err != nil {
+		return nil, &consensus.Response{Domain: e.Domain, Err: fmt.Errorf("endpoint %s: %w", e.URL, err)}
+	}
+
+	if !published(docResolution, status) {
+		return nil, &consensus.Response{Domain: e.Domain, Err: fmt.Errorf("endpoint %s: not published", e.URL)}
+	}
+
+	// deactivated DIDs may not resolve, so all endpoints reporting them agree
+	if docResolution == nil {
+		return nil, &consensus.Response{Domain: e.Domain}
+	}
+
+	doc, err := docResolution.DIDDocument.JSONBytes()
+	if err != nil {
+		return nil, &consensus.Response{Domain: e.Domain, Err: fmt.Errorf("endpoint %s: %w", e.URL, err)}
+	}
+
+	return docResolution, &consensus.Response{Domain: e.Domain, Doc: doc}
+}
+
+// resolveAtEndpoint resolves the DID at the sidetree endpoint, returning the resolution's http status and, if the
+// status is 200, the resolution
+func (c *Client) resolveAtEndpoint(ctx context.Context, endpointURL, did string,
+) (*docdid.DocResolution, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpointURL+"/identifiers/"+did, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create http request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer closeResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, nil
+	}
+
+	responseBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response : %s", err)
+	}
+
+	docResolution, err := parseDocResolution(responseBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return docResolution, resp.StatusCode, nil
+}
+
+// isPublished returns whether the method metadata of a DID resolution shows the DID's latest operation is published.
+// Resolutions without method metadata aren't taken as published.
+func isPublished(docResolution *docdid.DocResolution) bool {
+	if docResolution.DocumentMetadata == nil || docResolution.DocumentMetadata.Method == nil {
+		return false
+	}
+
+	return docResolution.DocumentMetadata.Method.Published
+}
+
+// createPublished reports a create operation as published once the DID resolves and is published
+func createPublished(docResolution *docdid.DocResolution, status int) bool {
+	return status == http.StatusOK && isPublished(docResolution)
+}
+
+// commitmentPublished returns a publishedFunc reporting an update or recover operation as published once the DID's
+// update commitment is the operation's and the operation is published
+func commitmentPublished(updateCommitment string) publishedFunc {
+	return func(docResolution *docdid.DocResolution, status int) bool {
+		if status != http.StatusOK || !isPublished(docResolution) {
+			return false
+		}
+
+		return docResolution.DocumentMetadata.Method.UpdateCommitment == updateCommitment
+	}
+}
+
+// deactivatePublished reports a deactivate operation as published once the DID no longer resolves or is reported
+// as deactivated
+func deactivatePublished(docResolution *docdid.DocResolution, status int) bool {
+	if status == http.StatusGone {
+		return true
+	}
+
+	return status == http.StatusOK && docResolution.DocumentMetadata != nil &&
+		docResolution.DocumentMetadata.Deactivated
+}
+
+// updateCommitmentOf returns the update commitment of an update or recover request
+func updateCommitmentOf(req []byte) (string, error) {
+	r := struct {
+		Delta *model.DeltaModel `json:"delta"`
+	}{}
+
+	err := json.Unmarshal(req, &r)
+	if err != nil || r.Delta == nil {
+		return "", fmt.Errorf("failed to get update commitment of request")
+	}
+
+	return r.Delta.UpdateCommitment, nil
+}
+
+// parseDocResolution parses a sidetree resolution, which is a document resolution or, from older sidetree versions,
+// a document. Sidetree reports method metadata outside the document metadata, so if the document resolution has no
+// method metadata, it is set from the sidetree method metadata.
+func parseDocResolution(response []byte) (*docdid.DocResolution, error) {
+	docResolution, err := docdid.ParseDocumentResolution(response)
+	if err != nil {
+		if !errors.Is(err, docdid.ErrDIDDocumentNotExist) {
+			return nil, fmt.Errorf("failed to parse document resolution: %w", err)
+		}
+
+		didDoc, e := docdid.ParseDocument(response)
+		if e != nil {
+			return nil, fmt.Errorf("failed to parse did document: %w", e)
+		}
+
+		return &docdid.DocResolution{DIDDocument: didDoc}, nil
+	}
+
+	if docResolution.DocumentMetadata != nil && docResolution.DocumentMetadata.Method != nil {
+		return docResolution, nil
+	}
+
+	raw := &didResolution{}
+
+	err = json.Unmarshal(response, raw)
+	if err != nil || len(raw.MethodMetadata) == 0 {
+		return docResolution, nil // nolint: nilerr
+	}
+
+	method := &docdid.MethodMetadata{}
+
+	err = json.Unmarshal(raw.MethodMetadata, method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse method metadata: %w", err)
+	}
+
+	if docResolution.DocumentMetadata == nil {
+		docResolution.DocumentMetadata = &docdid.DocumentMetadata{}
+	}
+
+	docResolution.DocumentMetadata.Method = method
+
+	return docResolution, nil
+}
@@ -0,0 +1,360 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/create"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/deactivate"
+	"github.com/trustbloc/trustbloc-did-method/pkg/did/option/update"
+	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
+	mockendpoint "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/endpoint"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+// publishingServer is a sidetree server accepting operations and resolving the DID with the given method metadata
+// until the operation is published after a number of resolutions
+type publishingServer struct {
+	mutex            sync.Mutex
+	id               string
+	unpublishedReads int
+	resolutions      int
+	updateCommitment string
+	deactivate       bool
+}
+
+func (s *publishingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.Method == http.MethodPost {
+		req := &struct {
+			Type  string `json:"type"`
+			Delta *model.DeltaModel
+		}{}
+
+		if err := json.NewDecoder(r.Body).Decode(req); err == nil && req.Delta != nil && req.Type != "create" {
+			s.updateCommitment = req.Delta.UpdateCommitment
+		}
+
+		s.deactivate = req.Type == "deactivate"
+
+		s.writeResolution(w, false)
+
+		return
+	}
+
+	s.resolutions++
+
+	published := s.resolutions > s.unpublishedReads
+
+	if s.deactivate && published {
+		w.WriteHeader(http.StatusGone)
+
+		return
+	}
+
+	s.writeResolution(w, published)
+}
+
+func (s *publishingServer) writeResolution(w http.ResponseWriter, published bool) {
+	doc, _ := (&did.Doc{ID: s.id, Context: []string{did.Context}}).JSONBytes() // nolint: errcheck
+
+	updateCommitment := "old"
+	if published {
+		updateCommitment = s.updateCommitment
+	}
+
+	methodMetadata, _ := json.Marshal(map[string]interface{}{ // nolint: errcheck
+		"published":        published,
+		"updateCommitment": updateCommitment,
+	})
+
+	b, _ := json.Marshal(didResolution{Context: "https://www.w3.org/ns/did-resolution/v1", // nolint: errcheck
+		DIDDocument: doc, MethodMetadata: methodMetadata})
+
+	_, _ = fmt.Fprint(w, string(b)) // nolint: errcheck
+}
+
+func TestClient_WaitUntilPublished(t *testing.T) {
+	newClient := func(timeout time.Duration) *Client {
+		v := New(WithWaitUntilPublished(timeout, time.Millisecond))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		return v
+	}
+
+	recoveryKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("test create", func(t *testing.T) {
+		s := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 2}
+
+		serv := httptest.NewServer(s)
+		defer serv.Close()
+
+		docResolution, _, err := newClient(time.Second).CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.NoError(t, err)
+		require.Equal(t, 3, s.resolutions)
+		require.True(t, docResolution.DocumentMetadata.Method.Published)
+	})
+
+	t.Run("test update", func(t *testing.T) {
+		s := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 2}
+
+		serv := httptest.NewServer(s)
+		defer serv.Close()
+
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		err = newClient(time.Second).UpdateDID("did:trustbloc:testnet:123", "",
+			update.WithSidetreeEndpoint(serv.URL), update.WithSigningKey(privKey),
+			update.WithNextUpdatePublicKey(pubKey), update.WithRemoveService("svc1"))
+		require.NoError(t, err)
+		require.Equal(t, 3, s.resolutions)
+	})
+
+	t.Run("test deactivate", func(t *testing.T) {
+		s := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1}
+
+		serv := httptest.NewServer(s)
+		defer serv.Close()
+
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		err = newClient(time.Second).DeactivateDID("did:trustbloc:testnet:123", "",
+			deactivate.WithSidetreeEndpoint(serv.URL), deactivate.WithSigningKey(privKey))
+		require.NoError(t, err)
+		require.Equal(t, 2, s.resolutions)
+	})
+
+	t.Run("test timeout", func(t *testing.T) {
+		s := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1000}
+
+		serv := httptest.NewServer(s)
+		defer serv.Close()
+
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		err = newClient(20*time.Millisecond).UpdateDID("did:trustbloc:testnet:123", "",
+			update.WithSidetreeEndpoint(serv.URL), update.WithSigningKey(privKey),
+			update.WithNextUpdatePublicKey(pubKey), update.WithRemoveService("svc1"))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrPublishTimeout))
+	})
+
+	t.Run("test context canceled", func(t *testing.T) {
+		s := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1000}
+
+		serv := httptest.NewServer(s)
+		defer serv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, _, err := newClient(time.Minute).CreateDIDWithContext(ctx, "", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrPublishTimeout))
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("test resolution errors are retried", func(t *testing.T) {
+		resolutions := 0
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				resolutions++
+
+				if resolutions < 3 {
+					w.WriteHeader(http.StatusNotFound)
+
+					return
+				}
+			}
+
+			_, err := fmt.Fprint(w, `{"@context":"https://www.w3.org/ns/did-resolution/v1",`+
+				`"didDocument":{"id":"did1","@context":"https://www.w3.org/ns/did/v1"},"methodMetadata":{"published":true}}`)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		docResolution, _, err := newClient(time.Second).CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.NoError(t, err)
+		require.Equal(t, 3, resolutions)
+		require.Equal(t, "did1", docResolution.DIDDocument.ID)
+	})
+
+	t.Run("test resolution without method metadata isn't published", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := fmt.Fprint(w, `{"id":"did1","@context":"https://www.w3.org/ns/did/v1"}`)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		_, _, err := newClient(20*time.Millisecond).CreateDID("", create.WithSidetreeEndpoint(serv.URL),
+			create.WithRecoveryPublicKey(recoveryKey), create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrPublishTimeout))
+		require.Contains(t, err.Error(), "not published")
+	})
+
+	// createAtDomain creates a DID at the domain, whose operation is accepted by the first of the discovered servers
+	createAtDomain := func(v *Client, servers ...*httptest.Server) error {
+		var discovered []*models.Endpoint
+
+		for i, serv := range servers {
+			discovered = append(discovered, &models.Endpoint{URL: serv.URL, Domain: fmt.Sprintf("d%d", i+1)})
+		}
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+				return discovered[:1], nil
+			}}
+		v.discoveryService = discoveryMock(discovered, nil)
+
+		_, _, err := v.CreateDID("testnet", create.WithRecoveryPublicKey(recoveryKey),
+			create.WithUpdatePublicKey(updateKey))
+
+		return err
+	}
+
+	t.Run("test discovered endpoints must all publish by default", func(t *testing.T) {
+		s1 := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1}
+		s2 := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 3}
+
+		serv1, serv2 := httptest.NewServer(s1), httptest.NewServer(s2)
+		defer serv1.Close()
+		defer serv2.Close()
+
+		require.NoError(t, createAtDomain(newClient(time.Second), serv1, serv2))
+		require.Equal(t, 4, s2.resolutions)
+	})
+
+	t.Run("test discovered endpoints with consensus policy", func(t *testing.T) {
+		s1 := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1}
+		s2 := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1000}
+
+		serv1, serv2 := httptest.NewServer(s1), httptest.NewServer(s2)
+		defer serv1.Close()
+		defer serv2.Close()
+
+		v := newClient(time.Second)
+		WithConsensusPolicy(consensus.FirstSuccess())(v)
+
+		require.NoError(t, createAtDomain(v, serv1, serv2))
+		require.Equal(t, 2, s1.resolutions)
+	})
+
+	t.Run("test discovered endpoint not publishing times out", func(t *testing.T) {
+		s1 := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1}
+		s2 := &publishingServer{id: "did:trustbloc:testnet:123", unpublishedReads: 1000}
+
+		serv1, serv2 := httptest.NewServer(s1), httptest.NewServer(s2)
+		defer serv1.Close()
+		defer serv2.Close()
+
+		err := createAtDomain(newClient(20*time.Millisecond), serv1, serv2)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrPublishTimeout))
+		require.Contains(t, err.Error(), "resolution mismatch")
+		require.Contains(t, err.Error(), "d2: endpoint "+serv2.URL+": not published")
+	})
+
+	t.Run("test discovery of endpoints to poll fails", func(t *testing.T) {
+		s := &publishingServer{id: "did:trustbloc:testnet:123"}
+
+		serv := httptest.NewServer(s)
+		defer serv.Close()
+
+		v := newClient(time.Second)
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+				return []*models.Endpoint{{URL: serv.URL}}, nil
+			}}
+		v.discoveryService = discoveryMock(nil, errors.New("discovery failed"))
+
+		_, _, err := v.CreateDID("testnet", create.WithRecoveryPublicKey(recoveryKey),
+			create.WithUpdatePublicKey(updateKey))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to discover endpoints to wait for publication: discovery failed")
+		require.Equal(t, 0, s.resolutions)
+	})
+}
+
+func Test_parseDocResolution(t *testing.T) {
+	t.Run("test sidetree method metadata", func(t *testing.T) {
+		docResolution, err := parseDocResolution([]byte(`{"@context":"https://www.w3.org/ns/did-resolution/v1",
+"didDocument":{"id":"did1","@context":"https://www.w3.org/ns/did/v1"},
+"methodMetadata":{"published":true,"updateCommitment":"uc","recoveryCommitment":"rc"}}`))
+		require.NoError(t, err)
+		require.True(t, docResolution.DocumentMetadata.Method.Published)
+		require.Equal(t, "uc", docResolution.DocumentMetadata.Method.UpdateCommitment)
+		require.Equal(t, "rc", docResolution.DocumentMetadata.Method.RecoveryCommitment)
+	})
+
+	t.Run("test invalid method metadata", func(t *testing.T) {
+		_, err := parseDocResolution([]byte(`{"@context":"https://www.w3.org/ns/did-resolution/v1",
+"didDocument":{"id":"did1","@context":"https://www.w3.org/ns/did/v1"},"methodMetadata":{"published":"x"}}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse method metadata")
+	})
+
+	t.Run("test document", func(t *testing.T) {
+		docResolution, err := parseDocResolution([]byte(`{"id":"did1","@context":"https://www.w3.org/ns/did/v1"}`))
+		require.NoError(t, err)
+		require.Equal(t, "did1", docResolution.DIDDocument.ID)
+		require.False(t, isPublished(docResolution))
+	})
+
+	t.Run("test invalid document", func(t *testing.T) {
+		_, err := parseDocResolution([]byte(`{"didDocument":{}}`))
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "failed to parse"))
+	})
+}
+
+func Test_updateCommitmentOf(t *testing.T) {
+	_, err := updateCommitmentOf([]byte("{}"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to get update commitment")
+}
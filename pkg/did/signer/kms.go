@@ -0,0 +1,132 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/trustbloc/sidetree-core-go/pkg/jws"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+)
+
+const p256KeySize = 32
+
+// KMSSigner signs with a key held by a KMS, so the private key never leaves the KMS
+type KMSSigner struct {
+	crypto    ariescrypto.Crypto
+	kh        interface{}
+	keyType   kms.KeyType
+	publicKey *jws.JWK
+}
+
+// NewKMSSigner returns a signer for the key with the given ID in the key manager, signing with the given crypto.
+// The key type must be kms.ED25519Type, kms.ECDSAP256TypeIEEEP1363 or kms.ECDSAP256TypeDER.
+func NewKMSSigner(keyManager kms.KeyManager, crypto ariescrypto.Crypto, keyID string,
+	keyType kms.KeyType) (*KMSSigner, error) {
+	pubKeyBytes, err := keyManager.ExportPubKeyBytes(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export public key %s: %w", keyID, err)
+	}
+
+	publicKey, err := publicKeyJWK(pubKeyBytes, keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	kh, err := keyManager.Get(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", keyID, err)
+	}
+
+	return &KMSSigner{crypto: crypto, kh: kh, keyType: keyType, publicKey: publicKey}, nil
+}
+
+// Sign signs data with the KMS key
+func (s *KMSSigner) Sign(data []byte) ([]byte, error) {
+	signature, err := s.crypto.Sign(data, s.kh)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.keyType == kms.ECDSAP256TypeDER {
+		return derToIEEEP1363(signature)
+	}
+
+	return signature, nil
+}
+
+// Algorithm returns the JWS algorithm of the signatures
+func (s *KMSSigner) Algorithm() string {
+	if s.keyType == kms.ED25519Type {
+		return EdDSA
+	}
+
+	return ES256
+}
+
+// PublicKeyJWK returns the public key of the KMS key
+func (s *KMSSigner) PublicKeyJWK() *jws.JWK {
+	return s.publicKey
+}
+
+func publicKeyJWK(pubKeyBytes []byte, keyType kms.KeyType) (*jws.JWK, error) {
+	switch keyType {
+	case kms.ED25519Type:
+		if len(pubKeyBytes) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key")
+		}
+
+		return pubkey.GetPublicKeyJWK(ed25519.PublicKey(pubKeyBytes))
+	case kms.ECDSAP256TypeIEEEP1363:
+		x, y := elliptic.Unmarshal(elliptic.P256(), pubKeyBytes)
+		if x == nil {
+			return nil, fmt.Errorf("invalid P-256 public key")
+		}
+
+		return pubkey.GetPublicKeyJWK(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y})
+	case kms.ECDSAP256TypeDER:
+		// keys with DER signatures are exported in PKIX format
+		key, err := x509.ParsePKIXPublicKey(pubKeyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid P-256 public key: %w", err)
+		}
+
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("invalid P-256 public key")
+		}
+
+		return pubkey.GetPublicKeyJWK(ecKey)
+	default:
+		return nil, fmt.Errorf("key type %s not supported", keyType)
+	}
+}
+
+// derToIEEEP1363 converts an ASN.1 DER ECDSA P-256 signature to the r||s format used by JWS
+func derToIEEEP1363(signature []byte) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+
+	rest, err := asn1.Unmarshal(signature, &sig)
+	if err != nil || len(rest) != 0 || sig.R.BitLen() > 8*p256KeySize || sig.S.BitLen() > 8*p256KeySize {
+		return nil, fmt.Errorf("invalid DER signature")
+	}
+
+	out := make([]byte, 2*p256KeySize)
+	sig.R.FillBytes(out[:p256KeySize])
+	sig.S.FillBytes(out[p256KeySize:])
+
+	return out, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (p *kmsProvider) StorageProvider() storage.Provider {
+	return p.storageProvider
+}
+
+func (p *kmsProvider) SecretLock() secretlock.Service {
+	return p.secretLock
+}
+
+// mockKeyManager wraps a key manager, failing Get if getErr is set
+type mockKeyManager struct {
+	kms.KeyManager
+	getErr error
+}
+
+func (m *mockKeyManager) Get(keyID string) (interface{}, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+
+	return m.KeyManager.Get(keyID)
+}
+
+func TestKMSSigner(t *testing.T) {
+	keyManager, err := localkms.New("local-lock://test/key/uri",
+		&kmsProvider{storageProvider: mem.NewProvider(), secretLock: &noop.NoLock{}})
+	require.NoError(t, err)
+
+	crypto, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	data := []byte("data")
+
+	t.Run("test ed25519", func(t *testing.T) {
+		keyID, pubKeyBytes, err := keyManager.CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+
+		s, err := NewKMSSigner(keyManager, crypto, keyID, kms.ED25519Type)
+		require.NoError(t, err)
+		require.Equal(t, EdDSA, s.Algorithm())
+		require.Equal(t, "Ed25519", s.PublicKeyJWK().Crv)
+
+		signature, err := s.Sign(data)
+		require.NoError(t, err)
+		require.True(t, ed25519.Verify(pubKeyBytes, data, signature))
+	})
+
+	t.Run("test ecdsa P-256 IEEE P1363", func(t *testing.T) {
+		keyID, pubKeyBytes, err := keyManager.CreateAndExportPubKeyBytes(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		s, err := NewKMSSigner(keyManager, crypto, keyID, kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+		require.Equal(t, ES256, s.Algorithm())
+		require.Equal(t, "P-256", s.PublicKeyJWK().Crv)
+
+		signature, err := s.Sign(data)
+		require.NoError(t, err)
+
+		x, y := elliptic.Unmarshal(elliptic.P256(), pubKeyBytes)
+		verifyES256(t, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, data, signature)
+	})
+
+	t.Run("test ecdsa P-256 DER", func(t *testing.T) {
+		keyID, pubKeyBytes, err := keyManager.CreateAndExportPubKeyBytes(kms.ECDSAP256TypeDER)
+		require.NoError(t, err)
+
+		s, err := NewKMSSigner(keyManager, crypto, keyID, kms.ECDSAP256TypeDER)
+		require.NoError(t, err)
+		require.Equal(t, ES256, s.Algorithm())
+		require.Equal(t, "P-256", s.PublicKeyJWK().Crv)
+
+		signature, err := s.Sign(data)
+		require.NoError(t, err)
+
+		publicKey, err := x509.ParsePKIXPublicKey(pubKeyBytes)
+		require.NoError(t, err)
+		verifyES256(t, publicKey.(*ecdsa.PublicKey), data, signature)
+
+		_, err = NewKMSSigner(keyManager, crypto, keyID, kms.ECDSAP256TypeIEEEP1363)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid P-256 public key")
+	})
+
+	t.Run("test key type not supported", func(t *testing.T) {
+		keyID, _, err := keyManager.CreateAndExportPubKeyBytes(kms.ECDSAP384TypeIEEEP1363)
+		require.NoError(t, err)
+
+		_, err = NewKMSSigner(keyManager, crypto, keyID, kms.ECDSAP384TypeIEEEP1363)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not supported")
+	})
+
+	t.Run("test key type mismatch", func(t *testing.T) {
+		keyID, _, err := keyManager.CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+
+		_, err = NewKMSSigner(keyManager, crypto, keyID, kms.ECDSAP256TypeIEEEP1363)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid P-256 public key")
+
+		_, err = NewKMSSigner(keyManager, crypto, keyID, kms.ECDSAP256TypeDER)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid P-256 public key")
+
+		_, err = NewKMSSigner(keyManager, crypto, keyID+"x", kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to export public key")
+	})
+
+	t.Run("test error getting key", func(t *testing.T) {
+		keyID, _, err := keyManager.CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+
+		_, err = NewKMSSigner(&mockKeyManager{KeyManager: keyManager, getErr: errors.New("get error")},
+			crypto, keyID, kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get error")
+	})
+
+	t.Run("test invalid ed25519 public key", func(t *testing.T) {
+		_, err := publicKeyJWK([]byte("key"), kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid ed25519 public key")
+	})
+}
+
+func TestDERToIEEEP1363(t *testing.T) {
+	_, err := derToIEEEP1363([]byte("signature"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid DER signature")
+}
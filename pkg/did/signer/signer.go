@@ -0,0 +1,80 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/jws"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/ecsigner"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/edsigner"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/client"
+)
+
+const (
+	// EdDSA is the JWS algorithm of ed25519 signatures
+	EdDSA = "EdDSA"
+	// ES256 is the JWS algorithm of ECDSA P-256 signatures
+	ES256 = "ES256"
+)
+
+// Signer signs sidetree operations. The signing key may be held outside the process, e.g. by a KMS.
+type Signer interface {
+	// Sign signs data, returning a signature in the JWS format of the signer's algorithm
+	Sign(data []byte) ([]byte, error)
+	// Algorithm returns the JWS algorithm of the signatures
+	Algorithm() string
+	// PublicKeyJWK returns the public key of the signing key, which the signed operations reveal
+	PublicKeyJWK() *jws.JWK
+}
+
+// PrivateKeySigner signs with a private key held in memory
+type PrivateKeySigner struct {
+	signer    client.Signer
+	alg       string
+	publicKey *jws.JWK
+}
+
+// NewPrivateKeySigner returns a signer for an ed25519.PrivateKey or *ecdsa.PrivateKey
+func NewPrivateKeySigner(privateKey crypto.PrivateKey) (*PrivateKeySigner, error) {
+	switch key := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		publicKey, err := pubkey.GetPublicKeyJWK(key.Public())
+		if err != nil {
+			return nil, err
+		}
+
+		return &PrivateKeySigner{signer: ecsigner.New(key, ES256, ""), alg: ES256, publicKey: publicKey}, nil
+	case ed25519.PrivateKey:
+		publicKey, err := pubkey.GetPublicKeyJWK(key.Public())
+		if err != nil {
+			return nil, err
+		}
+
+		return &PrivateKeySigner{signer: edsigner.New(key, EdDSA, ""), alg: EdDSA, publicKey: publicKey}, nil
+	default:
+		return nil, fmt.Errorf("key not supported")
+	}
+}
+
+// Sign signs data
+func (s *PrivateKeySigner) Sign(data []byte) ([]byte, error) {
+	return s.signer.Sign(data)
+}
+
+// Algorithm returns the JWS algorithm of the signatures
+func (s *PrivateKeySigner) Algorithm() string {
+	return s.alg
+}
+
+// PublicKeyJWK returns the public key of the signing key
+func (s *PrivateKeySigner) PublicKeyJWK() *jws.JWK {
+	return s.publicKey
+}
@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+)
+
+func verifyES256(t *testing.T, publicKey *ecdsa.PublicKey, data, signature []byte) {
+	require.Len(t, signature, 2*p256KeySize)
+
+	hash := sha256.Sum256(data)
+	r := new(big.Int).SetBytes(signature[:p256KeySize])
+	s := new(big.Int).SetBytes(signature[p256KeySize:])
+
+	require.True(t, ecdsa.Verify(publicKey, hash[:], r, s))
+}
+
+func TestPrivateKeySigner(t *testing.T) {
+	data := []byte("data")
+
+	t.Run("test ed25519", func(t *testing.T) {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		s, err := NewPrivateKeySigner(privateKey)
+		require.NoError(t, err)
+		require.Equal(t, EdDSA, s.Algorithm())
+
+		jwk, err := pubkey.GetPublicKeyJWK(publicKey)
+		require.NoError(t, err)
+		require.Equal(t, jwk, s.PublicKeyJWK())
+
+		signature, err := s.Sign(data)
+		require.NoError(t, err)
+		require.True(t, ed25519.Verify(publicKey, data, signature))
+	})
+
+	t.Run("test ecdsa", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		s, err := NewPrivateKeySigner(privateKey)
+		require.NoError(t, err)
+		require.Equal(t, ES256, s.Algorithm())
+
+		jwk, err := pubkey.GetPublicKeyJWK(&privateKey.PublicKey)
+		require.NoError(t, err)
+		require.Equal(t, jwk, s.PublicKeyJWK())
+
+		signature, err := s.Sign(data)
+		require.NoError(t, err)
+		verifyES256(t, &privateKey.PublicKey, data, signature)
+	})
+
+	t.Run("test key not supported", func(t *testing.T) {
+		_, err := NewPrivateKeySigner("key")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key not supported")
+	})
+}
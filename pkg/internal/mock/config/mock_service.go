@@ -6,14 +6,18 @@ SPDX-License-Identifier: Apache-2.0
 package config
 
 import (
+	"context"
+
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 // MockConfigService implements a mock config service
 type MockConfigService struct {
-	GetConsortiumFunc     func(string, string) (*models.ConsortiumFileData, error)
-	GetStakeholderFunc    func(string, string) (*models.StakeholderFileData, error)
-	GetSidetreeConfigFunc func(string) (*models.SidetreeConfig, error)
+	GetConsortiumFunc         func(string, string) (*models.ConsortiumFileData, error)
+	GetConsortiumHistoryFunc  func(string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderFunc        func(string, string) (*models.StakeholderFileData, error)
+	GetStakeholderHistoryFunc func(string, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigFunc     func(string) (*models.SidetreeConfig, error)
 }
 
 // GetConsortium get the consortium config file for a given domain from the given url
@@ -25,6 +29,15 @@ func (m *MockConfigService) GetConsortium(url, domain string) (*models.Consortiu
 	return nil, nil
 }
 
+// GetConsortiumHistory get the historical consortium config file with the given hash from the given url
+func (m *MockConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	if m.GetConsortiumHistoryFunc != nil {
+		return m.GetConsortiumHistoryFunc(url, hash, hashAlgorithm)
+	}
+
+	return nil, nil
+}
+
 // GetStakeholder get the stakeholder config file for a given domain from the given url
 func (m *MockConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
 	if m.GetStakeholderFunc != nil {
@@ -34,6 +47,16 @@ func (m *MockConfigService) GetStakeholder(url, domain string) (*models.Stakehol
 	return nil, nil
 }
 
+// GetStakeholderHistory get the historical stakeholder config file with the given hash from the given url
+func (m *MockConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	if m.GetStakeholderHistoryFunc != nil {
+		return m.GetStakeholderHistoryFunc(url, hash, hashAlgorithm)
+	}
+
+	return nil, nil
+}
+
 // GetSidetreeConfig get the sidetree config
 func (m *MockConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
 	if m.GetSidetreeConfigFunc != nil {
@@ -42,3 +65,33 @@ func (m *MockConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfi
 
 	return nil, nil
 }
+
+// GetConsortiumWithContext get the consortium config file for a given domain from the given url
+func (m *MockConfigService) GetConsortiumWithContext(_ context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	return m.GetConsortium(url, domain)
+}
+
+// GetConsortiumHistoryWithContext get the historical consortium config file with the given hash from the given url
+func (m *MockConfigService) GetConsortiumHistoryWithContext(_ context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	return m.GetConsortiumHistory(url, hash, hashAlgorithm)
+}
+
+// GetStakeholderWithContext get the stakeholder config file for a given domain from the given url
+func (m *MockConfigService) GetStakeholderWithContext(_ context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	return m.GetStakeholder(url, domain)
+}
+
+// GetStakeholderHistoryWithContext get the historical stakeholder config file with the given hash from the given url
+func (m *MockConfigService) GetStakeholderHistoryWithContext(_ context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	return m.GetStakeholderHistory(url, hash, hashAlgorithm)
+}
+
+// GetSidetreeConfigWithContext get the sidetree config
+func (m *MockConfigService) GetSidetreeConfigWithContext(_ context.Context, url string,
+) (*models.SidetreeConfig, error) {
+	return m.GetSidetreeConfig(url)
+}
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package didconfiguration
 
 import (
+	"context"
+
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 )
 
@@ -22,3 +24,8 @@ func (m *MockDIDConfigService) VerifyStakeholder(domain string, doc *did.Doc) er
 
 	return nil
 }
+
+// VerifyStakeholderWithContext fetch and verify a did configuration for a given stakeholder
+func (m *MockDIDConfigService) VerifyStakeholderWithContext(_ context.Context, domain string, doc *did.Doc) error {
+	return m.VerifyStakeholder(domain, doc)
+}
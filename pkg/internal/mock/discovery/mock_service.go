@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package discovery
 
 import (
+	"context"
+
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
@@ -22,3 +24,8 @@ func (m *MockDiscoveryService) GetEndpoints(domain string) ([]*models.Endpoint,
 
 	return nil, nil
 }
+
+// GetEndpointsWithContext discover endpoints from a consortium
+func (m *MockDiscoveryService) GetEndpointsWithContext(_ context.Context, domain string) ([]*models.Endpoint, error) {
+	return m.GetEndpoints(domain)
+}
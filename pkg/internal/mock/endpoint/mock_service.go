@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package endpoint
 
 import (
+	"context"
+
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
@@ -22,3 +24,8 @@ func (m *MockEndpointService) GetEndpoints(domain string) ([]*models.Endpoint, e
 
 	return nil, nil
 }
+
+// GetEndpointsWithContext discover endpoints for a consortium domain
+func (m *MockEndpointService) GetEndpointsWithContext(_ context.Context, domain string) ([]*models.Endpoint, error) {
+	return m.GetEndpoints(domain)
+}
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package discovery
 
 import (
+	"context"
+
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
@@ -22,3 +24,9 @@ func (m *MockSelectionService) SelectEndpoints(domain string, endpoints []*model
 
 	return nil, nil
 }
+
+// SelectEndpointsWithContext select endpoints
+func (m *MockSelectionService) SelectEndpointsWithContext(_ context.Context, domain string,
+	endpoints []*models.Endpoint) ([]*models.Endpoint, error) {
+	return m.SelectEndpoints(domain, endpoints)
+}
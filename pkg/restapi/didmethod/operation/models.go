@@ -76,3 +76,45 @@ type Service struct {
 	RoutingKeys   []string `json:"routingKeys,omitempty"`
 	Endpoint      string   `json:"serviceEndpoint,omitempty"`
 }
+
+// ConsortiumStatus is the outcome of validating a consortium, with the status of each stakeholder queried
+type ConsortiumStatus struct {
+	Domain       string              `json:"domain"`
+	Valid        bool                `json:"valid"`
+	Error        string              `json:"error,omitempty"`
+	Required     int                 `json:"required"`
+	Endorsed     int                 `json:"endorsed"`
+	LifetimeMS   int64               `json:"lifetimeMs,omitempty"`
+	LatencyMS    int64               `json:"latencyMs"`
+	Stakeholders []StakeholderStatus `json:"stakeholders"`
+}
+
+// StakeholderStatus is the outcome of verifying a stakeholder's endorsement of a consortium
+type StakeholderStatus struct {
+	Domain                      string `json:"domain"`
+	ConfigFetched               bool   `json:"configFetched"`
+	DIDResolved                 bool   `json:"didResolved"`
+	ResolvedVia                 string `json:"resolvedVia,omitempty"`
+	DIDConfigurationValid       bool   `json:"didConfigurationValid"`
+	ConsortiumSignatureVerified bool   `json:"consortiumSignatureVerified"`
+	SelfSignatureVerified       bool   `json:"selfSignatureVerified"`
+	LatencyMS                   int64  `json:"latencyMs"`
+	Error                       string `json:"error,omitempty"`
+}
+
+// CacheStatus lists the configs cached by the resolver
+type CacheStatus struct {
+	Entries []CacheEntryStatus `json:"entries"`
+}
+
+// CacheEntryStatus describes a cached config
+type CacheEntryStatus struct {
+	Kind        string `json:"kind"`
+	URL         string `json:"url"`
+	Domain      string `json:"domain,omitempty"`
+	Consortium  string `json:"consortium,omitempty"`
+	Expiry      string `json:"expiry,omitempty"`
+	Error       string `json:"error,omitempty"`
+	ErrorExpiry string `json:"errorExpiry,omitempty"`
+	Updated     bool   `json:"updated,omitempty"`
+}
@@ -15,8 +15,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
@@ -27,12 +29,16 @@ import (
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/doc"
 	"github.com/trustbloc/trustbloc-did-method/pkg/internal/common/support"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 const (
 	registerBasePath     = "/1.0"
 	registerPath         = registerBasePath + "/register"
 	resolveDIDEndpoint   = "/resolveDID"
+	consortiumStatusPath = "/consortium/{domain}/status"
+	cacheAdminPath       = "/admin/cache"
+	cacheAdminDomainPath = cacheAdminPath + "/{domain}"
 	didLDJson            = "application/did+ld+json"
 	invalidRequestErrMsg = "invalid request"
 
@@ -49,10 +55,23 @@ type Handler interface {
 	Handle() http.HandlerFunc
 }
 
+type consortiumValidator interface {
+	ConsortiumReport(consortiumDomain string) (*trustbloc.ConsortiumReport, error)
+}
+
+type cacheAdmin interface {
+	CacheEntries() []*trustbloc.CacheEntry
+	EvictCache(domain string) error
+	FlushCache() error
+}
+
 // Operation defines handlers
 type Operation struct {
-	blocVDRI   vdr.VDR
-	blocDomain string
+	blocVDRI            vdr.VDR
+	blocDomain          string
+	consortiumValidator consortiumValidator
+	cacheAdmin          cacheAdmin
+	enableCacheAdmin    bool
 }
 
 // GenesisFileConfig defines a genesis file for the trustbloc did method vdri
@@ -70,6 +89,12 @@ type Config struct {
 	SidetreeWriteToken string
 	EnableSignatures   bool
 	GenesisFiles       []GenesisFileConfig
+	// RetryPolicy is the policy for retrying failed consortium and stakeholder requests, if not the VDRI default
+	RetryPolicy *retry.Policy
+	// CacheDir is the directory caching verified consortium and stakeholder configs across restarts. Optional.
+	CacheDir string
+	// EnableCacheAdmin enables the endpoints listing and evicting cached configs, in resolver and combined modes
+	EnableCacheAdmin bool
 }
 
 // New returns did method operation instance
@@ -81,11 +106,22 @@ func New(config *Config) *Operation {
 		trustbloc.WithDomain(config.BlocDomain),
 	}
 
+	if config.RetryPolicy != nil {
+		vdriOpts = append(vdriOpts, trustbloc.WithRetryPolicy(config.RetryPolicy))
+	}
+
+	if config.CacheDir != "" {
+		vdriOpts = append(vdriOpts, trustbloc.WithCacheDir(config.CacheDir))
+	}
+
 	for _, genesisFile := range config.GenesisFiles {
 		vdriOpts = append(vdriOpts, trustbloc.UseGenesisFile(genesisFile.URL, genesisFile.URL, genesisFile.Data))
 	}
 
-	return &Operation{blocVDRI: trustbloc.New(vdriOpts...), blocDomain: config.BlocDomain}
+	blocVDRI := trustbloc.New(vdriOpts...)
+
+	return &Operation{blocVDRI: blocVDRI, blocDomain: config.BlocDomain, consortiumValidator: blocVDRI,
+		cacheAdmin: blocVDRI, enableCacheAdmin: config.EnableCacheAdmin}
 }
 
 func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request) { //nolint: funlen
@@ -232,6 +268,110 @@ func (o *Operation) resolveDIDHandler(rw http.ResponseWriter, req *http.Request)
 	}
 }
 
+// consortiumStatusHandler responds with the report of the last validation of the consortium of the bloc domain, with
+// each stakeholder's verification. The consortium is only validated again once that validation expires, so requests
+// can't make the server validate it on demand. Responds with status 503 if the consortium doesn't validate, and with
+// status 404 for other domains, so requests can't make the server fetch configs from arbitrary hosts.
+func (o *Operation) consortiumStatusHandler(rw http.ResponseWriter, req *http.Request) {
+	domain := mux.Vars(req)["domain"]
+	if domain != o.blocDomain {
+		o.writeErrorResponse(rw, http.StatusNotFound, fmt.Sprintf("consortium %s is not served", domain))
+
+		return
+	}
+
+	report, err := o.consortiumValidator.ConsortiumReport(domain)
+	if err != nil {
+		log.Warnf("consortium %s invalid: %s", report.Domain, err.Error())
+	}
+
+	status := ConsortiumStatus{
+		Domain:     report.Domain,
+		Valid:      report.Valid,
+		Error:      report.Error,
+		Required:   report.Required,
+		Endorsed:   report.Endorsed,
+		LifetimeMS: report.Lifetime.Milliseconds(),
+		LatencyMS:  report.Latency.Milliseconds(),
+	}
+
+	for _, s := range report.Stakeholders {
+		status.Stakeholders = append(status.Stakeholders, StakeholderStatus{
+			Domain:                      s.Domain,
+			ConfigFetched:               s.ConfigFetched,
+			DIDResolved:                 s.DIDResolved,
+			ResolvedVia:                 s.ResolvedVia,
+			DIDConfigurationValid:       s.DIDConfigurationValid,
+			ConsortiumSignatureVerified: s.ConsortiumSignatureVerified,
+			SelfSignatureVerified:       s.SelfSignatureVerified,
+			LatencyMS:                   s.Latency.Milliseconds(),
+			Error:                       s.Error,
+		})
+	}
+
+	rw.Header().Set("Content-type", "application/json")
+
+	if !report.Valid {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	o.writeResponse(rw, status)
+}
+
+// listCacheHandler lists the cached configs, optionally only those whose url, domain or consortium is the requested
+// domain
+func (o *Operation) listCacheHandler(rw http.ResponseWriter, req *http.Request) {
+	domain, filtered := mux.Vars(req)["domain"]
+
+	status := CacheStatus{Entries: []CacheEntryStatus{}}
+
+	for _, e := range o.cacheAdmin.CacheEntries() {
+		if filtered && e.URL != domain && e.Domain != domain && e.Consortium != domain {
+			continue
+		}
+
+		entry := CacheEntryStatus{Kind: e.Kind, URL: e.URL, Domain: e.Domain, Consortium: e.Consortium, Error: e.Error,
+			Updated: e.Updated}
+
+		if !e.Expiry.IsZero() {
+			entry.Expiry = e.Expiry.UTC().Format(time.RFC3339)
+		}
+
+		if !e.ErrorExpiry.IsZero() {
+			entry.ErrorExpiry = e.ErrorExpiry.UTC().Format(time.RFC3339)
+		}
+
+		status.Entries = append(status.Entries, entry)
+	}
+
+	rw.Header().Set("Content-type", "application/json")
+
+	o.writeResponse(rw, status)
+}
+
+// evictCacheHandler evicts the cached configs of the requested domain, or flushes all cached configs
+func (o *Operation) evictCacheHandler(rw http.ResponseWriter, req *http.Request) {
+	var err error
+
+	if domain, ok := mux.Vars(req)["domain"]; ok {
+		log.Infof("evicting %s from config cache", domain)
+
+		err = o.cacheAdmin.EvictCache(domain)
+	} else {
+		log.Infof("flushing config cache")
+
+		err = o.cacheAdmin.FlushCache()
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
 // writeErrorResponse writes interface value to response
 func (o *Operation) writeErrorResponse(rw http.ResponseWriter, status int, msg string) {
 	rw.WriteHeader(status)
@@ -255,8 +395,19 @@ func (o *Operation) registrarHandlers() []Handler {
 }
 
 func (o *Operation) resolverHandlers() []Handler {
-	return []Handler{
-		support.NewHTTPHandler(resolveDIDEndpoint, http.MethodGet, o.resolveDIDHandler)}
+	handlers := []Handler{
+		support.NewHTTPHandler(resolveDIDEndpoint, http.MethodGet, o.resolveDIDHandler),
+		support.NewHTTPHandler(consortiumStatusPath, http.MethodGet, o.consortiumStatusHandler)}
+
+	if o.enableCacheAdmin {
+		handlers = append(handlers,
+			support.NewHTTPHandler(cacheAdminPath, http.MethodGet, o.listCacheHandler),
+			support.NewHTTPHandler(cacheAdminDomainPath, http.MethodGet, o.listCacheHandler),
+			support.NewHTTPHandler(cacheAdminPath, http.MethodDelete, o.evictCacheHandler),
+			support.NewHTTPHandler(cacheAdminDomainPath, http.MethodDelete, o.evictCacheHandler))
+	}
+
+	return handlers
 }
 
 // GetRESTHandlers get all controller API handler available for this service
@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
@@ -28,6 +29,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/did/doc"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc"
 )
 
 func TestNew(t *testing.T) {
@@ -37,9 +39,10 @@ func TestNew(t *testing.T) {
 		handlers, err := svc.GetRESTHandlers(combinedMode)
 		require.NoError(t, err)
 		require.NotEmpty(t, handlers)
-		require.Equal(t, 2, len(handlers))
+		require.Equal(t, 3, len(handlers))
 		require.Equal(t, registerPath, handlers[0].Path())
 		require.Equal(t, resolveDIDEndpoint, handlers[1].Path())
+		require.Equal(t, consortiumStatusPath, handlers[2].Path())
 	})
 
 	t.Run("test registrar mode", func(t *testing.T) {
@@ -58,8 +61,26 @@ func TestNew(t *testing.T) {
 		handlers, err := svc.GetRESTHandlers(resolverMode)
 		require.NoError(t, err)
 		require.NotEmpty(t, handlers)
-		require.Equal(t, 1, len(handlers))
+		require.Equal(t, 2, len(handlers))
 		require.Equal(t, resolveDIDEndpoint, handlers[0].Path())
+		require.Equal(t, consortiumStatusPath, handlers[1].Path())
+	})
+
+	t.Run("test cache admin", func(t *testing.T) {
+		svc := New(&Config{EnableCacheAdmin: true, CacheDir: t.TempDir()})
+		require.NotNil(t, svc)
+
+		handlers, err := svc.GetRESTHandlers(resolverMode)
+		require.NoError(t, err)
+		require.Equal(t, 6, len(handlers))
+		require.Equal(t, cacheAdminPath, handlers[2].Path())
+		require.Equal(t, http.MethodGet, handlers[2].Method())
+		require.Equal(t, cacheAdminDomainPath, handlers[5].Path())
+		require.Equal(t, http.MethodDelete, handlers[5].Method())
+
+		handlers, err = svc.GetRESTHandlers(registrarMode)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(handlers))
 	})
 
 	t.Run("test invalid mode", func(t *testing.T) {
@@ -259,6 +280,240 @@ func TestResolveDIDHandler(t *testing.T) {
 	})
 }
 
+type consortiumValidatorFunc func(consortiumDomain string) (*trustbloc.ConsortiumReport, error)
+
+func (f consortiumValidatorFunc) ConsortiumReport(consortiumDomain string,
+) (*trustbloc.ConsortiumReport, error) {
+	return f(consortiumDomain)
+}
+
+func TestConsortiumStatusHandler(t *testing.T) {
+	t.Run("test valid consortium", func(t *testing.T) {
+		svc := New(&Config{BlocDomain: "consortium.example.com"})
+		svc.consortiumValidator = consortiumValidatorFunc(
+			func(consortiumDomain string) (*trustbloc.ConsortiumReport, error) {
+				return &trustbloc.ConsortiumReport{
+					Domain:   consortiumDomain,
+					Valid:    true,
+					Required: 1,
+					Endorsed: 1,
+					Lifetime: time.Minute,
+					Latency:  2 * time.Second,
+					Stakeholders: []*trustbloc.StakeholderReport{{
+						Domain:                      "stakeholder.one",
+						ConfigFetched:               true,
+						DIDResolved:                 true,
+						ResolvedVia:                 "https://stakeholder.one/sidetree/0.0.1",
+						DIDConfigurationValid:       true,
+						ConsortiumSignatureVerified: true,
+						SelfSignatureVerified:       true,
+						Latency:                     time.Second,
+					}},
+				}, nil
+			})
+
+		handler := handlerLookup(t, svc, consortiumStatusPath)
+
+		body, status, err := handleRequest(handler, "/consortium/consortium.example.com/status", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var consortiumStatus ConsortiumStatus
+		require.NoError(t, json.Unmarshal(body.Bytes(), &consortiumStatus))
+		require.Equal(t, ConsortiumStatus{
+			Domain:     "consortium.example.com",
+			Valid:      true,
+			Required:   1,
+			Endorsed:   1,
+			LifetimeMS: 60000,
+			LatencyMS:  2000,
+			Stakeholders: []StakeholderStatus{{
+				Domain:                      "stakeholder.one",
+				ConfigFetched:               true,
+				DIDResolved:                 true,
+				ResolvedVia:                 "https://stakeholder.one/sidetree/0.0.1",
+				DIDConfigurationValid:       true,
+				ConsortiumSignatureVerified: true,
+				SelfSignatureVerified:       true,
+				LatencyMS:                   1000,
+			}},
+		}, consortiumStatus)
+	})
+
+	t.Run("test invalid consortium", func(t *testing.T) {
+		svc := New(&Config{BlocDomain: "consortium.example.com"})
+		svc.consortiumValidator = consortiumValidatorFunc(
+			func(consortiumDomain string) (*trustbloc.ConsortiumReport, error) {
+				return &trustbloc.ConsortiumReport{
+					Domain:   consortiumDomain,
+					Error:    "insufficient stakeholder endorsement",
+					Required: 1,
+					Stakeholders: []*trustbloc.StakeholderReport{{
+						Domain: "stakeholder.one",
+						Error:  "stakeholder stakeholder.one down",
+					}},
+				}, fmt.Errorf("insufficient stakeholder endorsement")
+			})
+
+		handler := handlerLookup(t, svc, consortiumStatusPath)
+
+		body, status, err := handleRequest(handler, "/consortium/consortium.example.com/status", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, status)
+
+		var consortiumStatus ConsortiumStatus
+		require.NoError(t, json.Unmarshal(body.Bytes(), &consortiumStatus))
+		require.False(t, consortiumStatus.Valid)
+		require.Equal(t, "insufficient stakeholder endorsement", consortiumStatus.Error)
+		require.Len(t, consortiumStatus.Stakeholders, 1)
+		require.False(t, consortiumStatus.Stakeholders[0].ConfigFetched)
+		require.Equal(t, "stakeholder stakeholder.one down", consortiumStatus.Stakeholders[0].Error)
+	})
+
+	t.Run("test consortium of other domain", func(t *testing.T) {
+		svc := New(&Config{BlocDomain: "consortium.example.com"})
+		svc.consortiumValidator = consortiumValidatorFunc(
+			func(consortiumDomain string) (*trustbloc.ConsortiumReport, error) {
+				require.Fail(t, "consortium of other domain validated")
+
+				return nil, nil
+			})
+
+		handler := handlerLookup(t, svc, consortiumStatusPath)
+
+		body, status, err := handleRequest(handler, "/consortium/internal.example.com/status", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Equal(t, "consortium internal.example.com is not served", body.String())
+	})
+}
+
+type mockCacheAdmin struct {
+	entries  []*trustbloc.CacheEntry
+	evicted  []string
+	flushed  bool
+	adminErr error
+}
+
+func (m *mockCacheAdmin) CacheEntries() []*trustbloc.CacheEntry {
+	return m.entries
+}
+
+func (m *mockCacheAdmin) EvictCache(domain string) error {
+	m.evicted = append(m.evicted, domain)
+
+	return m.adminErr
+}
+
+func (m *mockCacheAdmin) FlushCache() error {
+	m.flushed = true
+
+	return m.adminErr
+}
+
+func TestCacheAdminHandlers(t *testing.T) {
+	expiry := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newService := func(admin *mockCacheAdmin) *Operation {
+		svc := New(&Config{EnableCacheAdmin: true})
+		svc.cacheAdmin = admin
+
+		return svc
+	}
+
+	lookup := func(t *testing.T, svc *Operation, path, method string) Handler {
+		t.Helper()
+
+		handlers, err := svc.GetRESTHandlers(resolverMode)
+		require.NoError(t, err)
+
+		for _, h := range handlers {
+			if h.Path() == path && h.Method() == method {
+				return h
+			}
+		}
+
+		require.Fail(t, "unable to find handler")
+
+		return nil
+	}
+
+	admin := &mockCacheAdmin{entries: []*trustbloc.CacheEntry{
+		{Kind: "consortium", URL: "consortium.example.com", Domain: "consortium.example.com", Expiry: expiry},
+		{Kind: "stakeholder", URL: "stakeholder.one", Domain: "stakeholder.one", Error: "stakeholder down",
+			ErrorExpiry: expiry},
+		{Kind: "pinnedconsortium", URL: "consortium.example.com", Domain: "consortium.example.com", Updated: true},
+	}}
+
+	t.Run("test list entries", func(t *testing.T) {
+		handler := lookup(t, newService(admin), cacheAdminPath, http.MethodGet)
+
+		body, status, err := handleRequest(handler, "/admin/cache", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var cacheStatus CacheStatus
+		require.NoError(t, json.Unmarshal(body.Bytes(), &cacheStatus))
+		require.Equal(t, CacheStatus{Entries: []CacheEntryStatus{
+			{Kind: "consortium", URL: "consortium.example.com", Domain: "consortium.example.com",
+				Expiry: "2021-01-02T03:04:05Z"},
+			{Kind: "stakeholder", URL: "stakeholder.one", Domain: "stakeholder.one", Error: "stakeholder down",
+				ErrorExpiry: "2021-01-02T03:04:05Z"},
+			{Kind: "pinnedconsortium", URL: "consortium.example.com", Domain: "consortium.example.com", Updated: true},
+		}}, cacheStatus)
+	})
+
+	t.Run("test list entries of domain", func(t *testing.T) {
+		handler := lookup(t, newService(admin), cacheAdminDomainPath, http.MethodGet)
+
+		body, status, err := handleRequest(handler, "/admin/cache/stakeholder.one", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var cacheStatus CacheStatus
+		require.NoError(t, json.Unmarshal(body.Bytes(), &cacheStatus))
+		require.Len(t, cacheStatus.Entries, 1)
+		require.Equal(t, "stakeholder", cacheStatus.Entries[0].Kind)
+
+		body, status, err = handleRequest(handler, "/admin/cache/unknown.example.com", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "{\"entries\":[]}\n", body.String())
+	})
+
+	t.Run("test evict domain", func(t *testing.T) {
+		admin := &mockCacheAdmin{}
+		handler := lookup(t, newService(admin), cacheAdminDomainPath, http.MethodDelete)
+
+		_, status, err := handleRequest(handler, "/admin/cache/consortium.example.com", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+		require.Equal(t, []string{"consortium.example.com"}, admin.evicted)
+		require.False(t, admin.flushed)
+	})
+
+	t.Run("test flush", func(t *testing.T) {
+		admin := &mockCacheAdmin{}
+		handler := lookup(t, newService(admin), cacheAdminPath, http.MethodDelete)
+
+		_, status, err := handleRequest(handler, "/admin/cache", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+		require.Empty(t, admin.evicted)
+		require.True(t, admin.flushed)
+	})
+
+	t.Run("test evict error", func(t *testing.T) {
+		admin := &mockCacheAdmin{adminErr: fmt.Errorf("removing cache file")}
+		handler := lookup(t, newService(admin), cacheAdminDomainPath, http.MethodDelete)
+
+		body, status, err := handleRequest(handler, "/admin/cache/consortium.example.com", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+		require.Contains(t, body.String(), "removing cache file")
+	})
+}
+
 func handleRequest(handler Handler, path string, body []byte) (*bytes.Buffer, int, error) { //nolint:lll
 	req, err := http.NewRequest(handler.Method(), path, bytes.NewBuffer(body))
 	if err != nil {
@@ -0,0 +1,124 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"fmt"
+	"time"
+)
+
+// pinnedConsortiumKind is the kind of the cache entries of consortium configs used to validate updates
+const pinnedConsortiumKind = "pinnedconsortium"
+
+// CacheEntry describes a config cached by the VDRI
+type CacheEntry struct {
+	// Kind is the kind of config: consortium, stakeholder, sidetreeconfig, or pinnedconsortium for a consortium config
+	// used to validate updates of a consortium with a genesis file
+	Kind string
+	// URL is the url the config is fetched from
+	URL string
+	// Domain is the domain of the config, empty for a sidetree config
+	Domain string
+	// Consortium is the domain of the consortium a stakeholder config is cached for, if any
+	Consortium string
+	// Expiry is the time the config expires. Zero if only a failure is cached, or for a pinned consortium config,
+	// which doesn't expire.
+	Expiry time.Time
+	// Error is the cached failure to fetch the config, if any
+	Error string
+	// ErrorExpiry is the time the cached failure expires
+	ErrorExpiry time.Time
+	// Updated is whether a pinned consortium config was updated from its genesis file
+	Updated bool
+}
+
+// CacheEntries returns the configs cached in memory, followed by the pinned consortium configs if genesis files are
+// used. Configs cached on disk are only listed once loaded in memory.
+func (v *VDRI) CacheEntries() []*CacheEntry {
+	var entries []*CacheEntry
+
+	if v.memoryCache != nil {
+		for _, e := range v.memoryCache.Entries() {
+			entries = append(entries, &CacheEntry{
+				Kind:        e.Kind,
+				URL:         e.URL,
+				Domain:      e.Domain,
+				Consortium:  e.Consortium,
+				Expiry:      e.Expiry,
+				Error:       e.Error,
+				ErrorExpiry: e.ErrorExpiry,
+			})
+		}
+	}
+
+	if v.updateValidationService != nil {
+		for _, e := range v.updateValidationService.Entries() {
+			entries = append(entries, &CacheEntry{
+				Kind:    pinnedConsortiumKind,
+				URL:     e.URL,
+				Domain:  e.Domain,
+				Updated: e.Updated,
+			})
+		}
+	}
+
+	return entries
+}
+
+// EvictCache removes the cached configs whose url, domain or consortium is the given domain, in memory and on disk,
+// and resets pinned consortium configs for the domain to their genesis files. A consortium with the given domain is
+// validated again when next used.
+func (v *VDRI) EvictCache(domain string) error {
+	if v.memoryCache != nil {
+		v.memoryCache.Evict(domain)
+	}
+
+	if v.updateValidationService != nil {
+		v.updateValidationService.Evict(domain)
+	}
+
+	v.validatedLock.Lock()
+	delete(v.validatedConsortium, domain)
+
+	if v.trustedStakeholder == domain {
+		v.validatedTrustedStakeholder = ""
+	}
+
+	v.validatedLock.Unlock()
+
+	if v.fileCache != nil {
+		if _, err := v.fileCache.Evict(domain); err != nil {
+			return fmt.Errorf("evicting %s from cache: %w", domain, err)
+		}
+	}
+
+	return nil
+}
+
+// FlushCache removes all cached configs, in memory and on disk, and resets pinned consortium configs to their genesis
+// files. Consortia are validated again when next used.
+func (v *VDRI) FlushCache() error {
+	if v.memoryCache != nil {
+		v.memoryCache.Flush()
+	}
+
+	if v.updateValidationService != nil {
+		v.updateValidationService.Flush()
+	}
+
+	v.validatedLock.Lock()
+	v.validatedConsortium = map[string]*consortiumValidation{}
+	v.validatedTrustedStakeholder = ""
+	v.validatedLock.Unlock()
+
+	if v.fileCache != nil {
+		if err := v.fileCache.Flush(); err != nil {
+			return fmt.Errorf("flushing cache: %w", err)
+		}
+	}
+
+	return nil
+}
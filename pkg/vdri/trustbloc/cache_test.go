@@ -0,0 +1,194 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/filecacheconfig"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/memorycacheconfig"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/stakeholdervalidationconfig"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+func TestVDRI_Cache(t *testing.T) {
+	sigKey := ed25519SigningKey(t, keyJSON)
+
+	consortium := dummyConsortium("testnet", "stakeholder.url")
+	consortium.Policy.Cache.MaxAge = 1000
+
+	stakeholder := dummyStakeholder("stakeholder.url")
+	stakeholder.Policy.Cache.MaxAge = 1000
+
+	cfd := signedConsortiumFileData(t, consortium, sigKey)
+	sfd := signedStakeholderFileData(t, stakeholder, sigKey)
+
+	wrapped := &mockconfig.MockConfigService{
+		GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+			return cfd, nil
+		},
+		GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+			return sfd, nil
+		},
+	}
+
+	newVDRI := func(t *testing.T) *VDRI {
+		t.Helper()
+
+		v := New(UseGenesisFile("testnet", "testnet", []byte(cfd.JWS.FullSerialize())))
+		require.NoError(t, v.genesisErr)
+
+		fileCache, err := filecacheconfig.NewService(wrapped, filepath.Join(t.TempDir(), "cache"))
+		require.NoError(t, err)
+
+		v.fileCache = fileCache
+		v.memoryCache = memorycacheconfig.NewService(fileCache)
+		v.configService = v.memoryCache
+
+		_, err = v.configService.GetConsortiumWithContext(context.Background(), "testnet", "testnet")
+		require.NoError(t, err)
+
+		_, err = v.configService.GetStakeholderWithContext(context.Background(), "stakeholder.url", "stakeholder.url")
+		require.NoError(t, err)
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		return v
+	}
+
+	t.Run("success - list entries", func(t *testing.T) {
+		v := newVDRI(t)
+
+		entries := v.CacheEntries()
+		require.Len(t, entries, 3)
+
+		require.Equal(t, "consortium", entries[0].Kind)
+		require.Equal(t, "testnet", entries[0].Domain)
+		require.True(t, entries[0].Expiry.After(time.Now()))
+
+		require.Equal(t, "stakeholder", entries[1].Kind)
+		require.Equal(t, "stakeholder.url", entries[1].Domain)
+
+		require.Equal(t, pinnedConsortiumKind, entries[2].Kind)
+		require.Equal(t, "testnet", entries[2].Domain)
+		require.True(t, entries[2].Expiry.IsZero())
+		require.False(t, entries[2].Updated)
+	})
+
+	t.Run("success - no caches", func(t *testing.T) {
+		v := &VDRI{validatedConsortium: map[string]*consortiumValidation{}}
+
+		require.Empty(t, v.CacheEntries())
+		require.NoError(t, v.EvictCache("testnet"))
+		require.NoError(t, v.FlushCache())
+	})
+
+	t.Run("success - evict", func(t *testing.T) {
+		v := newVDRI(t)
+
+		require.NoError(t, v.EvictCache("testnet"))
+
+		entries := v.CacheEntries()
+		require.Len(t, entries, 2)
+		require.Equal(t, "stakeholder", entries[0].Kind)
+		require.Equal(t, pinnedConsortiumKind, entries[1].Kind)
+
+		require.NotContains(t, v.validatedConsortium, "testnet")
+
+		evicted, err := v.fileCache.Evict("testnet")
+		require.NoError(t, err)
+		require.Equal(t, 0, evicted)
+	})
+
+	t.Run("success - evict trusted stakeholder", func(t *testing.T) {
+		v := newVDRI(t)
+		v.trustedStakeholder = "stakeholder.url"
+		v.validatedTrustedStakeholder = sfd.JWS.FullSerialize()
+
+		require.NoError(t, v.EvictCache("stakeholder.url"))
+		require.Empty(t, v.validatedTrustedStakeholder)
+		require.Contains(t, v.validatedConsortium, "testnet")
+	})
+
+	t.Run("success - flush", func(t *testing.T) {
+		v := newVDRI(t)
+
+		require.NoError(t, v.FlushCache())
+
+		entries := v.CacheEntries()
+		require.Len(t, entries, 1)
+		require.Equal(t, pinnedConsortiumKind, entries[0].Kind)
+
+		require.Empty(t, v.validatedConsortium)
+
+		evicted, err := v.fileCache.Evict("stakeholder.url")
+		require.NoError(t, err)
+		require.Equal(t, 0, evicted)
+	})
+}
+
+func TestVDRI_CacheDirRestart(t *testing.T) {
+	sigKey := ed25519SigningKey(t, keyJSON)
+
+	cfd := signedConsortiumFileData(t, dummyConsortium("testnet", "stakeholder.url"), sigKey)
+	sfd := signedStakeholderFileData(t, dummyStakeholder("stakeholder.url"), sigKey)
+
+	var fetchErr error
+
+	wrapped := &mockconfig.MockConfigService{
+		GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+			return cfd, fetchErr
+		},
+		GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+			return sfd, fetchErr
+		},
+	}
+
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	// the on-disk cache is below the stakeholder validation service, as in New
+	newService := func(t *testing.T) *stakeholdervalidationconfig.ConfigService {
+		t.Helper()
+
+		fileCache, err := filecacheconfig.NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		return stakeholdervalidationconfig.NewService(fileCache)
+	}
+
+	cs := newService(t)
+
+	_, err := cs.GetConsortium("testnet", "testnet")
+	require.NoError(t, err)
+
+	_, err = cs.GetStakeholder("stakeholder.url", "stakeholder.url")
+	require.NoError(t, err)
+
+	// after a restart, configs loaded from disk are validated as fetched ones are, so the stakeholder keys listed
+	// by the consortium are known when its servers can't be reached
+	fetchErr = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	cs = newService(t)
+
+	_, err = cs.GetConsortium("testnet", "testnet")
+	require.NoError(t, err)
+
+	sh, err := cs.GetStakeholder("stakeholder.url", "stakeholder.url")
+	require.NoError(t, err)
+	require.Equal(t, "stakeholder.url", sh.Config.Domain)
+
+	lifetime, err := sh.CacheLifetime()
+	require.NoError(t, err)
+	require.Zero(t, lifetime)
+}
@@ -0,0 +1,415 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package filecacheconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+const (
+	consortiumKind     = "consortium"
+	stakeholderKind    = "stakeholder"
+	sidetreeConfigKind = "sidetreeconfig"
+
+	// defaultMaxStale is how long after it expires a config is served while its server is unavailable, by default
+	defaultMaxStale = 24 * time.Hour
+)
+
+// errNotCached is returned by readEntry when there is no cache entry at the path
+var errNotCached = errors.New("not cached")
+
+type config interface {
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetConsortiumHistoryWithContext(context.Context, string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetStakeholderHistoryWithContext(context.Context, string, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
+}
+
+// ConfigService fetches consortium and stakeholder configs using a wrapped config service, caching results on disk
+// so they survive a restart.
+// Each config is stored in its own file in the cache directory, with its JWS and the time it expires. An expired
+// config is refetched from the wrapped service. If its server can't be reached, the expired config is served
+// for a limited time, with a cache lifetime of zero so it isn't cached as fresh by the services above.
+// The service caches configs as they are fetched, so it is meant to wrap the http config service, below the
+// services verifying them.
+type ConfigService struct {
+	config   config
+	dir      string
+	maxStale time.Duration
+}
+
+// NewService create new ConfigService, caching configs in the given directory
+func NewService(config config, dir string, opts ...Option) (*ConfigService, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	configService := &ConfigService{config: config, dir: dir, maxStale: defaultMaxStale}
+
+	for _, opt := range opts {
+		opt(configService)
+	}
+
+	return configService, nil
+}
+
+// entry is the on-disk form of a cached config
+type entry struct {
+	Kind   string    `json:"kind"`
+	URL    string    `json:"url"`
+	Domain string    `json:"domain,omitempty"`
+	Expiry time.Time `json:"expiry"`
+	// JWS is the serialized JWS of a consortium or stakeholder config
+	JWS string `json:"jws,omitempty"`
+	// Sidetree holds a sidetree config, which isn't signed
+	Sidetree *models.SidetreeConfig `json:"sidetree,omitempty"`
+	// MaxAge is the cache lifetime of a sidetree config, in seconds
+	MaxAge uint `json:"maxAge,omitempty"`
+}
+
+type cacheable interface {
+	CacheLifetime() (time.Duration, error)
+}
+
+// codec converts a config to and from its cache entry
+type codec struct {
+	fetch  func(ctx context.Context, url, domain string) (cacheable, error)
+	encode func(data cacheable, e *entry) error
+	decode func(e *entry) (cacheable, error)
+	// expire sets the cache lifetime of a decoded config to zero
+	expire func(data cacheable)
+}
+
+// getEntryHelper returns the config of the given kind cached under the given url and domain, using the codec to fetch
+// and cache the config if it is missing or expired. If its server can't be reached, a config which expired less
+// than maxStale ago is returned instead, with a cache lifetime of zero.
+func (cs *ConfigService) getEntryHelper(ctx context.Context, kind, url, domain string, c *codec) (cacheable, error) {
+	path := cs.path(kind, url, domain)
+
+	cached, err := readEntry(path)
+	if err != nil && !errors.Is(err, errNotCached) {
+		log.Warnf("ignoring unreadable %s cache entry for %s %s: %s", kind, url, domain, err.Error())
+	}
+
+	var stale cacheable
+
+	if cached != nil {
+		stale, err = c.decode(cached)
+		if err != nil {
+			log.Warnf("ignoring invalid %s cache entry for %s %s: %s", kind, url, domain, err.Error())
+		} else if time.Now().Before(cached.Expiry) {
+			return stale, nil
+		}
+	}
+
+	fetched, err := c.fetch(ctx, url, domain)
+	if err != nil {
+		if stale != nil && unavailable(ctx, err) && time.Now().Before(cached.Expiry.Add(cs.maxStale)) {
+			log.Warnf("serving expired %s config for %s %s: %s", kind, url, domain, err.Error())
+
+			c.expire(stale)
+
+			return stale, nil
+		}
+
+		return nil, fmt.Errorf("getting %s from cache: fetching cacheable object: %w", kind, err)
+	}
+
+	lifetime, err := fetched.CacheLifetime()
+	if err != nil {
+		return nil, fmt.Errorf("getting %s from cache: failed to get object expiry time: %w", kind, err)
+	}
+
+	e := &entry{Kind: kind, URL: url, Domain: domain, Expiry: time.Now().Add(lifetime)}
+
+	err = c.encode(fetched, e)
+	if err == nil {
+		err = writeEntry(path, e)
+	}
+
+	if err != nil {
+		log.Warnf("failed to cache %s config for %s %s: %s", kind, url, domain, err.Error())
+	}
+
+	return fetched, nil
+}
+
+// unavailable returns whether fetching a config failed because its server couldn't be reached, rather than because
+// the caller gave up or the server sent an invalid config
+func unavailable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var (
+		consortiumUnavailable *models.ConsortiumUnavailable
+		stakeholderDown       *models.StakeholderDown
+		netErr                net.Error
+	)
+
+	return errors.As(err, &consortiumUnavailable) || errors.As(err, &stakeholderDown) || errors.As(err, &netErr)
+}
+
+// path returns the path of the cache file for the given config
+func (cs *ConfigService) path(kind, url, domain string) string {
+	hash := sha256.Sum256([]byte(kind + "\n" + url + "\n" + domain))
+
+	return filepath.Join(cs.dir, kind+"-"+hex.EncodeToString(hash[:])+".json")
+}
+
+// readEntry reads the cache entry at the given path, or returns errNotCached
+func readEntry(path string) (*entry, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if os.IsNotExist(err) {
+		return nil, errNotCached
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{}
+
+	err = json.Unmarshal(data, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// writeEntry saves the cache entry to the given path, replacing any previous entry.
+// The entry is written to a temporary file first, so concurrent readers never see a partial entry.
+func writeEntry(path string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling cache entry: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating cache file: %w", err)
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close() // nolint: errcheck
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name()) // nolint: errcheck
+
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+
+	return nil
+}
+
+// Evict removes the cached configs whose url or domain is the given domain, returning the number removed
+func (cs *ConfigService) Evict(domain string) (int, error) {
+	return cs.remove(func(e *entry) bool {
+		return e.URL == domain || e.Domain == domain
+	})
+}
+
+// Flush removes all cached configs
+func (cs *ConfigService) Flush() error {
+	_, err := cs.remove(func(*entry) bool {
+		return true
+	})
+
+	return err
+}
+
+// remove removes the cached configs matching the given filter, returning the number removed.
+// Unreadable cache files are removed too, as they are never used.
+func (cs *ConfigService) remove(filter func(e *entry) bool) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(cs.dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing cache files: %w", err)
+	}
+
+	removed := 0
+
+	for _, path := range paths {
+		e, readErr := readEntry(path)
+		if readErr == nil && !filter(e) {
+			continue
+		}
+
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("removing cache file: %w", err)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// GetConsortium calls GetConsortiumWithContext with a background context
+func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext fetches and parses the consortium file at the given domain, caching the value
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	data, err := cs.getEntryHelper(ctx, consortiumKind, url, domain, &codec{
+		fetch: func(ctx context.Context, url, domain string) (cacheable, error) {
+			return cs.config.GetConsortiumWithContext(ctx, url, domain)
+		},
+		encode: func(data cacheable, e *entry) error {
+			consortium := data.(*models.ConsortiumFileData)
+			if consortium.JWS == nil {
+				return fmt.Errorf("consortium config has no JWS")
+			}
+
+			e.JWS = consortium.JWS.FullSerialize()
+
+			return nil
+		},
+		decode: func(e *entry) (cacheable, error) {
+			return models.ParseConsortium([]byte(e.JWS))
+		},
+		expire: func(data cacheable) {
+			data.(*models.ConsortiumFileData).ServerMaxAge = new(time.Duration)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.(*models.ConsortiumFileData), nil
+}
+
+// GetConsortiumHistory calls GetConsortiumHistoryWithContext with a background context
+func (cs *ConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetConsortiumHistoryWithContext returns the historical consortium config file fetched by the wrapped config service
+func (cs *ConfigService) GetConsortiumHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	return cs.config.GetConsortiumHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetStakeholder calls GetStakeholderWithContext with a background context
+func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext returns the stakeholder config file fetched by the wrapped config service, caching it
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	data, err := cs.getEntryHelper(ctx, stakeholderKind, url, domain, &codec{
+		fetch: func(ctx context.Context, url, domain string) (cacheable, error) {
+			return cs.config.GetStakeholderWithContext(ctx, url, domain)
+		},
+		encode: func(data cacheable, e *entry) error {
+			stakeholder := data.(*models.StakeholderFileData)
+			if stakeholder.JWS == nil {
+				return fmt.Errorf("stakeholder config has no JWS")
+			}
+
+			e.JWS = stakeholder.JWS.FullSerialize()
+
+			return nil
+		},
+		decode: func(e *entry) (cacheable, error) {
+			return models.ParseStakeholder([]byte(e.JWS))
+		},
+		expire: func(data cacheable) {
+			data.(*models.StakeholderFileData).ServerMaxAge = new(time.Duration)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.(*models.StakeholderFileData), nil
+}
+
+// GetStakeholderHistory calls GetStakeholderHistoryWithContext with a background context
+func (cs *ConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetStakeholderHistoryWithContext returns the historical stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
+func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext returns the sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
+	data, err := cs.getEntryHelper(ctx, sidetreeConfigKind, url, "", &codec{
+		fetch: func(ctx context.Context, url, _ string) (cacheable, error) {
+			return cs.config.GetSidetreeConfigWithContext(ctx, url)
+		},
+		encode: func(data cacheable, e *entry) error {
+			e.Sidetree = data.(*models.SidetreeConfig)
+			e.MaxAge = e.Sidetree.MaxAge
+
+			return nil
+		},
+		decode: func(e *entry) (cacheable, error) {
+			if e.Sidetree == nil {
+				return nil, fmt.Errorf("sidetree config missing from cache entry")
+			}
+
+			e.Sidetree.MaxAge = e.MaxAge
+
+			return e.Sidetree, nil
+		},
+		expire: func(data cacheable) {
+			data.(*models.SidetreeConfig).MaxAge = 0
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.(*models.SidetreeConfig), nil
+}
+
+// Option is a config service instance option
+type Option func(opts *ConfigService)
+
+// WithMaxStale sets how long after it expires a config is served while its server can't be reached. Defaults to a day.
+func WithMaxStale(maxStale time.Duration) Option {
+	return func(opts *ConfigService) {
+		opts.maxStale = maxStale
+	}
+}
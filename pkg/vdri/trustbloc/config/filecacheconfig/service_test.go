@@ -0,0 +1,474 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package filecacheconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
+	mockmodels "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+func consortiumFileData(t *testing.T, maxAge uint32) *models.ConsortiumFileData {
+	t.Helper()
+
+	consortium := mockmodels.DummyConsortium("foo.bar", []*models.StakeholderListElement{{Domain: "bar.baz"}})
+	consortium.Policy.Cache.MaxAge = maxAge
+
+	data, err := mockmodels.WrapConsortium(consortium)
+	require.NoError(t, err)
+
+	consortiumData, err := models.ParseConsortium([]byte(data))
+	require.NoError(t, err)
+
+	return consortiumData
+}
+
+func stakeholderFileData(t *testing.T, maxAge uint32) *models.StakeholderFileData {
+	t.Helper()
+
+	stakeholder := mockmodels.DummyStakeholder("bar.baz", []string{"https://bar.baz/webapi/123456"})
+	stakeholder.Policy.Cache.MaxAge = maxAge
+
+	data, err := mockmodels.WrapStakeholder(stakeholder)
+	require.NoError(t, err)
+
+	stakeholderData, err := models.ParseStakeholder([]byte(data))
+	require.NoError(t, err)
+
+	return stakeholderData
+}
+
+func TestNewService(t *testing.T) {
+	t.Run("success - creates cache directory", func(t *testing.T) {
+		cs, err := NewService(&mockconfig.MockConfigService{}, filepath.Join(t.TempDir(), "cache"))
+		require.NoError(t, err)
+		require.NotNil(t, cs)
+	})
+
+	t.Run("failure - cache directory is a file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, ioutil.WriteFile(file, []byte("data"), 0600))
+
+		_, err := NewService(&mockconfig.MockConfigService{}, file)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creating cache directory")
+	})
+}
+
+func TestConfigService_GetConsortium(t *testing.T) {
+	t.Run("success - cached across restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		callCount := 0
+
+		wrapped := &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return consortiumFileData(t, 1000), nil
+			}}
+
+		cs, err := NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		conf, err := cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", conf.Config.Domain)
+
+		// a new service on the same directory, as after a restart, uses the cached config
+		cs, err = NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			conf, err = cs.GetConsortium("foo.bar", "foo.bar")
+			require.NoError(t, err)
+			require.Equal(t, "foo.bar", conf.Config.Domain)
+			require.Equal(t, []*models.StakeholderListElement{{Domain: "bar.baz"}}, conf.Config.Members)
+			require.NotNil(t, conf.JWS)
+		}
+
+		require.Equal(t, 1, callCount)
+
+		// configs are cached under their url and domain
+		_, err = cs.GetConsortium("foo.bar", "other.domain")
+		require.NoError(t, err)
+		require.Equal(t, 2, callCount)
+	})
+
+	t.Run("success - re-call wrapped service when cache expires", func(t *testing.T) {
+		callCount := 0
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return consortiumFileData(t, 0), nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err = cs.GetConsortium("foo.bar", "foo.bar")
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 3, callCount)
+	})
+
+	t.Run("success - serve expired config when its server is unavailable", func(t *testing.T) {
+		var fetchErr error
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				if fetchErr != nil {
+					return nil, fetchErr
+				}
+
+				return consortiumFileData(t, 0), nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		for _, fetchErr = range []error{
+			&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			fmt.Errorf("wrapped: %w", &models.ConsortiumUnavailable{Domain: "foo.bar", Err: errors.New("down")}),
+			&models.StakeholderDown{Domain: "bar.baz", Err: errors.New("down")},
+		} {
+			conf, err := cs.GetConsortium("foo.bar", "foo.bar")
+			require.NoError(t, err)
+			require.Equal(t, "foo.bar", conf.Config.Domain)
+
+			// the expired config isn't cached as fresh by the services above
+			lifetime, err := conf.CacheLifetime()
+			require.NoError(t, err)
+			require.Zero(t, lifetime)
+		}
+	})
+
+	t.Run("failure - expired config isn't served when the fetched config is invalid", func(t *testing.T) {
+		fail := false
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				if fail {
+					return nil, &models.InvalidStakeholderSignature{Domain: "bar.baz", Err: errors.New("bad signature")}
+				}
+
+				return consortiumFileData(t, 0), nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		fail = true
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "bad signature")
+	})
+
+	t.Run("failure - expired config isn't served past max stale", func(t *testing.T) {
+		fail := false
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				if fail {
+					return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+				}
+
+				return consortiumFileData(t, 0), nil
+			}}, t.TempDir(), WithMaxStale(time.Millisecond))
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		fail = true
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("success - invalid cache entry is refetched", func(t *testing.T) {
+		dir := t.TempDir()
+		callCount := 0
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return consortiumFileData(t, 1000), nil
+			}}, dir)
+		require.NoError(t, err)
+
+		require.NoError(t, ioutil.WriteFile(cs.path(consortiumKind, "foo.bar", "foo.bar"), []byte("{"), 0600))
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		require.NoError(t, ioutil.WriteFile(cs.path(consortiumKind, "foo.bar", "foo.bar"),
+			[]byte(`{"jws":"not a jws"}`), 0600))
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 2, callCount)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 2, callCount)
+	})
+
+	t.Run("success - config without JWS isn't cached", func(t *testing.T) {
+		callCount := 0
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return &models.ConsortiumFileData{Config: consortiumFileData(t, 1000).Config}, nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = cs.GetConsortium("foo.bar", "foo.bar")
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 2, callCount)
+	})
+
+	t.Run("failure - wrapped service fails with nothing cached", func(t *testing.T) {
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("stakeholders unreachable")
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholders unreachable")
+	})
+
+	t.Run("failure - nil pointer", func(t *testing.T) {
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{Config: nil}, nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing config object")
+	})
+}
+
+func TestConfigService_GetStakeholder(t *testing.T) {
+	t.Run("success - cached across restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		callCount := 0
+
+		wrapped := &mockconfig.MockConfigService{
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				callCount++
+
+				return stakeholderFileData(t, 1000), nil
+			}}
+
+		cs, err := NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("bar.baz", "bar.baz")
+		require.NoError(t, err)
+
+		cs, err = NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		conf, err := cs.GetStakeholder("bar.baz", "bar.baz")
+		require.NoError(t, err)
+		require.Equal(t, "bar.baz", conf.Config.Domain)
+		require.Equal(t, []string{"https://bar.baz/webapi/123456"}, conf.Config.Endpoints)
+		require.Equal(t, 1, callCount)
+	})
+
+	t.Run("success - serve expired config when its server is unavailable", func(t *testing.T) {
+		fail := false
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				if fail {
+					return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+				}
+
+				return stakeholderFileData(t, 0), nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("bar.baz", "bar.baz")
+		require.NoError(t, err)
+
+		fail = true
+
+		conf, err := cs.GetStakeholder("bar.baz", "bar.baz")
+		require.NoError(t, err)
+		require.Equal(t, "bar.baz", conf.Config.Domain)
+	})
+
+	t.Run("failure - wrapped service fails with nothing cached", func(t *testing.T) {
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return nil, fmt.Errorf("stakeholder unreachable")
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("bar.baz", "bar.baz")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder unreachable")
+	})
+}
+
+func TestConfigService_GetSidetreeConfig(t *testing.T) {
+	t.Run("success - cached across restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		callCount := 0
+
+		wrapped := &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(url string) (*models.SidetreeConfig, error) {
+				callCount++
+
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18, MaxAge: 1000}, nil
+			}}
+
+		cs, err := NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		_, err = cs.GetSidetreeConfig("foo.bar")
+		require.NoError(t, err)
+
+		cs, err = NewService(wrapped, dir)
+		require.NoError(t, err)
+
+		conf, err := cs.GetSidetreeConfig("foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, &models.SidetreeConfig{MultiHashAlgorithm: 18, MaxAge: 1000}, conf)
+		require.Equal(t, 1, callCount)
+	})
+
+	t.Run("success - serve expired config when its server is unavailable", func(t *testing.T) {
+		fail := false
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(url string) (*models.SidetreeConfig, error) {
+				if fail {
+					return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+				}
+
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18, MaxAge: 1}, nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetSidetreeConfig("foo.bar")
+		require.NoError(t, err)
+
+		fail = true
+
+		time.Sleep(1100 * time.Millisecond)
+
+		conf, err := cs.GetSidetreeConfig("foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, &models.SidetreeConfig{MultiHashAlgorithm: 18, MaxAge: 0}, conf)
+	})
+
+	t.Run("failure - wrapped service fails with nothing cached", func(t *testing.T) {
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(url string) (*models.SidetreeConfig, error) {
+				return nil, fmt.Errorf("sidetree unreachable")
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetSidetreeConfig("foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sidetree unreachable")
+	})
+}
+
+func TestConfigService_Evict(t *testing.T) {
+	newService := func(t *testing.T) (*ConfigService, *int) {
+		t.Helper()
+
+		callCount := 0
+
+		cs, err := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return consortiumFileData(t, 1000), nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return stakeholderFileData(t, 1000), nil
+			},
+			GetSidetreeConfigFunc: func(url string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18, MaxAge: 1000}, nil
+			}}, t.TempDir())
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("bar.baz", "bar.baz")
+		require.NoError(t, err)
+
+		_, err = cs.GetSidetreeConfig("https://bar.baz/sidetree/0.0.1")
+		require.NoError(t, err)
+
+		return cs, &callCount
+	}
+
+	t.Run("success - evict by domain", func(t *testing.T) {
+		cs, callCount := newService(t)
+
+		evicted, err := cs.Evict("foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 1, evicted)
+
+		evicted, err = cs.Evict("foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 0, evicted)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 2, *callCount)
+
+		paths, err := filepath.Glob(filepath.Join(cs.dir, "*.json"))
+		require.NoError(t, err)
+		require.Len(t, paths, 3)
+	})
+
+	t.Run("success - flush", func(t *testing.T) {
+		cs, _ := newService(t)
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(cs.dir, "corrupt.json"), []byte("{"), 0600))
+
+		require.NoError(t, cs.Flush())
+
+		paths, err := filepath.Glob(filepath.Join(cs.dir, "*"))
+		require.NoError(t, err)
+		require.Empty(t, paths)
+	})
+}
@@ -6,17 +6,23 @@ SPDX-License-Identifier: Apache-2.0
 package httpconfig
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/historyhash"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 const (
@@ -25,28 +31,52 @@ const (
 	maxAge   = 3600
 )
 
-// ConfigService fetches consortium and stakeholder configs over http
+// ConfigService fetches consortium and stakeholder configs over http.
+// Configs served with an ETag or Last-Modified header are refreshed with conditional requests. When the server
+// responds that a config is not modified, the config parsed from the previous response is returned again, with the
+// same JWS, so that services verifying the config can tell that it is unchanged.
 type ConfigService struct {
-	httpClient *http.Client
-	tlsConfig  *tls.Config
-	authToken  string
+	httpClient  *http.Client
+	tlsConfig   *tls.Config
+	authToken   string
+	historyHash *historyhash.Registry
+	retryPolicy *retry.Policy
+
+	// lock guards responses
+	lock      sync.Mutex
+	responses map[string]*cachedResponse
+}
+
+// cachedResponse holds the validators of the last response for a config url, and the config parsed from it
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	data         interface{}
 }
 
 // NewService create new ConfigService
 func NewService(opts ...Option) *ConfigService {
-	configService := &ConfigService{httpClient: &http.Client{}}
+	configService := &ConfigService{
+		historyHash: historyhash.NewRegistry(),
+		responses:   map[string]*cachedResponse{},
+	}
 
 	for _, opt := range opts {
 		opt(configService)
 	}
 
-	configService.httpClient.Transport = &http.Transport{TLSClientConfig: configService.tlsConfig}
+	if configService.httpClient == nil {
+		configService.httpClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: configService.tlsConfig},
+		}
+	}
 
 	return configService
 }
 
 const consortiumURLInfix = "/.well-known/did-trustbloc/"
 const consortiumURLSuffix = ".json"
+const historyURLInfix = "history/"
 
 func configURL(urlDomain, consortiumDomain string) string {
 	prefix := ""
@@ -57,9 +87,39 @@ func configURL(urlDomain, consortiumDomain string) string {
 	return prefix + urlDomain + consortiumURLInfix + consortiumDomain + consortiumURLSuffix
 }
 
-// GetConsortium fetches and parses the consortium file at the given domain
+// GetConsortium calls GetConsortiumWithContext with a background context
 func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
-	res, err := cs.httpClient.Get(configURL(url, domain))
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext fetches and parses the consortium file at the given domain
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	data, maxAge, err := cs.getConfig(ctx, configURL(url, domain), "consortium config",
+		func(body []byte) (interface{}, error) {
+			return models.ParseConsortium(body)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// copied, as the parsed config is shared by all responses until it is modified
+	consortiumData := *data.(*models.ConsortiumFileData)
+	consortiumData.ServerMaxAge = maxAge
+
+	return &consortiumData, nil
+}
+
+// GetConsortiumHistory calls GetConsortiumHistoryWithContext with a background context
+func (cs *ConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetConsortiumHistoryWithContext fetches and parses the historical consortium file with the given hash from the given
+// url, verifying the file against the hash using the given history hash algorithm
+func (cs *ConfigService) GetConsortiumHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	res, err := cs.httpGet(ctx, configURL(url, historyURLInfix+hash))
 	if err != nil {
 		return nil, err
 	}
@@ -73,27 +133,68 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 	}
 
 	if res.StatusCode != http.StatusOK {
-		// TODO retry https://github.com/trustbloc/trustbloc-did-method/issues/159
-		return nil, fmt.Errorf("consortium config request failed: error %d, `%s`", res.StatusCode, string(body))
+		return nil, fmt.Errorf("consortium history request failed: error %d, `%s`", res.StatusCode, string(body))
+	}
+
+	err = cs.historyHash.Verify(hashAlgorithm, hash, body)
+	if err != nil {
+		return nil, fmt.Errorf("consortium history file invalid: %w", err)
 	}
 
 	return models.ParseConsortium(body)
 }
 
-// GetSidetreeConfig get sidetree config
+// GetStakeholderHistory calls GetStakeholderHistoryWithContext with a background context
+func (cs *ConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetStakeholderHistoryWithContext fetches and parses the historical stakeholder file with the given hash from the
+// given url, verifying the file against the hash using the given history hash algorithm
+func (cs *ConfigService) GetStakeholderHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	res, err := cs.httpGet(ctx, configURL(url, historyURLInfix+hash))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint: errcheck
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stakeholder history request failed: error %d, `%s`", res.StatusCode, string(body))
+	}
+
+	err = cs.historyHash.Verify(hashAlgorithm, hash, body)
+	if err != nil {
+		return nil, fmt.Errorf("stakeholder history file invalid: %w", err)
+	}
+
+	return models.ParseStakeholder(body)
+}
+
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
 func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext get sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
 	url = fmt.Sprintf("%s/%s", url, "version")
 
-	httpReq, err := http.NewRequest(http.MethodGet, url, nil)
+	httpReq, err := cs.newRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	if cs.authToken != "" {
-		httpReq.Header.Add("Authorization", cs.authToken)
-	}
+	cached := cs.setValidators(httpReq)
 
 	resp, err := cs.httpClient.Do(httpReq)
 	if err != nil {
@@ -109,40 +210,217 @@ func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig,
 
 	config := models.SidetreeConfig{MultiHashAlgorithm: sha2_256, MaxAge: maxAge}
 
-	if resp.StatusCode != http.StatusOK {
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		config = *cached.data.(*models.SidetreeConfig)
+	case resp.StatusCode != http.StatusOK:
 		log.Warnf("return unexpected response from %s status '%d' body %s, will return default sidetree config",
 			url, resp.StatusCode, responseBytes)
 		return &config, nil
-	}
+	default:
+		if err := json.Unmarshal(responseBytes, &config); err != nil {
+			return nil, err
+		}
 
-	if err := json.Unmarshal(responseBytes, &config); err != nil {
-		return nil, err
+		parsed := config
+		cs.storeValidators(url, resp.Header, &parsed)
 	}
 
+	boundMaxAge(&config, resp.Header)
+
 	return &config, nil
 }
 
-// GetStakeholder fetches and parses a stakeholder file under the given url with the given domain
+// boundMaxAge limits the cache lifetime of a sidetree config to the max-age it was served with
+func boundMaxAge(config *models.SidetreeConfig, header http.Header) {
+	limit := serverMaxAge(header)
+	if limit != nil && uint(*limit/time.Second) < config.MaxAge {
+		config.MaxAge = uint(*limit / time.Second)
+	}
+}
+
+// GetStakeholder calls GetStakeholderWithContext with a background context
 func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
-	res, err := cs.httpClient.Get(configURL(url, domain))
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext fetches and parses a stakeholder file under the given url with the given domain
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	data, maxAge, err := cs.getConfig(ctx, configURL(url, domain), "stakeholder config",
+		func(body []byte) (interface{}, error) {
+			return models.ParseStakeholder(body)
+		})
 	if err != nil {
 		return nil, err
 	}
 
+	// copied, as the parsed config is shared by all responses until it is modified
+	stakeholderData := *data.(*models.StakeholderFileData)
+	stakeholderData.ServerMaxAge = maxAge
+
+	return &stakeholderData, nil
+}
+
+// getConfig fetches the config file at the given url with a conditional request if the file was fetched before,
+// parsing the file with the given function. Returns the parsed file, which is the file parsed from the previous
+// response if the file is not modified, and the max-age the file was served with, if any.
+func (cs *ConfigService) getConfig(ctx context.Context, url, objectName string,
+	parse func(body []byte) (interface{}, error)) (interface{}, *time.Duration, error) {
+	req, err := cs.newRequest(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cached := cs.setValidators(req)
+
+	res, err := cs.retryPolicy.Do(cs.httpClient, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// nolint: errcheck
 	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	maxAge := serverMaxAge(res.Header)
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.data, maxAge, nil
 	}
 
 	if res.StatusCode != http.StatusOK {
-		// TODO retry https://github.com/trustbloc/trustbloc-did-method/issues/159
-		return nil, fmt.Errorf("stakeholder config request failed: error %d, `%s`", res.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("%s request failed: error %d, `%s`", objectName, res.StatusCode, string(body))
 	}
 
-	return models.ParseStakeholder(body)
+	data, err := parse(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cs.storeValidators(url, res.Header, data)
+
+	return data, maxAge, nil
+}
+
+// setValidators adds the validators of the last response for the requested url to the request, making it conditional.
+// Returns the last response, or nil if there is none.
+func (cs *ConfigService) setValidators(req *http.Request) *cachedResponse {
+	cs.lock.Lock()
+	cached := cs.responses[req.URL.String()]
+	cs.lock.Unlock()
+
+	if cached == nil {
+		return nil
+	}
+
+	if cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	if cached.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.lastModified)
+	}
+
+	return cached
+}
+
+// storeValidators saves the validators of a response for the given url with the config parsed from it, for
+// conditional requests. Responses without validators, or which mustn't be stored, replace any saved response.
+func (cs *ConfigService) storeValidators(url string, header http.Header, data interface{}) {
+	cached := &cachedResponse{etag: header.Get("ETag"), lastModified: header.Get("Last-Modified"), data: data}
+
+	_, noStore := cacheDirectives(header)["no-store"]
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if noStore || (cached.etag == "" && cached.lastModified == "") {
+		delete(cs.responses, url)
+
+		return
+	}
+
+	cs.responses[url] = cached
+}
+
+// cacheDirectives returns the directives of the Cache-Control header, with their values
+func cacheDirectives(header http.Header) map[string]string {
+	directives := map[string]string{}
+
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name := strings.ToLower(strings.TrimSpace(directive))
+			arg := ""
+
+			if i := strings.Index(name, "="); i >= 0 {
+				name, arg = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+			}
+
+			directives[name] = arg
+		}
+	}
+
+	return directives
+}
+
+// serverMaxAge returns the max-age of a response, or nil if it isn't set or is invalid.
+// Responses which mustn't be cached, or must be revalidated before use, have a max-age of zero.
+func serverMaxAge(header http.Header) *time.Duration {
+	directives := cacheDirectives(header)
+
+	var maxAge time.Duration
+
+	_, noStore := directives["no-store"]
+	_, noCache := directives["no-cache"]
+
+	if noStore || noCache {
+		return &maxAge
+	}
+
+	seconds, ok := directives["max-age"]
+	if !ok {
+		return nil
+	}
+
+	n, err := strconv.ParseUint(seconds, 10, 32)
+	if err != nil {
+		log.Warnf("ignoring invalid max-age: %s", seconds)
+
+		return nil
+	}
+
+	maxAge = time.Duration(n) * time.Second
+
+	return &maxAge
+}
+
+// httpGet sends a GET request for the given url using the given context, retrying according to the retry policy
+func (cs *ConfigService) httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := cs.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.retryPolicy.Do(cs.httpClient, req)
+}
+
+// newRequest creates a GET request for the given url using the given context, with the auth token if one is set
+func (cs *ConfigService) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if cs.authToken != "" {
+		req.Header.Set("Authorization", cs.authToken)
+	}
+
+	return req, nil
 }
 
 // Option is a config service instance option
@@ -162,6 +440,27 @@ func WithAuthToken(authToken string) Option {
 	}
 }
 
+// WithHTTPClient sets the http client used to fetch configs, in place of a client using the TLS config
+func WithHTTPClient(client *http.Client) Option {
+	return func(opts *ConfigService) {
+		opts.httpClient = client
+	}
+}
+
+// WithHistoryHashRegistry sets the registry of hash algorithms used to verify history files
+func WithHistoryHashRegistry(registry *historyhash.Registry) Option {
+	return func(opts *ConfigService) {
+		opts.historyHash = registry
+	}
+}
+
+// WithRetryPolicy sets the policy for retrying failed config file requests. By default, requests are not retried.
+func WithRetryPolicy(policy *retry.Policy) Option {
+	return func(opts *ConfigService) {
+		opts.retryPolicy = policy
+	}
+}
+
 func closeResponseBody(respBody io.Closer) {
 	e := respBody.Close()
 	if e != nil {
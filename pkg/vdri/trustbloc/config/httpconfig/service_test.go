@@ -6,17 +6,23 @@ SPDX-License-Identifier: Apache-2.0
 package httpconfig
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	mockmodels "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/historyhash"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 func TestConfigService_GetConsortium(t *testing.T) {
@@ -78,6 +84,219 @@ func TestConfigService_GetConsortium(t *testing.T) {
 
 		require.Contains(t, err.Error(), "consortium config data should be a JWS")
 	})
+
+	t.Run("success: retry intermittent failures", func(t *testing.T) {
+		consortium := mockmodels.DummyConsortium("foo.bar", nil)
+
+		consortiumFile, err := mockmodels.WrapConsortium(consortium)
+		require.NoError(t, err)
+
+		requests := 0
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService(WithRetryPolicy(&retry.Policy{MaxAttempts: 3, RetryableStatusClasses: []int{5}}))
+
+		conf, err := cs.GetConsortium(serv.URL, "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", conf.Config.Domain)
+		require.Equal(t, 3, requests)
+
+		requests = 0
+
+		cs = NewService()
+
+		_, err = cs.GetConsortium(serv.URL, "foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "consortium config request failed: error 503")
+		require.Equal(t, 1, requests)
+	})
+
+	t.Run("failure: context canceled", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer serv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		cs := NewService()
+
+		_, err := cs.GetConsortiumWithContext(ctx, serv.URL, "foo.bar")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("success: conditional request", func(t *testing.T) {
+		consortium := mockmodels.DummyConsortium("foo.bar", []*models.StakeholderListElement{{Domain: "bar.baz"}})
+		consortium.Policy.Cache.MaxAge = 3600
+
+		consortiumFile, err := mockmodels.WrapConsortium(consortium)
+		require.NoError(t, err)
+
+		requests := 0
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			w.Header().Set("Cache-Control", "public, max-age=60")
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		conf, err := cs.GetConsortium(serv.URL, "foo.bar")
+		require.NoError(t, err)
+
+		lifetime, err := conf.CacheLifetime()
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, lifetime)
+
+		notModified, err := cs.GetConsortium(serv.URL, "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 2, requests)
+
+		// the config parsed from the first response is reused, so its JWS needn't be verified again
+		require.Same(t, conf.JWS, notModified.JWS)
+		require.Same(t, conf.Config, notModified.Config)
+
+		lifetime, err = notModified.CacheLifetime()
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, lifetime)
+	})
+
+	t.Run("success: no-store response isn't revalidated", func(t *testing.T) {
+		consortiumFile, err := mockmodels.WrapConsortium(
+			mockmodels.DummyConsortium("foo.bar", []*models.StakeholderListElement{{Domain: "bar.baz"}}))
+		require.NoError(t, err)
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Empty(t, r.Header.Get("If-Modified-Since"))
+
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		for i := 0; i < 2; i++ {
+			conf, err := cs.GetConsortium(serv.URL, "foo.bar")
+			require.NoError(t, err)
+
+			lifetime, err := conf.CacheLifetime()
+			require.NoError(t, err)
+			require.Zero(t, lifetime)
+		}
+	})
+}
+
+func TestConfigService_GetConsortiumHistory(t *testing.T) {
+	consortium := mockmodels.DummyConsortium("foo.bar", []*models.StakeholderListElement{
+		{
+			Domain: "bar.baz",
+		},
+	})
+
+	consortiumFile, err := mockmodels.WrapConsortium(consortium)
+	require.NoError(t, err)
+
+	hash, err := historyhash.NewRegistry().Hash(historyhash.SHA512, []byte(consortiumFile))
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/.well-known/did-trustbloc/history/"+hash+".json", r.URL.Path)
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		conf, err := cs.GetConsortiumHistory(serv.URL, hash, historyhash.SHA512)
+		require.NoError(t, err)
+
+		require.Equal(t, "foo.bar", conf.Config.Domain)
+	})
+
+	t.Run("failure: can't reach server", func(t *testing.T) {
+		cs := NewService()
+
+		_, err := cs.GetConsortiumHistory("https://0.0.0.0:0", hash, historyhash.SHA512)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("failure: bad response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		_, err := cs.GetConsortiumHistory(serv.URL, hash, historyhash.SHA512)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "consortium history request failed")
+	})
+
+	t.Run("failure: file doesn't match hash", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		_, err := cs.GetConsortiumHistory(serv.URL, "wrongHash", historyhash.SHA512)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match hash")
+	})
+
+	t.Run("failure: file hashed with a different algorithm", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		_, err := cs.GetConsortiumHistory(serv.URL, hash, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match hash")
+	})
+
+	t.Run("failure: unsupported hash algorithm", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService(WithHistoryHashRegistry(&historyhash.Registry{}))
+
+		_, err := cs.GetConsortiumHistory(serv.URL, hash, historyhash.SHA512)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not supported")
+	})
 }
 
 func TestConfigService_GetSidetreeConfig(t *testing.T) {
@@ -110,6 +329,40 @@ func TestConfigService_GetSidetreeConfig(t *testing.T) {
 		require.Equal(t, uint(10), c.MultiHashAlgorithm)
 	})
 
+	t.Run("success: conditional request", func(t *testing.T) {
+		requests := 0
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			w.Header().Set("Cache-Control", "max-age=60")
+
+			if r.Header.Get("If-Modified-Since") == "Wed, 21 Oct 2015 07:28:00 GMT" {
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+
+			bytes, err := json.Marshal(models.SidetreeConfig{MultiHashAlgorithm: 10})
+			require.NoError(t, err)
+
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+			fmt.Fprint(w, string(bytes))
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		for i := 0; i < 2; i++ {
+			c, err := cs.GetSidetreeConfig(serv.URL)
+			require.NoError(t, err)
+			require.Equal(t, uint(10), c.MultiHashAlgorithm)
+			require.Equal(t, uint(60), c.MaxAge)
+		}
+
+		require.Equal(t, 2, requests)
+	})
+
 	t.Run("test failed to unmarshal response", func(t *testing.T) {
 		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprint(w, "{{")
@@ -125,6 +378,65 @@ func TestConfigService_GetSidetreeConfig(t *testing.T) {
 	})
 }
 
+func TestConfigService_GetStakeholderHistory(t *testing.T) {
+	stakeholder := mockmodels.DummyStakeholder("foo.bar", []string{"endpoint.website/go/here/"})
+
+	stakeholderFile, err := mockmodels.WrapStakeholder(stakeholder)
+	require.NoError(t, err)
+
+	hash, err := historyhash.NewRegistry().Hash(historyhash.SHA256, []byte(stakeholderFile))
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/.well-known/did-trustbloc/history/"+hash+".json", r.URL.Path)
+			fmt.Fprint(w, stakeholderFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		conf, err := cs.GetStakeholderHistory(serv.URL, hash, historyhash.SHA256)
+		require.NoError(t, err)
+
+		require.Equal(t, "foo.bar", conf.Config.Domain)
+	})
+
+	t.Run("failure: can't reach server", func(t *testing.T) {
+		cs := NewService()
+
+		_, err := cs.GetStakeholderHistory("https://0.0.0.0:0", hash, historyhash.SHA256)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("failure: bad response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		_, err := cs.GetStakeholderHistory(serv.URL, hash, historyhash.SHA256)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder history request failed")
+	})
+
+	t.Run("failure: file doesn't match hash", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, stakeholderFile)
+		}))
+		defer serv.Close()
+
+		cs := NewService()
+
+		_, err := cs.GetStakeholderHistory(serv.URL, "wrongHash", historyhash.SHA256)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder history file invalid")
+	})
+}
+
 func TestConfigService_GetStakeholder(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		stakeholder := mockmodels.DummyStakeholder("foo.bar", []string{
@@ -234,4 +546,49 @@ func TestOpts(t *testing.T) {
 
 		require.Equal(t, "test", cs.tlsConfig.ServerName)
 	})
+
+	t.Run("test http client", func(t *testing.T) {
+		client := &http.Client{}
+
+		cs := NewService(WithHTTPClient(client), WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+		require.Same(t, client, cs.httpClient)
+	})
+}
+
+func TestConfigService_AuthToken(t *testing.T) {
+	consortiumFile, err := mockmodels.WrapConsortium(
+		mockmodels.DummyConsortium("foo.bar", []*models.StakeholderListElement{{Domain: "bar.baz"}}))
+	require.NoError(t, err)
+
+	stakeholderFile, err := mockmodels.WrapStakeholder(
+		mockmodels.DummyStakeholder("bar.baz", []string{"https://bar.baz/webapi/123456"}))
+	require.NoError(t, err)
+
+	var authorizations []string
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+
+		if strings.Contains(r.URL.Path, "bar.baz") {
+			fmt.Fprint(w, stakeholderFile)
+
+			return
+		}
+
+		fmt.Fprint(w, consortiumFile)
+	}))
+	defer serv.Close()
+
+	cs := NewService(WithAuthToken("tk1"))
+
+	_, err = cs.GetConsortium(serv.URL, "foo.bar")
+	require.NoError(t, err)
+
+	_, err = cs.GetStakeholder(serv.URL, "bar.baz")
+	require.NoError(t, err)
+
+	_, err = cs.GetSidetreeConfig(serv.URL)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"Bearer tk1", "Bearer tk1", "Bearer tk1"}, authorizations)
 }
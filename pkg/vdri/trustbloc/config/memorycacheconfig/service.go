@@ -6,104 +6,361 @@ SPDX-License-Identifier: Apache-2.0
 package memorycacheconfig
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/bluele/gcache"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 type config interface {
-	GetConsortium(string, string) (*models.ConsortiumFileData, error)
-	GetStakeholder(string, string) (*models.StakeholderFileData, error)
-	GetSidetreeConfig(url string) (*models.SidetreeConfig, error)
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
 }
 
-// ConfigService fetches consortium and stakeholder configs using a wrapped config service, caching results in-memory
+// ConfigService fetches consortium and stakeholder configs using a wrapped config service, caching results in-memory.
+// Stakeholder configs are cached separately for each consortium set on the context with models.WithConsortium, as the
+// wrapped service may validate them against the consortium.
+// Concurrent loads of the same config share a single fetch, which isn't canceled when a caller gives up.
+// Optionally, an expired config is served while it is refreshed in the background, and fetch failures are cached so
+// a failing source isn't queried on every call.
 type ConfigService struct {
 	config              config
-	cCache              gcache.Cache
-	sCache              gcache.Cache
-	sidetreeConfigCache gcache.Cache
+	cCache              *cache
+	sCache              *cache
+	sidetreeConfigCache *cache
+	staleTTL            time.Duration
+	negativeTTL         time.Duration
+	loadTimeout         time.Duration
 }
 
+// defaultLoadTimeout bounds a shared fetch, which doesn't use the context of any of the callers waiting for it
+const defaultLoadTimeout = 30 * time.Second
+
 // NewService create new ConfigService
-func NewService(config config) *ConfigService {
+func NewService(config config, opts ...Option) *ConfigService {
 	configService := &ConfigService{
-		config: config,
+		config:              config,
+		cCache:              newCache("consortium"),
+		sCache:              newCache("stakeholder"),
+		sidetreeConfigCache: newCache("sidetreeconfig"),
+		loadTimeout:         defaultLoadTimeout,
 	}
 
-	configService.cCache = makeCache(
-		configService.getNewCacheable(func(url, domain string) (cacheable, error) {
-			return configService.config.GetConsortium(url, domain)
-		}))
-
-	configService.sCache = makeCache(
-		configService.getNewCacheable(func(url, domain string) (cacheable, error) {
-			return configService.config.GetStakeholder(url, domain)
-		}))
-
-	configService.sidetreeConfigCache = makeCache(
-		configService.getNewCacheable(func(url, domain string) (cacheable, error) {
-			return configService.config.GetSidetreeConfig(url)
-		}))
+	for _, opt := range opts {
+		opt(configService)
+	}
 
 	return configService
 }
 
-type stringPair struct {
+// cacheKey is the key of a cached config. Stakeholder configs are also keyed by the consortium they're validated for.
+type cacheKey struct {
 	url, domain string
+	consortium  models.ConsortiumRef
+}
+
+type cacheable interface {
+	CacheLifetime() (time.Duration, error)
+}
+
+type fetcher func(ctx context.Context, url, domain string) (cacheable, error)
+
+// cache holds the entries for one kind of config, and the loads in progress for its keys
+type cache struct {
+	objectName string
+	entries    gcache.Cache
+
+	// lock guards loads
+	lock  sync.Mutex
+	loads map[cacheKey]*load
+}
+
+func newCache(objectName string) *cache {
+	return &cache{objectName: objectName, entries: gcache.New(0).Build(), loads: map[cacheKey]*load{}}
+}
+
+// entry is a cached config, and the last failure to refresh it if failures are cached.
+// Entries are replaced rather than modified, so they can be read without locking.
+type entry struct {
+	data   cacheable
+	expiry time.Time
+
+	err       error
+	errExpiry time.Time
+}
+
+// load is a fetch in progress, shared by the callers loading the same key. Its fields are set before done is closed.
+type load struct {
+	done chan struct{}
+	data cacheable
+	err  error
+}
+
+// get returns the entry under the given key, or an empty entry if there is none
+func (c *cache) get(key cacheKey) (*entry, error) {
+	cached, err := c.entries.Get(key)
+	if errors.Is(err, gcache.KeyNotFoundError) {
+		return &entry{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("getting %s from cache: %w", c.objectName, err)
+	}
+
+	return cached.(*entry), nil
+}
+
+// cachedFailure returns the error for a cached failure to fetch the entry
+func (c *cache) cachedFailure(e *entry) error {
+	return fmt.Errorf("getting %s from cache: fetching cacheable object (cached failure): %w", c.objectName, e.err)
+}
+
+// fresh returns whether the entry holds an object that hasn't expired
+func (e *entry) fresh(now time.Time) bool {
+	return e.data != nil && now.Before(e.expiry)
 }
 
-func makeCache(fetcher func(url, domain string) (interface{}, *time.Duration, error)) gcache.Cache {
-	return gcache.New(0).LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
-		keyStrPair, ok := key.(stringPair)
-		if !ok {
-			return nil, nil, fmt.Errorf("key must be stringPair")
+// servable returns whether the entry holds an object that expired less than maxStale ago
+func (e *entry) servable(now time.Time, maxStale time.Duration) bool {
+	return e.data != nil && now.Before(e.expiry.Add(maxStale))
+}
+
+// getEntryHelper returns the cached object under the given key, using fetch to fetch and cache the object
+// if it is missing or expired
+func (cs *ConfigService) getEntryHelper(ctx context.Context, c *cache, key cacheKey, fetch fetcher,
+) (cacheable, error) {
+	e, err := c.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	switch {
+	case e.fresh(now):
+		return e.data, nil
+	case e.servable(now, cs.staleTTL):
+		// while a refresh failure is cached, the expired object is served without retrying
+		if !now.Before(e.errExpiry) {
+			go cs.refresh(c, key, fetch)
 		}
 
-		return fetcher(keyStrPair.url, keyStrPair.domain)
-	}).Build()
+		return e.data, nil
+	case now.Before(e.errExpiry):
+		return nil, c.cachedFailure(e)
+	}
+
+	return cs.load(ctx, c, key, fetch, false)
 }
 
-type cacheable interface {
-	CacheLifetime() (time.Duration, error)
+// refresh reloads an expired object in the background, while the expired object is served
+func (cs *ConfigService) refresh(c *cache, key cacheKey, fetch fetcher) {
+	_, err := cs.load(context.Background(), c, key, fetch, true)
+	if err != nil {
+		log.Warnf("refreshing %s for %s %s: %s", c.objectName, key.url, key.domain, err.Error())
+	}
+}
+
+// load fetches and caches the object under the given key, or if the key is already being loaded, shares that load.
+// The fetch runs on its own context, so a caller giving up doesn't fail the others waiting for it.
+func (cs *ConfigService) load(ctx context.Context, c *cache, key cacheKey, fetch fetcher, refresh bool,
+) (cacheable, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("getting %s from cache: %w", c.objectName, ctx.Err())
+	}
+
+	c.lock.Lock()
+
+	l, ok := c.loads[key]
+	if !ok {
+		l = &load{done: make(chan struct{})}
+		c.loads[key] = l
+
+		go cs.runLoad(c, key, l, fetch, refresh)
+	}
+
+	c.lock.Unlock()
+
+	select {
+	case <-l.done:
+		return l.data, l.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("getting %s from cache: %w", c.objectName, ctx.Err())
+	}
 }
 
-func (cs *ConfigService) getNewCacheable(
-	fetcher func(url, domain string) (cacheable, error),
-) func(url, domain string) (interface{}, *time.Duration, error) {
-	return func(url, domain string) (interface{}, *time.Duration, error) {
-		data, err := fetcher(url, domain)
-		if err != nil {
-			return nil, nil, fmt.Errorf("fetching cacheable object: %w", err)
+// runLoad fetches the object for the load, bounded by the load timeout, then completes the load
+func (cs *ConfigService) runLoad(c *cache, key cacheKey, l *load, fetch fetcher, refresh bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), cs.loadTimeout)
+	defer cancel()
+
+	l.data, l.err = cs.fetchEntry(ctx, c, key, fetch, refresh)
+
+	c.lock.Lock()
+	delete(c.loads, key)
+	c.lock.Unlock()
+
+	close(l.done)
+}
+
+// fetchEntry fetches the object under the given key and caches it, or caches the failure if failures are cached.
+// A background refresh is skipped if an earlier load finished since the refresh was started.
+func (cs *ConfigService) fetchEntry(ctx context.Context, c *cache, key cacheKey, fetch fetcher, refresh bool,
+) (cacheable, error) {
+	if refresh {
+		if e, err := c.get(key); err == nil && e.fresh(time.Now()) {
+			return e.data, nil
+		} else if err == nil && time.Now().Before(e.errExpiry) {
+			return nil, c.cachedFailure(e)
 		}
+	}
 
-		expiryTime, err := data.CacheLifetime()
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get object expiry time: %w", err)
+	fetched, err := fetch(ctx, key.url, key.domain)
+	if err != nil {
+		if cs.negativeTTL > 0 {
+			cs.cacheFailure(c, key, err)
 		}
 
-		return data, &expiryTime, nil
+		return nil, fmt.Errorf("getting %s from cache: fetching cacheable object: %w", c.objectName, err)
 	}
+
+	expiryTime, err := fetched.CacheLifetime()
+	if err != nil {
+		return nil, fmt.Errorf("getting %s from cache: failed to get object expiry time: %w", c.objectName, err)
+	}
+
+	err = c.entries.Set(key, &entry{data: fetched, expiry: time.Now().Add(expiryTime)})
+	if err != nil {
+		return nil, fmt.Errorf("caching %s: %w", c.objectName, err)
+	}
+
+	return fetched, nil
 }
 
-func getEntryHelper(cache gcache.Cache, key interface{}, objectName string) (interface{}, error) {
-	data, err := cache.Get(key)
+// cacheFailure records a failure to fetch the object under the given key, keeping any previously cached object
+func (cs *ConfigService) cacheFailure(c *cache, key cacheKey, fetchErr error) {
+	failed := &entry{err: fetchErr, errExpiry: time.Now().Add(cs.negativeTTL)}
+
+	if previous, err := c.get(key); err == nil {
+		failed.data, failed.expiry = previous.data, previous.expiry
+	}
+
+	err := c.entries.Set(key, failed)
 	if err != nil {
-		return nil, fmt.Errorf("getting %s from cache: %w", objectName, err)
+		log.Warnf("caching %s failure: %s", c.objectName, err.Error())
+	}
+}
+
+// Entry describes a cached config
+type Entry struct {
+	// Kind is the kind of config: consortium, stakeholder or sidetreeconfig
+	Kind string
+	// URL is the url the config was fetched from
+	URL string
+	// Domain is the domain of the config, empty for a sidetree config
+	Domain string
+	// Consortium is the domain of the consortium a stakeholder config is cached for, if any
+	Consortium string
+	// Expiry is the time the config expires, zero if only a failure is cached
+	Expiry time.Time
+	// Error is the cached failure to fetch the config, if any
+	Error string
+	// ErrorExpiry is the time the cached failure expires
+	ErrorExpiry time.Time
+}
+
+// Entries returns the cached configs and fetch failures, including expired configs that may still be served,
+// ordered by kind, url, domain and consortium
+func (cs *ConfigService) Entries() []*Entry {
+	var entries []*Entry
+
+	for _, c := range cs.caches() {
+		for k, v := range c.entries.GetALL(false) {
+			key, e := k.(cacheKey), v.(*entry)
+
+			listed := &Entry{Kind: c.objectName, URL: key.url, Domain: key.domain, Consortium: key.consortium.Domain,
+				Expiry: e.expiry}
+
+			if e.err != nil {
+				listed.Error, listed.ErrorExpiry = e.err.Error(), e.errExpiry
+			}
+
+			entries = append(entries, listed)
+		}
 	}
 
-	return data, nil
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+
+		return a.Consortium < b.Consortium
+	})
+
+	return entries
 }
 
-// GetConsortium fetches and parses the consortium file at the given domain, caching the value
+// Evict removes the cached configs and fetch failures whose url, domain or consortium is the given domain,
+// returning the number removed. A load in progress for an evicted config still caches its result.
+func (cs *ConfigService) Evict(domain string) int {
+	evicted := 0
+
+	for _, c := range cs.caches() {
+		for _, k := range c.entries.Keys(false) {
+			key := k.(cacheKey)
+			if key.url == domain || key.domain == domain || key.consortium.Domain == domain {
+				if c.entries.Remove(key) {
+					evicted++
+				}
+			}
+		}
+	}
+
+	return evicted
+}
+
+// Flush removes all cached configs and fetch failures
+func (cs *ConfigService) Flush() {
+	for _, c := range cs.caches() {
+		c.entries.Purge()
+	}
+}
+
+func (cs *ConfigService) caches() []*cache {
+	return []*cache{cs.cCache, cs.sCache, cs.sidetreeConfigCache}
+}
+
+// GetConsortium calls GetConsortiumWithContext with a background context
 func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
-	consortiumDataInterface, err := getEntryHelper(cs.cCache, stringPair{
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext fetches and parses the consortium file at the given domain, caching the value
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	consortiumDataInterface, err := cs.getEntryHelper(ctx, cs.cCache, cacheKey{
 		url:    url,
 		domain: domain,
-	}, "consortium")
+	}, func(ctx context.Context, url, domain string) (cacheable, error) {
+		return cs.config.GetConsortiumWithContext(ctx, url, domain)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -111,12 +368,29 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 	return consortiumDataInterface.(*models.ConsortiumFileData), nil
 }
 
-// GetStakeholder returns the stakeholder config file fetched by the wrapped config service, caching the value
+// GetStakeholder calls GetStakeholderWithContext with a background context
 func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
-	stakeholderDataInterface, err := getEntryHelper(cs.sCache, stringPair{
-		url:    url,
-		domain: domain,
-	}, "stakeholder")
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext returns the stakeholder config file fetched by the wrapped config service, caching the
+// value for the consortium set on the context, if any
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	consortium, scoped := models.ConsortiumFromContext(ctx)
+
+	stakeholderDataInterface, err := cs.getEntryHelper(ctx, cs.sCache, cacheKey{
+		url:        url,
+		domain:     domain,
+		consortium: consortium,
+	}, func(ctx context.Context, url, domain string) (cacheable, error) {
+		// a background refresh doesn't have the caller's context
+		if scoped {
+			ctx = models.WithConsortium(ctx, consortium.URL, consortium.Domain)
+		}
+
+		return cs.config.GetStakeholderWithContext(ctx, url, domain)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -124,14 +398,48 @@ func (cs *ConfigService) GetStakeholder(url, domain string) (*models.Stakeholder
 	return stakeholderDataInterface.(*models.StakeholderFileData), nil
 }
 
-// GetSidetreeConfig returns the sidetree config
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
 func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
-	sidetreeConfigDataInterface, err := getEntryHelper(cs.sidetreeConfigCache, stringPair{
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext returns the sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
+	sidetreeConfigDataInterface, err := cs.getEntryHelper(ctx, cs.sidetreeConfigCache, cacheKey{
 		url: url,
-	}, "sidetreeconfig")
+	}, func(ctx context.Context, url, _ string) (cacheable, error) {
+		return cs.config.GetSidetreeConfigWithContext(ctx, url)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return sidetreeConfigDataInterface.(*models.SidetreeConfig), nil
 }
+
+// Option is a config service instance option
+type Option func(opts *ConfigService)
+
+// WithStaleWhileRevalidate serves an expired config for up to the given duration after it expires, while it is
+// refreshed in the background. Disabled by default, so an expired config is refreshed before it is returned.
+func WithStaleWhileRevalidate(maxStale time.Duration) Option {
+	return func(opts *ConfigService) {
+		opts.staleTTL = maxStale
+	}
+}
+
+// WithNegativeCaching caches a failure to fetch a config for the given duration, during which the failure is returned
+// without querying the wrapped service again. Disabled by default.
+func WithNegativeCaching(ttl time.Duration) Option {
+	return func(opts *ConfigService) {
+		opts.negativeTTL = ttl
+	}
+}
+
+// WithLoadTimeout bounds the time spent fetching a config, which is shared by the callers loading it.
+// Defaults to 30 seconds.
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(opts *ConfigService) {
+		opts.loadTimeout = timeout
+	}
+}
@@ -6,8 +6,13 @@ SPDX-License-Identifier: Apache-2.0
 package memorycacheconfig
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -210,19 +215,78 @@ func TestConfigService_GetStakeholder(t *testing.T) {
 		require.Contains(t, err.Error(), "missing")
 	})
 
-	t.Run("failure - bad lookup key", func(t *testing.T) {
+	t.Run("failure - wrapped service errors aren't cached", func(t *testing.T) {
+		callCount := 0
+
 		cs := NewService(&mockconfig.MockConfigService{
 			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
-				return &models.StakeholderFileData{Config: nil}, nil
+				callCount++
+				if callCount == 1 {
+					return nil, fmt.Errorf("stakeholder error")
+				}
+
+				return &models.StakeholderFileData{Config: mockmodels.DummyStakeholder("foo.bar", nil)}, nil
 			}})
 
-		_, err := cs.sCache.Get(struct{}{})
+		_, err := cs.GetStakeholderWithContext(context.Background(), "foo.bar", "foo.bar")
 		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder error")
 
-		require.Contains(t, err.Error(), "key must be stringPair")
+		conf, err := cs.GetStakeholderWithContext(context.Background(), "foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", conf.Config.Domain)
 	})
 }
 
+// consortiumConfigService records the consortium set on the context of the stakeholder configs it fetches
+type consortiumConfigService struct {
+	mockconfig.MockConfigService
+	consortia []string
+}
+
+func (m *consortiumConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	consortium, _ := models.ConsortiumFromContext(ctx)
+	m.consortia = append(m.consortia, consortium.Domain)
+
+	return m.GetStakeholder(url, domain)
+}
+
+func TestConfigService_GetStakeholderForConsortium(t *testing.T) {
+	stakeholder := mockmodels.DummyStakeholder("foo.bar", []string{"foo", "bar"})
+	stakeholder.Policy.Cache.MaxAge = 1000
+
+	wrapped := &consortiumConfigService{MockConfigService: mockconfig.MockConfigService{
+		GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+			return &models.StakeholderFileData{Config: stakeholder}, nil
+		}}}
+
+	cs := NewService(wrapped)
+
+	for i := 0; i < 2; i++ {
+		for _, consortium := range []string{"one.consortium", "two.consortium"} {
+			_, err := cs.GetStakeholderWithContext(models.WithConsortium(context.Background(), consortium, consortium),
+				"foo.bar", "foo.bar")
+			require.NoError(t, err)
+		}
+
+		_, err := cs.GetStakeholder("foo.bar", "foo.bar")
+		require.NoError(t, err)
+	}
+
+	// each consortium's copy is fetched once, for that consortium
+	require.Equal(t, []string{"one.consortium", "two.consortium", ""}, wrapped.consortia)
+
+	entries := cs.Entries()
+	require.Len(t, entries, 3)
+	require.Equal(t, "", entries[0].Consortium)
+	require.Equal(t, "one.consortium", entries[1].Consortium)
+	require.Equal(t, "two.consortium", entries[2].Consortium)
+
+	require.Equal(t, 1, cs.Evict("one.consortium"))
+	require.Len(t, cs.Entries(), 2)
+}
+
 func TestConfigService_GetSidetreeConfig(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
@@ -285,3 +349,393 @@ func TestConfigService_GetSidetreeConfig(t *testing.T) {
 		require.Contains(t, err.Error(), "double-call")
 	})
 }
+
+func TestConfigService_StaleWhileRevalidate(t *testing.T) {
+	t.Run("success - expired config served while refreshed in the background", func(t *testing.T) {
+		var lock sync.Mutex
+
+		version := 0
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(u string) (*models.SidetreeConfig, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				version++
+
+				return &models.SidetreeConfig{MultiHashAlgorithm: uint(version), MaxAge: 0}, nil
+			}}, WithStaleWhileRevalidate(time.Hour))
+
+		conf, err := cs.GetSidetreeConfig("foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, uint(1), conf.MultiHashAlgorithm)
+
+		conf, err = cs.GetSidetreeConfig("foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, uint(1), conf.MultiHashAlgorithm)
+
+		require.Eventually(t, func() bool {
+			conf, err = cs.GetSidetreeConfig("foo.bar")
+			require.NoError(t, err)
+
+			return conf.MultiHashAlgorithm > 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("success - expired config served while refresh fails", func(t *testing.T) {
+		callCount := 0
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+				if callCount > 1 {
+					return nil, fmt.Errorf("consortium server down")
+				}
+
+				return &models.ConsortiumFileData{Config: mockmodels.DummyConsortium("foo.bar", nil)}, nil
+			}}, WithStaleWhileRevalidate(time.Hour), WithNegativeCaching(time.Hour))
+
+		for i := 0; i < 5; i++ {
+			conf, err := cs.GetConsortium("foo.bar", "foo.bar")
+			require.NoError(t, err)
+			require.Equal(t, "foo.bar", conf.Config.Domain)
+		}
+
+		// the first refresh fails, and the failure is cached so no more refreshes are attempted
+		require.Eventually(t, func() bool {
+			e, err := cs.cCache.get(cacheKey{url: "foo.bar", domain: "foo.bar"})
+			require.NoError(t, err)
+
+			return e.err != nil
+		}, time.Second, 10*time.Millisecond)
+
+		_, err := cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, 2, callCount)
+	})
+
+	t.Run("failure - config expired for longer than the stale duration is refetched", func(t *testing.T) {
+		callCount := 0
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				callCount++
+				if callCount > 1 {
+					return nil, fmt.Errorf("double-call")
+				}
+
+				return &models.StakeholderFileData{Config: mockmodels.DummyStakeholder("foo.bar", nil)}, nil
+			}}, WithStaleWhileRevalidate(time.Nanosecond))
+
+		_, err := cs.GetStakeholder("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		time.Sleep(time.Millisecond)
+
+		_, err = cs.GetStakeholder("foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "double-call")
+	})
+}
+
+func TestConfigService_NegativeCaching(t *testing.T) {
+	t.Run("failure - fetch failure is cached", func(t *testing.T) {
+		callCount := 0
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return nil, fmt.Errorf("consortium server down")
+			}}, WithNegativeCaching(time.Hour))
+
+		for i := 0; i < 5; i++ {
+			_, err := cs.GetConsortium("foo.bar", "foo.bar")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "consortium server down")
+		}
+
+		require.Equal(t, 1, callCount)
+
+		// failures are cached per key
+		_, err := cs.GetConsortium("foo.bar", "bar.baz")
+		require.Error(t, err)
+		require.Equal(t, 2, callCount)
+	})
+
+	t.Run("success - fetch is retried when the cached failure expires", func(t *testing.T) {
+		callCount := 0
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+				if callCount == 1 {
+					return nil, fmt.Errorf("consortium server down")
+				}
+
+				return &models.ConsortiumFileData{Config: mockmodels.DummyConsortium("foo.bar", nil)}, nil
+			}}, WithNegativeCaching(time.Nanosecond))
+
+		_, err := cs.GetConsortium("foo.bar", "foo.bar")
+		require.Error(t, err)
+
+		time.Sleep(time.Millisecond)
+
+		conf, err := cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", conf.Config.Domain)
+	})
+
+	t.Run("failure - canceled caller doesn't fetch", func(t *testing.T) {
+		callCount := 0
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				callCount++
+
+				return nil, fmt.Errorf("consortium server down")
+			}}, WithNegativeCaching(time.Hour))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := cs.GetConsortiumWithContext(ctx, "foo.bar", "foo.bar")
+		require.True(t, errors.Is(err, context.Canceled))
+		require.Equal(t, 0, callCount)
+
+		_, err = cs.GetConsortium("foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.Equal(t, 1, callCount)
+	})
+}
+
+func TestConfigService_SingleFlight(t *testing.T) {
+	t.Run("success - concurrent loads share one fetch", func(t *testing.T) {
+		var calls int32
+
+		release := make(chan struct{})
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+
+				return &models.ConsortiumFileData{Config: mockmodels.DummyConsortium("foo.bar", nil)}, nil
+			}})
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				conf, err := cs.GetConsortium("foo.bar", "foo.bar")
+				require.NoError(t, err)
+				require.Equal(t, "foo.bar", conf.Config.Domain)
+			}()
+		}
+
+		// wait for the loads to join the first one before it completes
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt32(&calls) == 1
+		}, time.Second, time.Millisecond)
+
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		wg.Wait()
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("failure - waiting load canceled", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				<-release
+
+				return &models.StakeholderFileData{Config: mockmodels.DummyStakeholder("foo.bar", nil)}, nil
+			}})
+
+		go func() {
+			_, _ = cs.GetStakeholder("foo.bar", "foo.bar") // nolint: errcheck
+		}()
+
+		require.Eventually(t, func() bool {
+			cs.sCache.lock.Lock()
+			defer cs.sCache.lock.Unlock()
+
+			return len(cs.sCache.loads) == 1
+		}, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := cs.GetStakeholderWithContext(ctx, "foo.bar", "foo.bar")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("success - first caller giving up doesn't fail the waiting loads", func(t *testing.T) {
+		release := make(chan struct{})
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				<-release
+
+				return &models.ConsortiumFileData{Config: mockmodels.DummyConsortium("foo.bar", nil)}, nil
+			}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		first := make(chan error)
+
+		go func() {
+			_, err := cs.GetConsortiumWithContext(ctx, "foo.bar", "foo.bar")
+			first <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			cs.cCache.lock.Lock()
+			defer cs.cCache.lock.Unlock()
+
+			return len(cs.cCache.loads) == 1
+		}, time.Second, time.Millisecond)
+
+		second := make(chan error)
+
+		go func() {
+			_, err := cs.GetConsortium("foo.bar", "foo.bar")
+			second <- err
+		}()
+
+		cancel()
+		require.True(t, errors.Is(<-first, context.Canceled))
+
+		close(release)
+		require.NoError(t, <-second)
+	})
+
+	t.Run("failure - shared fetch times out", func(t *testing.T) {
+		cs := NewService(&blockingConfigService{}, WithLoadTimeout(10*time.Millisecond))
+
+		_, err := cs.GetSidetreeConfig("foo.bar")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+// blockingConfigService blocks fetching a sidetree config until the context is done
+type blockingConfigService struct {
+	mockconfig.MockConfigService
+}
+
+func (m *blockingConfigService) GetSidetreeConfigWithContext(ctx context.Context, _ string,
+) (*models.SidetreeConfig, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func TestConfigService_Entries(t *testing.T) {
+	newService := func() *ConfigService {
+		return NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				if d == "down.website" {
+					return nil, fmt.Errorf("consortium server down")
+				}
+
+				consortium := mockmodels.DummyConsortium(d, nil)
+				consortium.Policy.Cache.MaxAge = 1000
+
+				return &models.ConsortiumFileData{Config: consortium}, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return &models.StakeholderFileData{Config: mockmodels.DummyStakeholder(d, nil)}, nil
+			},
+			GetSidetreeConfigFunc: func(u string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18, MaxAge: 1000}, nil
+			}}, WithNegativeCaching(time.Hour))
+	}
+
+	load := func(t *testing.T, cs *ConfigService) {
+		t.Helper()
+
+		_, err := cs.GetConsortium("foo.bar", "foo.bar")
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("down.website", "down.website")
+		require.Error(t, err)
+
+		_, err = cs.GetStakeholder("bar.baz", "bar.baz")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("foo.bar", "stakeholder.foo.bar")
+		require.NoError(t, err)
+
+		_, err = cs.GetSidetreeConfig("https://bar.baz/sidetree/0.0.1")
+		require.NoError(t, err)
+	}
+
+	t.Run("success - list entries", func(t *testing.T) {
+		cs := newService()
+		require.Empty(t, cs.Entries())
+
+		start := time.Now()
+
+		load(t, cs)
+
+		entries := cs.Entries()
+		require.Len(t, entries, 5)
+
+		require.Equal(t, "consortium", entries[0].Kind)
+		require.Equal(t, "down.website", entries[0].Domain)
+		require.True(t, entries[0].Expiry.IsZero())
+		require.Contains(t, entries[0].Error, "consortium server down")
+		require.True(t, entries[0].ErrorExpiry.After(start))
+
+		require.Equal(t, "consortium", entries[1].Kind)
+		require.Equal(t, "foo.bar", entries[1].Domain)
+		require.True(t, entries[1].Expiry.After(start.Add(999*time.Second)))
+		require.Empty(t, entries[1].Error)
+
+		require.Equal(t, "sidetreeconfig", entries[2].Kind)
+		require.Equal(t, "https://bar.baz/sidetree/0.0.1", entries[2].URL)
+		require.Empty(t, entries[2].Domain)
+
+		require.Equal(t, "stakeholder", entries[3].Kind)
+		require.Equal(t, "bar.baz", entries[3].Domain)
+
+		require.Equal(t, "stakeholder", entries[4].Kind)
+		require.Equal(t, "stakeholder.foo.bar", entries[4].Domain)
+	})
+
+	t.Run("success - evict by domain", func(t *testing.T) {
+		cs := newService()
+		load(t, cs)
+
+		// matches both the consortium domain and the url the stakeholder was fetched from
+		require.Equal(t, 2, cs.Evict("foo.bar"))
+		require.Len(t, cs.Entries(), 3)
+
+		require.Equal(t, 1, cs.Evict("down.website"))
+		require.Equal(t, 0, cs.Evict("unknown.website"))
+		require.Len(t, cs.Entries(), 2)
+
+		_, err := cs.GetConsortium("down.website", "down.website")
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "cached failure")
+	})
+
+	t.Run("success - flush", func(t *testing.T) {
+		cs := newService()
+		load(t, cs)
+
+		cs.Flush()
+		require.Empty(t, cs.Entries())
+	})
+}
@@ -24,26 +24,34 @@ func VerifyConsortiumSignatures(signedData *models.ConsortiumFileData, signerCon
 
 	perm := rand.Perm(len(signerConsortium.Members))
 	verifiedCount := 0
-	verificationErrors := ""
+
+	var verificationErrors []error
 
 	for i := 0; i < len(signerConsortium.Members); i++ {
+		stakeholder := signerConsortium.Members[perm[i]].Domain
 		keyData := signerConsortium.Members[perm[i]].PublicKey.JWK
 		key := jose.JSONWebKey{}
 
 		err := key.UnmarshalJSON(keyData)
 		if err != nil {
-			msg := "bad key for stakeholder: " + signerConsortium.Members[perm[i]].Domain
-			logrus.Warn(msg)
-			verificationErrors += msg + ", "
+			logrus.Warn("bad key for stakeholder: " + stakeholder)
+
+			verificationErrors = append(verificationErrors, &models.InvalidStakeholderSignature{
+				Domain: stakeholder,
+				Err:    fmt.Errorf("bad key for stakeholder: %w", err),
+			})
 
 			continue
 		}
 
 		_, _, _, err = signedData.JWS.VerifyMulti(key)
 		if err != nil {
-			msg := "key fails to verify for stakeholder: " + signerConsortium.Members[perm[i]].Domain
-			logrus.Warn(msg)
-			verificationErrors += msg + ", "
+			logrus.Warn("key fails to verify for stakeholder: " + stakeholder)
+
+			verificationErrors = append(verificationErrors, &models.InvalidStakeholderSignature{
+				Domain: stakeholder,
+				Err:    fmt.Errorf("key fails to verify for stakeholder: %w", err),
+			})
 
 			continue
 		}
@@ -56,9 +64,17 @@ func VerifyConsortiumSignatures(signedData *models.ConsortiumFileData, signerCon
 	}
 
 	if verifiedCount < n {
-		return fmt.Errorf(
-			"insufficient stakeholder endorsement of consortium config file. errors are: [%s]",
-			verificationErrors)
+		e := &models.InsufficientEndorsement{
+			Required:     n,
+			Endorsed:     verifiedCount,
+			Stakeholders: verificationErrors,
+		}
+
+		if signedData.Config != nil {
+			e.Domain = signedData.Config.Domain
+		}
+
+		return e
 	}
 
 	return nil
@@ -6,32 +6,54 @@ SPDX-License-Identifier: Apache-2.0
 package signatureconfig
 
 import (
+	"context"
 	"fmt"
+	"sync"
+
+	"github.com/square/go-jose/v3"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 type config interface {
-	GetConsortium(string, string) (*models.ConsortiumFileData, error)
-	GetStakeholder(string, string) (*models.StakeholderFileData, error)
-	GetSidetreeConfig(url string) (*models.SidetreeConfig, error)
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetConsortiumHistoryWithContext(context.Context, string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetStakeholderHistoryWithContext(context.Context, string, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
 }
 
 // ConfigService fetches consortium and stakeholder configs over http
+// Consortium configs are verified against the keys of their stakeholders. A config whose JWS was verified before is
+// not verified again, so configs which weren't modified since they were last fetched are verified only once.
 type ConfigService struct {
 	config config
+
+	// lock guards verified
+	lock     sync.Mutex
+	verified map[stringPair]*jose.JSONWebSignature
+}
+
+type stringPair struct {
+	url, domain string
 }
 
 // NewService create new ConfigService
 func NewService(config config) *ConfigService {
-	configService := &ConfigService{config: config}
+	configService := &ConfigService{config: config, verified: map[stringPair]*jose.JSONWebSignature{}}
 
 	return configService
 }
 
-// GetConsortium fetches and parses the consortium file at the given domain
+// GetConsortium calls GetConsortiumWithContext with a background context
 func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
-	consortiumData, err := cs.config.GetConsortium(url, domain)
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext fetches and parses the consortium file at the given domain
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	consortiumData, err := cs.config.GetConsortiumWithContext(ctx, url, domain)
 	if err != nil {
 		return nil, fmt.Errorf("wrapped config service: %w", err)
 	}
@@ -41,20 +63,78 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 		return nil, fmt.Errorf("consortium is nil")
 	}
 
+	key := stringPair{url: url, domain: domain}
+
+	if cs.isVerified(key, consortiumData.JWS) {
+		return consortiumData, nil
+	}
+
 	err = VerifyConsortiumSignatures(consortiumData, consortium)
 	if err != nil {
 		return nil, err
 	}
 
+	cs.setVerified(key, consortiumData.JWS)
+
 	return consortiumData, nil
 }
 
-// GetStakeholder returns the stakeholder config file fetched by the wrapped config service
+// isVerified returns whether the given JWS is the last JWS verified for the given url and domain.
+// The wrapped service returns the same JWS again for a config that wasn't modified.
+func (cs *ConfigService) isVerified(key stringPair, jws *jose.JSONWebSignature) bool {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	return jws != nil && cs.verified[key] == jws
+}
+
+// setVerified records the given JWS as the last JWS verified for the given url and domain
+func (cs *ConfigService) setVerified(key stringPair, jws *jose.JSONWebSignature) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	cs.verified[key] = jws
+}
+
+// GetConsortiumHistory calls GetConsortiumHistoryWithContext with a background context
+func (cs *ConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetConsortiumHistoryWithContext returns the historical consortium config file fetched by the wrapped config service
+func (cs *ConfigService) GetConsortiumHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	return cs.config.GetConsortiumHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetStakeholder calls GetStakeholderWithContext with a background context
 func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
-	return cs.config.GetStakeholder(url, domain)
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext returns the stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderWithContext(ctx, url, domain)
+}
+
+// GetStakeholderHistory calls GetStakeholderHistoryWithContext with a background context
+func (cs *ConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
 }
 
-// GetSidetreeConfig get sidetree config
+// GetStakeholderHistoryWithContext returns the historical stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
 func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
-	return cs.config.GetSidetreeConfig(url)
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext get sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
+	return cs.config.GetSidetreeConfigWithContext(ctx, url)
 }
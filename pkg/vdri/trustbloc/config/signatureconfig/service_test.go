@@ -7,6 +7,7 @@ package signatureconfig
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -76,6 +77,50 @@ func TestConfigService_GetConsortium(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("success - config with the same JWS isn't verified again", func(t *testing.T) {
+		rawPubKey := []byte(`{
+  "kty": "OKP",
+  "kid": "key1",
+  "crv": "Ed25519",
+  "x": "bWRCy8DtNhRO3HdKTFB2eEG5Ac1J00D0DQPffOwtAD0"
+}`)
+
+		config := models.Consortium{
+			Members: []*models.StakeholderListElement{
+				{PublicKey: models.PublicKey{JWK: json.RawMessage(rawPubKey)}},
+			},
+		}
+
+		sig, err := signConsortium(&config, sigKey)
+		require.NoError(t, err)
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{
+					Config: &config,
+					JWS:    sig,
+				}, nil
+			},
+		})
+
+		_, err = cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+
+		// the config's key no longer verifies the JWS, but the JWS was verified already
+		config.Members[0].PublicKey.JWK = json.RawMessage(`[]`)
+
+		_, err = cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+
+		// a JWS that wasn't verified before is verified
+		sig, err = signConsortium(&config, sigKey)
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo", "foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "insufficient stakeholder endorsement")
+	})
+
 	t.Run("failure: can't parse key", func(t *testing.T) {
 		rawPubKey := []byte(`[]`)
 
@@ -111,8 +156,9 @@ func TestConfigService_GetConsortium(t *testing.T) {
 }`)
 
 		config := models.Consortium{
+			Domain: "foo.bar",
 			Members: []*models.StakeholderListElement{
-				{PublicKey: models.PublicKey{JWK: json.RawMessage(rawPubKey)}},
+				{Domain: "bad.key", PublicKey: models.PublicKey{JWK: json.RawMessage(rawPubKey)}},
 			},
 		}
 
@@ -131,6 +177,17 @@ func TestConfigService_GetConsortium(t *testing.T) {
 		_, err = cs.GetConsortium("foo", "foo")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "insufficient stakeholder endorsement")
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Equal(t, "foo.bar", insufficient.Domain)
+		require.Equal(t, 1, insufficient.Required)
+		require.Equal(t, 0, insufficient.Endorsed)
+		require.Len(t, insufficient.Stakeholders, 1)
+
+		var invalidSignature *models.InvalidStakeholderSignature
+		require.True(t, errors.As(insufficient.Stakeholders[0], &invalidSignature))
+		require.Equal(t, "bad.key", invalidSignature.Domain)
 	})
 
 	t.Run("failure: bad key data", func(t *testing.T) {
@@ -310,6 +367,30 @@ func TestConfigService_GetConsortium_MultiSig(t *testing.T) {
 	})
 }
 
+func TestConfigService_GetConsortiumHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{Config: &models.Consortium{Domain: "foo.bar"}}, nil
+			}})
+
+		c, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", c.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
 func TestConfigService_GetStakeholder(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
@@ -334,6 +415,30 @@ func TestConfigService_GetStakeholder(t *testing.T) {
 	})
 }
 
+func TestConfigService_GetStakeholderHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return &models.StakeholderFileData{Config: &models.Stakeholder{Domain: "foo.bar"}}, nil
+			}})
+
+		sh, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", sh.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
 func TestConfigService_GetSidetreeConfig(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
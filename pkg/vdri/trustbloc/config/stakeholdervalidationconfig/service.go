@@ -0,0 +1,353 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stakeholdervalidationconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/square/go-jose/v3"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+// maxHistoryDepth bounds the number of history files followed when updating a cached stakeholder config
+const maxHistoryDepth = 100
+
+type config interface {
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetConsortiumHistoryWithContext(context.Context, string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetStakeholderHistoryWithContext(context.Context, string, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
+}
+
+// ConfigService fetches consortium and stakeholder configs
+// Records the stakeholder keys listed in the consortium configs it fetches, pins the first stakeholder config seen
+// for each stakeholder of each consortium, and when updating, verifies that the updated stakeholder config is a valid
+// update to the pinned one. Stakeholder configs are validated against the consortium set on the context with
+// models.WithConsortium, or without one, against the only consortium listing the stakeholder.
+// The service is safe for concurrent use.
+type ConfigService struct {
+	config         config
+	allowLastValid bool
+
+	// lock guards stakeholders and members
+	lock         sync.RWMutex
+	stakeholders map[pinKey]*models.StakeholderFileData
+	members      map[memberKey]*memberData
+}
+
+// memberData holds what a consortium config says about one of its stakeholders
+type memberData struct {
+	key         models.PublicKey
+	historyHash string
+}
+
+// memberKey identifies a stakeholder listed by a consortium
+type memberKey struct {
+	consortium models.ConsortiumRef
+	domain     string
+}
+
+// pinKey identifies the stakeholder config pinned for a stakeholder of a consortium
+type pinKey struct {
+	consortium  models.ConsortiumRef
+	url, domain string
+}
+
+// NewService create new ConfigService
+func NewService(config config, opts ...Option) *ConfigService {
+	configService := &ConfigService{config: config}
+
+	for _, opt := range opts {
+		opt(configService)
+	}
+
+	configService.stakeholders = map[pinKey]*models.StakeholderFileData{}
+	configService.members = map[memberKey]*memberData{}
+
+	return configService
+}
+
+// GetConsortium calls GetConsortiumWithContext with a background context
+func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext returns the consortium config file fetched by the wrapped config service,
+// recording the keys it lists for its stakeholders in place of those its previous config listed
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	consortiumData, err := cs.config.GetConsortiumWithContext(ctx, url, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if consortiumData == nil || consortiumData.Config == nil {
+		return consortiumData, nil
+	}
+
+	consortium := models.ConsortiumRef{URL: url, Domain: domain}
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	for key := range cs.members {
+		if key.consortium == consortium {
+			delete(cs.members, key)
+		}
+	}
+
+	for _, member := range consortiumData.Config.Members {
+		if member == nil {
+			continue
+		}
+
+		cs.members[memberKey{consortium: consortium, domain: member.Domain}] = &memberData{
+			key:         member.PublicKey,
+			historyHash: consortiumData.Config.Policy.HistoryHash,
+		}
+	}
+
+	return consortiumData, nil
+}
+
+// GetConsortiumHistory calls GetConsortiumHistoryWithContext with a background context
+func (cs *ConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetConsortiumHistoryWithContext returns the historical consortium config file fetched by the wrapped config service
+func (cs *ConfigService) GetConsortiumHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	return cs.config.GetConsortiumHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetStakeholder calls GetStakeholderWithContext with a background context
+func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext fetches and parses the stakeholder file at the given domain, validating it against the
+// pinned version of the file. Validation passes if the retrieved file is either:
+//     a) the same as the pinned file
+//  or b) a valid successor, reached by following the history of the retrieved file back to the pinned file,
+//        where each version is signed by the key the consortium config lists for the stakeholder
+// where the key is the one listed by the consortium set on the context, or without one, by the only consortium
+// listing the stakeholder.
+// The first file retrieved for a stakeholder of a consortium is pinned after verifying its signature.
+// If validation fails part-way and the service allows it, the last valid version is used instead.
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	consortium, member, err := cs.member(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	stakeholderData, err := cs.config.GetStakeholderWithContext(ctx, url, domain)
+	if err != nil {
+		return nil, fmt.Errorf("wrapped config service: %w", err)
+	}
+
+	if stakeholderData == nil || stakeholderData.Config == nil || stakeholderData.JWS == nil {
+		return nil, fmt.Errorf("stakeholder is nil")
+	}
+
+	key := pinKey{consortium: consortium, url: url, domain: domain}
+
+	cs.lock.RLock()
+	pinned, ok := cs.stakeholders[key]
+	cs.lock.RUnlock()
+
+	if !ok {
+		err = verifyStakeholderSignature(stakeholderData, member.key)
+		if err != nil {
+			return nil, &models.InvalidStakeholderSignature{
+				Domain: domain,
+				Err:    fmt.Errorf("stakeholder config signature does not verify: %w", err),
+			}
+		}
+
+		cs.pin(key, nil, stakeholderData)
+
+		return stakeholderData, nil
+	}
+
+	// if they're the same, return the fetched copy, which carries how long it may be cached
+	if pinned.JWS.FullSerialize() == stakeholderData.JWS.FullSerialize() {
+		return stakeholderData, nil
+	}
+
+	history, err := cs.getHistory(ctx, url, member.historyHash, pinned, stakeholderData)
+	if err != nil {
+		return nil, fmt.Errorf("stakeholder config update history: %w", err)
+	}
+
+	// validate each version against the stakeholder's key, starting from the version after the pinned version
+	check := pinned
+
+	for i := len(history) - 1; i >= 0; i-- {
+		next := history[i]
+
+		err = verifyStakeholderSignature(next, member.key)
+		if err != nil {
+			if !cs.allowLastValid {
+				return nil, &models.InvalidStakeholderSignature{
+					Domain: domain,
+					Err:    fmt.Errorf("stakeholder config update signature does not verify: %w", err),
+				}
+			}
+
+			log.Warnf("stakeholder config update for domain %s stopped at last valid config: %s", domain, err.Error())
+
+			break
+		}
+
+		check = next
+	}
+
+	cs.pin(key, pinned, check)
+
+	return check, nil
+}
+
+// member returns what the consortium set on the context says about the stakeholder with the given domain, or without
+// a consortium on the context, what the only consortium listing the stakeholder says, with that consortium
+func (cs *ConfigService) member(ctx context.Context, domain string) (models.ConsortiumRef, *memberData, error) {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+
+	if consortium, ok := models.ConsortiumFromContext(ctx); ok {
+		member, ok := cs.members[memberKey{consortium: consortium, domain: domain}]
+		if !ok {
+			return consortium, nil, fmt.Errorf("consortium config %s does not list stakeholder %s",
+				consortium.Domain, domain)
+		}
+
+		return consortium, member, nil
+	}
+
+	var (
+		found  models.ConsortiumRef
+		member *memberData
+	)
+
+	for key, m := range cs.members {
+		if key.domain != domain {
+			continue
+		}
+
+		if member != nil {
+			return found, nil, fmt.Errorf("stakeholder %s is listed by more than one consortium config", domain)
+		}
+
+		found, member = key.consortium, m
+	}
+
+	if member == nil {
+		return found, nil, fmt.Errorf("no consortium config lists stakeholder %s", domain)
+	}
+
+	return found, member, nil
+}
+
+// pin pins the stakeholder config under the key, unless the config pinned under the key changed concurrently since
+// the previous config was read
+func (cs *ConfigService) pin(key pinKey, previous, sfd *models.StakeholderFileData) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if cs.stakeholders[key] == previous {
+		cs.stakeholders[key] = sfd
+	}
+}
+
+// getHistory returns the list of stakeholder configs leading back from the given latest config to the pinned config,
+// with the latest config first. The pinned config is not included.
+// If the history ends without reaching the pinned config, the oldest config found is treated as the direct
+// successor of the pinned config.
+func (cs *ConfigService) getHistory(ctx context.Context, url, hashAlgorithm string,
+	pinned, latest *models.StakeholderFileData) ([]*models.StakeholderFileData, error) {
+	history := []*models.StakeholderFileData{latest}
+
+	current := latest
+
+	for current.Config.Previous != "" {
+		if len(history) > maxHistoryDepth {
+			return nil, fmt.Errorf("history exceeds maximum depth of %d", maxHistoryDepth)
+		}
+
+		previous, err := cs.config.GetStakeholderHistoryWithContext(ctx, url, current.Config.Previous, hashAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("fetching previous config %s: %w", current.Config.Previous, err)
+		}
+
+		if previous == nil || previous.Config == nil || previous.JWS == nil {
+			return nil, fmt.Errorf("previous config %s is nil", current.Config.Previous)
+		}
+
+		if previous.JWS.FullSerialize() == pinned.JWS.FullSerialize() {
+			break
+		}
+
+		history = append(history, previous)
+		current = previous
+	}
+
+	return history, nil
+}
+
+// verifyStakeholderSignature verifies that a stakeholder file is signed by the given key
+func verifyStakeholderSignature(signedData *models.StakeholderFileData, publicKey models.PublicKey) error {
+	key := jose.JSONWebKey{}
+
+	err := key.UnmarshalJSON(publicKey.JWK)
+	if err != nil {
+		return fmt.Errorf("bad stakeholder key %s: %w", publicKey.ID, err)
+	}
+
+	_, _, _, err = signedData.JWS.VerifyMulti(key)
+	if err != nil {
+		return fmt.Errorf("stakeholder key %s fails to verify: %w", publicKey.ID, err)
+	}
+
+	return nil
+}
+
+// GetStakeholderHistory calls GetStakeholderHistoryWithContext with a background context
+func (cs *ConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetStakeholderHistoryWithContext returns the historical stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
+func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext get sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
+	return cs.config.GetSidetreeConfigWithContext(ctx, url)
+}
+
+// Option is a config service instance option
+type Option func(opts *ConfigService)
+
+// WithLastValidFallback enables using the last valid config in the history of an updated stakeholder config,
+// when a later config in the history fails to validate. When disabled, such an update fails instead.
+func WithLastValidFallback(enable bool) Option {
+	return func(opts *ConfigService) {
+		opts.allowLastValid = enable
+	}
+}
@@ -0,0 +1,513 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stakeholdervalidationconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+const (
+	rawPrivKey1 = `{
+  "kty": "OKP",
+  "kid": "key1",
+  "d": "CSLczqR1ly2lpyBcWne9gFKnsjaKJw0dKfoSQu7lNvg",
+  "crv": "Ed25519",
+  "x": "bWRCy8DtNhRO3HdKTFB2eEG5Ac1J00D0DQPffOwtAD0"
+}`
+	rawPrivKey2 = `{
+  "kty": "OKP",
+  "kid": "key2",
+  "d": "-YawjZSeB9Rkdol9SHeOcT9hIvo_VuH6zM-pgtk3b10",
+  "crv": "Ed25519",
+  "x": "8rfXFZNHZs9GYzGbQLYDasGUAm1brAgTLI0jrD4KheU"
+}`
+)
+
+func signingKey(t *testing.T, rawPrivKey string) (jose.SigningKey, json.RawMessage) {
+	key := jose.JSONWebKey{}
+	require.NoError(t, key.UnmarshalJSON([]byte(rawPrivKey)))
+
+	pubKey, err := key.Public().MarshalJSON()
+	require.NoError(t, err)
+
+	return jose.SigningKey{Key: key.Key, Algorithm: jose.EdDSA}, pubKey
+}
+
+func stakeholderFileData(t *testing.T, config *models.Stakeholder, key jose.SigningKey) *models.StakeholderFileData {
+	signer, err := jose.NewSigner(key, nil)
+	require.NoError(t, err)
+
+	configBytes, err := json.Marshal(config)
+	require.NoError(t, err)
+
+	sig, err := signer.Sign(configBytes)
+	require.NoError(t, err)
+
+	return &models.StakeholderFileData{Config: config, JWS: sig}
+}
+
+func consortiumFileData(stakeholderKey json.RawMessage) *models.ConsortiumFileData {
+	return &models.ConsortiumFileData{Config: &models.Consortium{
+		Domain: "consortium",
+		Policy: models.ConsortiumPolicy{HistoryHash: "SHA384"},
+		Members: []*models.StakeholderListElement{
+			nil,
+			{Domain: "stakeholder", PublicKey: models.PublicKey{ID: "did:example:stakeholder#key", JWK: stakeholderKey}},
+		},
+	}}
+}
+
+func TestConfigService_GetConsortium(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, pubKey := signingKey(t, rawPrivKey1)
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return consortiumFileData(pubKey), nil
+			}})
+
+		c, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+		require.Equal(t, "consortium", c.Config.Domain)
+
+		member, ok := cs.members[memberKey{
+			consortium: models.ConsortiumRef{URL: "consortium", Domain: "consortium"},
+			domain:     "stakeholder",
+		}]
+		require.True(t, ok)
+		require.Equal(t, "did:example:stakeholder#key", member.key.ID)
+		require.Equal(t, "SHA384", member.historyHash)
+	})
+
+	t.Run("success - members of an updated consortium config replace the previous ones", func(t *testing.T) {
+		_, pubKey := signingKey(t, rawPrivKey1)
+
+		consortiumData := consortiumFileData(pubKey)
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return consortiumData, nil
+			}})
+
+		_, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+		require.Len(t, cs.members, 1)
+
+		consortiumData = &models.ConsortiumFileData{Config: &models.Consortium{Domain: "consortium"}}
+
+		_, err = cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+		require.Empty(t, cs.members)
+	})
+
+	t.Run("success - nil consortium config", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{}, nil
+			}})
+
+		c, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+		require.Nil(t, c.Config)
+		require.Empty(t, cs.members)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetConsortium("consortium", "consortium")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
+func TestConfigService_GetStakeholder(t *testing.T) {
+	sigKey, pubKey := signingKey(t, rawPrivKey1)
+	otherSigKey, _ := signingKey(t, rawPrivKey2)
+
+	// v1 is pinned, v2 and v3 are updates signed by the stakeholder's consortium key
+	v1 := stakeholderFileData(t, &models.Stakeholder{
+		Domain:    "stakeholder",
+		Endpoints: []string{"https://v1.example.com"},
+	}, sigKey)
+
+	v2 := stakeholderFileData(t, &models.Stakeholder{
+		Domain:    "stakeholder",
+		Endpoints: []string{"https://v2.example.com"},
+		Previous:  "v1",
+	}, sigKey)
+
+	v3Config := &models.Stakeholder{
+		Domain:    "stakeholder",
+		Endpoints: []string{"https://v3.example.com"},
+		Previous:  "v2",
+	}
+
+	v3 := stakeholderFileData(t, v3Config, sigKey)
+
+	// v3 signed by a key the consortium doesn't list for the stakeholder
+	v3Bad := stakeholderFileData(t, v3Config, otherSigKey)
+
+	history := map[string]*models.StakeholderFileData{
+		"v1": v1,
+		"v2": v2,
+	}
+
+	// newService returns a service that has pinned v1, and then returns the given files
+	newService := func(files []*models.StakeholderFileData, opts ...Option) *ConfigService {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return consortiumFileData(pubKey), nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				file := files[0]
+				files = files[1:]
+
+				return file, nil
+			},
+			GetStakeholderHistoryFunc: func(u, hash, alg string) (*models.StakeholderFileData, error) {
+				// the history hash algorithm is declared by the consortium listing the stakeholder
+				require.Equal(t, "SHA384", alg)
+
+				h, ok := history[hash]
+				if !ok {
+					return nil, fmt.Errorf("history file not found")
+				}
+
+				return h, nil
+			},
+		}, opts...)
+
+		_, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+
+		return cs
+	}
+
+	t.Run("success - first file is pinned", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v1, v1})
+
+		res, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+		require.Equal(t, v1, res)
+
+		res, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+		require.Equal(t, v1, res)
+	})
+
+	t.Run("success - follows history to pinned file", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v1, v3, v3})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+
+		res, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+		require.Equal(t, v3, res)
+
+		// v3 is now pinned
+		res, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+		require.Equal(t, v3, res)
+	})
+
+	t.Run("success - concurrent fetches", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return consortiumFileData(pubKey), nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return v3, nil
+			},
+			GetStakeholderHistoryFunc: func(u, hash, alg string) (*models.StakeholderFileData, error) {
+				h, ok := history[hash]
+				if !ok {
+					return nil, fmt.Errorf("history file not found")
+				}
+
+				return h, nil
+			},
+		})
+
+		_, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := cs.GetConsortium("consortium", "consortium")
+				require.NoError(t, err)
+			}()
+
+			go func() {
+				defer wg.Done()
+
+				res, err := cs.GetStakeholder("stakeholder", "stakeholder")
+				require.NoError(t, err)
+				require.Equal(t, v3, res)
+			}()
+		}
+
+		wg.Wait()
+	})
+
+	t.Run("success - falls back to last valid config", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v1, v3Bad}, WithLastValidFallback(true))
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+
+		res, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+		require.Equal(t, v2, res)
+	})
+
+	t.Run("failure - update not signed by consortium key", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v1, v3Bad})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder config update signature does not verify")
+	})
+
+	t.Run("failure - first file not signed by consortium key", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v3Bad})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder config signature does not verify")
+
+		var invalidSignature *models.InvalidStakeholderSignature
+		require.True(t, errors.As(err, &invalidSignature))
+		require.Equal(t, "stakeholder", invalidSignature.Domain)
+	})
+
+	t.Run("failure - can't fetch history", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v1, stakeholderFileData(t, &models.Stakeholder{
+			Domain:   "stakeholder",
+			Previous: "missing",
+		}, sigKey)})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "history file not found")
+	})
+
+	t.Run("failure - history is nil", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{v1, v3})
+		cs.config.(*mockconfig.MockConfigService).GetStakeholderHistoryFunc = nil
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "previous config v2 is nil")
+	})
+
+	t.Run("failure - history too deep", func(t *testing.T) {
+		loop := stakeholderFileData(t, &models.Stakeholder{Domain: "stakeholder", Previous: "loop"}, sigKey)
+		history["loop"] = loop
+
+		defer delete(history, "loop")
+
+		cs := newService([]*models.StakeholderFileData{v1, loop})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "history exceeds maximum depth")
+	})
+
+	t.Run("success - validated against the consortium on the context", func(t *testing.T) {
+		_, otherPubKey := signingKey(t, rawPrivKey2)
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				if d == "other" {
+					return consortiumFileData(otherPubKey), nil
+				}
+
+				return consortiumFileData(pubKey), nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return v1, nil
+			},
+		})
+
+		_, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+
+		// another consortium listing the stakeholder with a different key doesn't affect validation for the first
+		_, err = cs.GetConsortium("other", "other")
+		require.NoError(t, err)
+
+		res, err := cs.GetStakeholderWithContext(models.WithConsortium(context.Background(), "consortium", "consortium"),
+			"stakeholder", "stakeholder")
+		require.NoError(t, err)
+		require.Equal(t, v1, res)
+
+		_, err = cs.GetStakeholderWithContext(models.WithConsortium(context.Background(), "other", "other"),
+			"stakeholder", "stakeholder")
+
+		var invalidSignature *models.InvalidStakeholderSignature
+		require.True(t, errors.As(err, &invalidSignature))
+
+		_, err = cs.GetStakeholderWithContext(models.WithConsortium(context.Background(), "unknown", "unknown"),
+			"stakeholder", "stakeholder")
+		require.EqualError(t, err, "consortium config unknown does not list stakeholder stakeholder")
+
+		_, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.EqualError(t, err, "stakeholder stakeholder is listed by more than one consortium config")
+	})
+
+	t.Run("failure - stakeholder not listed by a consortium", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no consortium config lists stakeholder")
+	})
+
+	t.Run("failure - bad consortium key", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return consortiumFileData(json.RawMessage(`"not a key"`)), nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return v1, nil
+			},
+		})
+
+		_, err := cs.GetConsortium("consortium", "consortium")
+		require.NoError(t, err)
+
+		_, err = cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "bad stakeholder key")
+	})
+
+	t.Run("failure - wrapped service error", func(t *testing.T) {
+		cs := newService(nil)
+		cs.config.(*mockconfig.MockConfigService).GetStakeholderFunc = func(u string, d string) (
+			*models.StakeholderFileData, error) {
+			return nil, fmt.Errorf("error error")
+		}
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+
+	t.Run("failure - nil stakeholder", func(t *testing.T) {
+		cs := newService([]*models.StakeholderFileData{{}})
+
+		_, err := cs.GetStakeholder("stakeholder", "stakeholder")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder is nil")
+	})
+}
+
+func TestConfigService_GetConsortiumHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{Config: &models.Consortium{Domain: "foo.bar"}}, nil
+			}})
+
+		c, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", c.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
+func TestConfigService_GetStakeholderHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return &models.StakeholderFileData{Config: &models.Stakeholder{Domain: "foo.bar"}}, nil
+			}})
+
+		sh, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", sh.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
+func TestConfigService_GetSidetreeConfig(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(u string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}})
+
+		c, err := cs.GetSidetreeConfig("foo")
+		require.NoError(t, err)
+		require.Equal(t, uint(18), c.MultiHashAlgorithm)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(u string) (*models.SidetreeConfig, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		sc, err := cs.GetSidetreeConfig("foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+		require.Nil(t, sc)
+	})
+}
@@ -6,43 +6,77 @@ SPDX-License-Identifier: Apache-2.0
 package updatevalidationconfig
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/signatureconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
+// maxHistoryDepth bounds the number of history files followed when updating a cached consortium config
+const maxHistoryDepth = 100
+
 type config interface {
-	GetConsortium(string, string) (*models.ConsortiumFileData, error)
-	GetStakeholder(string, string) (*models.StakeholderFileData, error)
-	GetSidetreeConfig(url string) (*models.SidetreeConfig, error)
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetConsortiumHistoryWithContext(context.Context, string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetStakeholderHistoryWithContext(context.Context, string, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
 }
 
 // ConfigService fetches consortium and stakeholder configs
 // Caches the current consortium config, and when updating, uses signature validation to verify that the updated
 // consortium config is a valid update to the current one.
+// Genesis files must be added before the service fetches any consortium config. The service is safe for concurrent use.
 type ConfigService struct {
-	config    config
+	config         config
+	allowLastValid bool
+
+	// lock guards consortia, genesis and serving
+	lock      sync.Mutex
 	consortia map[stringPair]*models.ConsortiumFileData
+	genesis   map[stringPair]*models.ConsortiumFileData
+	serving   bool
 }
 
 // NewService create new ConfigService
-func NewService(config config) *ConfigService {
+func NewService(config config, opts ...Option) *ConfigService {
 	configService := &ConfigService{config: config}
 
+	for _, opt := range opts {
+		opt(configService)
+	}
+
 	configService.consortia = map[stringPair]*models.ConsortiumFileData{}
+	configService.genesis = map[stringPair]*models.ConsortiumFileData{}
 
 	return configService
 }
 
-// GetConsortium fetches and parses the consortium file at the given domain, validating it against a cached version
-// of the file. Validation passes if the retrieved file is either:
-//     a) the same as the cached file
-//  or b) a valid successor, endorsed by the cached file
+// GetConsortium calls GetConsortiumWithContext with a background context
 func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext fetches and parses the consortium file at the given domain, validating it against a cached
+// version of the file. Validation passes if the retrieved file is either:
+//     a) the same as the cached file
+//  or b) a valid successor, reached by following the history of the retrieved file back to the cached file,
+//        where each version is endorsed by the version before it
+// If the latter fails part-way and the service allows it, the last valid version is used instead.
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
 	key := stringPair{domain: domain, url: url}
 
+	cs.lock.Lock()
+	cs.serving = true
 	cachedConsortium, ok := cs.consortia[key]
+	cs.lock.Unlock()
+
 	if !ok || cachedConsortium == nil {
 		return nil, fmt.Errorf("cached config missing from cache")
 	}
@@ -54,25 +88,86 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 
 	// if we're here, the cached consortium has expired and we must refresh or update, and validate
 
-	consortiumData, err := cs.config.GetConsortium(url, domain)
+	consortiumData, err := cs.config.GetConsortiumWithContext(ctx, url, domain)
 	if err != nil {
 		return nil, fmt.Errorf("wrapped config service: %w", err)
 	}
 
-	// if they're the same, return
+	// if they're the same, return the fetched copy, which carries how long it may be cached
 	if cachedConsortium.JWS.FullSerialize() == consortiumData.JWS.FullSerialize() {
-		return cachedConsortium, nil
+		return consortiumData, nil
 	}
 
-	// validate new fetched data against old's signatures
-	err = signatureconfig.VerifyConsortiumSignatures(consortiumData, consortium)
+	history, err := cs.getHistory(ctx, url, cachedConsortium, consortiumData)
 	if err != nil {
-		return nil, fmt.Errorf("config update signature does not verify: %w", err)
+		return nil, fmt.Errorf("config update history: %w", err)
+	}
+
+	// validate each version against the signatures of the version before it, starting from the cached version
+	check := cachedConsortium
+
+	for i := len(history) - 1; i >= 0; i-- {
+		next := history[i]
+
+		err = signatureconfig.VerifyConsortiumSignatures(next, check.Config)
+		if err != nil {
+			if !cs.allowLastValid {
+				return nil, fmt.Errorf("config update signature does not verify: %w", err)
+			}
+
+			log.Warnf("consortium config update for domain %s stopped at last valid config: %s", domain, err.Error())
+
+			break
+		}
+
+		check = next
+	}
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	// keep a concurrent update made since the cached version was read, as it was validated from the same version
+	if cs.consortia[key] == cachedConsortium {
+		cs.consortia[key] = check
 	}
 
-	cs.consortia[key] = consortiumData
+	return check, nil
+}
+
+// getHistory returns the list of consortium configs leading back from the given latest config to the cached config,
+// with the latest config first. The cached config is not included.
+// If the history ends without reaching the cached config, the oldest config found is treated as the direct
+// successor of the cached config.
+func (cs *ConfigService) getHistory(ctx context.Context, url string, cached, latest *models.ConsortiumFileData,
+) ([]*models.ConsortiumFileData, error) {
+	history := []*models.ConsortiumFileData{latest}
+
+	current := latest
+
+	for current.Config.Previous != "" {
+		if len(history) > maxHistoryDepth {
+			return nil, fmt.Errorf("history exceeds maximum depth of %d", maxHistoryDepth)
+		}
+
+		previous, err := cs.config.GetConsortiumHistoryWithContext(ctx, url, current.Config.Previous,
+			current.Config.Policy.HistoryHash)
+		if err != nil {
+			return nil, fmt.Errorf("fetching previous config %s: %w", current.Config.Previous, err)
+		}
+
+		if previous == nil || previous.Config == nil || previous.JWS == nil {
+			return nil, fmt.Errorf("previous config %s is nil", current.Config.Previous)
+		}
+
+		if previous.JWS.FullSerialize() == cached.JWS.FullSerialize() {
+			break
+		}
 
-	return consortiumData, nil
+		history = append(history, previous)
+		current = previous
+	}
+
+	return history, nil
 }
 
 type stringPair struct {
@@ -80,26 +175,152 @@ type stringPair struct {
 }
 
 // AddGenesisFile adds a genesis file to the config.
+// Fails if a genesis file was already added for the url and domain, or if the service has started fetching consortium
+// configs.
 func (cs *ConfigService) AddGenesisFile(url, domain string, genesisFile []byte) error {
 	genesisConsortium, err := models.ParseConsortium(genesisFile)
 	if err != nil {
 		return fmt.Errorf("failed to add genesis file for url: %s, error: %w", url, err)
 	}
 
-	// TODO: error if repeat (url, domain)?
-	// TODO: error if a genesis file is added during operation, after startup is already finished?
+	key := stringPair{domain: domain, url: url}
 
-	cs.consortia[stringPair{domain: domain, url: url}] = genesisConsortium
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if cs.serving {
+		return fmt.Errorf("failed to add genesis file for url: %s, error: consortium configs are already being fetched",
+			url)
+	}
+
+	if _, ok := cs.consortia[key]; ok {
+		return fmt.Errorf("failed to add genesis file for url: %s, error: genesis file already added for domain %s",
+			url, domain)
+	}
+
+	cs.consortia[key] = genesisConsortium
+	cs.genesis[key] = genesisConsortium
 
 	return nil
 }
 
-// GetStakeholder returns the stakeholder config file fetched by the wrapped config service
+// Entry describes a consortium config cached as the base for validating updates
+type Entry struct {
+	// URL is the url the consortium config is fetched from
+	URL string
+	// Domain is the consortium domain
+	Domain string
+	// Updated is whether the cached config was updated from the genesis file
+	Updated bool
+	// Config is the cached consortium config
+	Config *models.Consortium
+}
+
+// Entries returns the cached consortium configs, ordered by url and domain
+func (cs *ConfigService) Entries() []*Entry {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	entries := make([]*Entry, 0, len(cs.consortia))
+
+	for key, consortium := range cs.consortia {
+		entries = append(entries, &Entry{
+			URL:     key.url,
+			Domain:  key.domain,
+			Updated: consortium != cs.genesis[key],
+			Config:  consortium.Config,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+
+		return entries[i].Domain < entries[j].Domain
+	})
+
+	return entries
+}
+
+// Evict resets the cached consortium configs whose url or domain is the given domain to their genesis files, so the
+// next update is validated from the genesis file. Returns the number of configs reset.
+func (cs *ConfigService) Evict(domain string) int {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	evicted := 0
+
+	for key, genesis := range cs.genesis {
+		if (key.url == domain || key.domain == domain) && cs.consortia[key] != genesis {
+			cs.consortia[key] = genesis
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// Flush resets all cached consortium configs to their genesis files
+func (cs *ConfigService) Flush() {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	for key, genesis := range cs.genesis {
+		cs.consortia[key] = genesis
+	}
+}
+
+// GetConsortiumHistory calls GetConsortiumHistoryWithContext with a background context
+func (cs *ConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetConsortiumHistoryWithContext returns the historical consortium config file fetched by the wrapped config service
+func (cs *ConfigService) GetConsortiumHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	return cs.config.GetConsortiumHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetStakeholder calls GetStakeholderWithContext with a background context
 func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
-	return cs.config.GetStakeholder(url, domain)
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext returns the stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderWithContext(ctx, url, domain)
+}
+
+// GetStakeholderHistory calls GetStakeholderHistoryWithContext with a background context
+func (cs *ConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
 }
 
-// GetSidetreeConfig get sidetree config
+// GetStakeholderHistoryWithContext returns the historical stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
 func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
-	return cs.config.GetSidetreeConfig(url)
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext get sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
+	return cs.config.GetSidetreeConfigWithContext(ctx, url)
+}
+
+// Option is a config service instance option
+type Option func(opts *ConfigService)
+
+// WithLastValidFallback enables using the last valid config in the history of an updated consortium config,
+// when a later config in the history fails to validate. When disabled, such an update fails instead.
+func WithLastValidFallback(enable bool) Option {
+	return func(opts *ConfigService) {
+		opts.allowLastValid = enable
+	}
 }
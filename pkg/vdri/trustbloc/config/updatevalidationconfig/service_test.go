@@ -8,6 +8,7 @@ package updatevalidationconfig
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/square/go-jose/v3"
@@ -63,6 +64,44 @@ func TestConfigService_AddGenesisFile(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "genesis file for url")
 	})
+
+	t.Run("failure - genesis file already added", func(t *testing.T) {
+		cs := NewService(nil)
+
+		genesis, err := signConsortium(&models.Consortium{Domain: "foo"}, sigKey)
+		require.NoError(t, err)
+
+		err = cs.AddGenesisFile("foo", "foo", []byte(genesis.FullSerialize()))
+		require.NoError(t, err)
+
+		err = cs.AddGenesisFile("bar", "foo", []byte(genesis.FullSerialize()))
+		require.NoError(t, err)
+
+		err = cs.AddGenesisFile("foo", "foo", []byte(genesis.FullSerialize()))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "genesis file already added for domain foo")
+	})
+
+	t.Run("failure - consortium configs already fetched", func(t *testing.T) {
+		genesis, err := signConsortium(&models.Consortium{Domain: "foo"}, sigKey)
+		require.NoError(t, err)
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(url string, domain string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("config error")
+			},
+		})
+
+		err = cs.AddGenesisFile("foo", "foo", []byte(genesis.FullSerialize()))
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo", "foo")
+		require.Error(t, err)
+
+		err = cs.AddGenesisFile("bar", "bar", []byte(genesis.FullSerialize()))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "consortium configs are already being fetched")
+	})
 }
 
 func TestConfigService_GetConsortium(t *testing.T) {
@@ -297,6 +336,30 @@ func TestConfigService_GetConsortium(t *testing.T) {
 	})
 }
 
+func TestConfigService_GetConsortiumHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{Config: &models.Consortium{Domain: "foo.bar"}}, nil
+			}})
+
+		c, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", c.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
 func TestConfigService_GetStakeholder(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
@@ -321,6 +384,30 @@ func TestConfigService_GetStakeholder(t *testing.T) {
 	})
 }
 
+func TestConfigService_GetStakeholderHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return &models.StakeholderFileData{Config: &models.Stakeholder{Domain: "foo.bar"}}, nil
+			}})
+
+		sh, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", sh.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
 func TestConfigService_GetSidetreeConfig(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
@@ -345,3 +432,278 @@ func TestConfigService_GetSidetreeConfig(t *testing.T) {
 		require.Nil(t, sc)
 	})
 }
+
+func TestConfigService_GetConsortium_History(t *testing.T) {
+	rawPrivKeys := []string{`{
+  "kty": "OKP",
+  "kid": "key1",
+  "d": "CSLczqR1ly2lpyBcWne9gFKnsjaKJw0dKfoSQu7lNvg",
+  "crv": "Ed25519",
+  "x": "bWRCy8DtNhRO3HdKTFB2eEG5Ac1J00D0DQPffOwtAD0"
+}`, `{
+  "kty": "OKP",
+  "kid": "key2",
+  "d": "-YawjZSeB9Rkdol9SHeOcT9hIvo_VuH6zM-pgtk3b10",
+  "crv": "Ed25519",
+  "x": "8rfXFZNHZs9GYzGbQLYDasGUAm1brAgTLI0jrD4KheU"
+}`}
+
+	var sigKeys []jose.SigningKey
+
+	var pubKeys []json.RawMessage
+
+	for _, rawPrivKey := range rawPrivKeys {
+		key := jose.JSONWebKey{}
+		err := key.UnmarshalJSON([]byte(rawPrivKey))
+		require.NoError(t, err)
+
+		pubKey, err := key.Public().MarshalJSON()
+		require.NoError(t, err)
+
+		sigKeys = append(sigKeys, jose.SigningKey{Key: key.Key, Algorithm: jose.EdDSA})
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	fileData := func(config *models.Consortium, key jose.SigningKey) *models.ConsortiumFileData {
+		sig, err := signConsortium(config, key)
+		require.NoError(t, err)
+
+		return &models.ConsortiumFileData{Config: config, JWS: sig}
+	}
+
+	// genesis lists key 1, v1 rotates to key 2 (endorsed by key 1), v2 is endorsed by key 2
+	genesis := fileData(&models.Consortium{
+		Domain:  "foo",
+		Members: []*models.StakeholderListElement{{PublicKey: models.PublicKey{JWK: pubKeys[0]}}},
+	}, sigKeys[0])
+
+	v1 := fileData(&models.Consortium{
+		Domain:   "foo",
+		Members:  []*models.StakeholderListElement{{PublicKey: models.PublicKey{JWK: pubKeys[1]}}},
+		Previous: "genesis",
+	}, sigKeys[0])
+
+	v2Config := &models.Consortium{
+		Domain:   "foo",
+		Policy:   models.ConsortiumPolicy{HistoryHash: "SHA384"},
+		Members:  []*models.StakeholderListElement{{PublicKey: models.PublicKey{JWK: pubKeys[1]}}},
+		Previous: "v1",
+	}
+
+	v2 := fileData(v2Config, sigKeys[1])
+
+	// v2 signed by a key that v1 doesn't list
+	v2Bad := fileData(v2Config, sigKeys[0])
+
+	history := map[string]*models.ConsortiumFileData{
+		"genesis": genesis,
+		"v1":      v1,
+	}
+
+	newService := func(latest *models.ConsortiumFileData, opts ...Option) *ConfigService {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return latest, nil
+			},
+			GetConsortiumHistoryFunc: func(u, hash, alg string) (*models.ConsortiumFileData, error) {
+				// the history hash algorithm is declared by the config referencing the history file
+				if hash == "v1" {
+					require.Equal(t, "SHA384", alg)
+				}
+
+				h, ok := history[hash]
+				if !ok {
+					return nil, fmt.Errorf("history file not found")
+				}
+
+				return h, nil
+			},
+		}, opts...)
+
+		err := cs.AddGenesisFile("foo", "foo", []byte(genesis.JWS.FullSerialize()))
+		require.NoError(t, err)
+
+		return cs
+	}
+
+	t.Run("success - follows history through a key rotation", func(t *testing.T) {
+		cs := newService(v2)
+
+		res, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+		require.Equal(t, "v1", res.Config.Previous)
+	})
+
+	t.Run("success - later fetch validates against updated cache", func(t *testing.T) {
+		cs := newService(v2)
+
+		_, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+
+		res, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+		require.Equal(t, "v1", res.Config.Previous)
+	})
+
+	t.Run("success - concurrent fetches", func(t *testing.T) {
+		cs := newService(v2)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				res, err := cs.GetConsortium("foo", "foo")
+				require.NoError(t, err)
+				require.Equal(t, "v1", res.Config.Previous)
+			}()
+		}
+
+		wg.Wait()
+
+		res, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+		require.Equal(t, "v1", res.Config.Previous)
+	})
+
+	t.Run("success - falls back to last valid config", func(t *testing.T) {
+		cs := newService(v2Bad, WithLastValidFallback(true))
+
+		res, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+		require.Equal(t, "genesis", res.Config.Previous)
+	})
+
+	t.Run("failure - invalid config in history", func(t *testing.T) {
+		cs := newService(v2Bad)
+
+		_, err := cs.GetConsortium("foo", "foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "signature does not verify")
+	})
+
+	t.Run("failure - can't fetch history", func(t *testing.T) {
+		cs := newService(fileData(&models.Consortium{
+			Domain:   "foo",
+			Members:  []*models.StakeholderListElement{{PublicKey: models.PublicKey{JWK: pubKeys[1]}}},
+			Previous: "missing",
+		}, sigKeys[1]))
+
+		_, err := cs.GetConsortium("foo", "foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "history file not found")
+	})
+
+	t.Run("failure - history is nil", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return v2, nil
+			},
+		})
+
+		err := cs.AddGenesisFile("foo", "foo", []byte(genesis.JWS.FullSerialize()))
+		require.NoError(t, err)
+
+		_, err = cs.GetConsortium("foo", "foo")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "previous config v1 is nil")
+	})
+}
+
+func TestConfigService_Entries(t *testing.T) {
+	rawPrivKey := []byte(`{
+  "kty": "OKP",
+  "kid": "key1",
+  "d": "CSLczqR1ly2lpyBcWne9gFKnsjaKJw0dKfoSQu7lNvg",
+  "crv": "Ed25519",
+  "x": "bWRCy8DtNhRO3HdKTFB2eEG5Ac1J00D0DQPffOwtAD0"
+}`)
+
+	rawPubKey := []byte(`{
+  "kty": "OKP",
+  "kid": "key1",
+  "crv": "Ed25519",
+  "x": "bWRCy8DtNhRO3HdKTFB2eEG5Ac1J00D0DQPffOwtAD0"
+}`)
+
+	key := jose.JSONWebKey{}
+	require.NoError(t, key.UnmarshalJSON(rawPrivKey))
+
+	sigKey := jose.SigningKey{Key: key.Key, Algorithm: jose.EdDSA}
+
+	members := []*models.StakeholderListElement{{PublicKey: models.PublicKey{JWK: json.RawMessage(rawPubKey)}}}
+
+	genesis, err := signConsortium(&models.Consortium{Domain: "foo", Members: members}, sigKey)
+	require.NoError(t, err)
+
+	updatedConfig := &models.Consortium{Domain: "foo", Members: members, Policy: models.ConsortiumPolicy{NumQueries: 2}}
+
+	updated, err := signConsortium(updatedConfig, sigKey)
+	require.NoError(t, err)
+
+	newService := func(t *testing.T) *ConfigService {
+		t.Helper()
+
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{Config: updatedConfig, JWS: updated}, nil
+			},
+		})
+
+		require.NoError(t, cs.AddGenesisFile("foo", "foo", []byte(genesis.FullSerialize())))
+		require.NoError(t, cs.AddGenesisFile("bar", "bar", []byte(genesis.FullSerialize())))
+
+		_, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+
+		return cs
+	}
+
+	t.Run("success - list entries", func(t *testing.T) {
+		entries := newService(t).Entries()
+		require.Len(t, entries, 2)
+
+		require.Equal(t, "bar", entries[0].URL)
+		require.False(t, entries[0].Updated)
+		require.Equal(t, 0, entries[0].Config.Policy.NumQueries)
+
+		require.Equal(t, "foo", entries[1].URL)
+		require.Equal(t, "foo", entries[1].Domain)
+		require.True(t, entries[1].Updated)
+		require.Equal(t, 2, entries[1].Config.Policy.NumQueries)
+	})
+
+	t.Run("success - evict resets to genesis file", func(t *testing.T) {
+		cs := newService(t)
+
+		require.Equal(t, 0, cs.Evict("bar"))
+		require.Equal(t, 1, cs.Evict("foo"))
+		require.Equal(t, 0, cs.Evict("foo"))
+
+		for _, entry := range cs.Entries() {
+			require.False(t, entry.Updated)
+		}
+
+		// the update is validated again from the genesis file
+		res, err := cs.GetConsortium("foo", "foo")
+		require.NoError(t, err)
+		require.Equal(t, 2, res.Config.Policy.NumQueries)
+		require.True(t, cs.Entries()[1].Updated)
+	})
+
+	t.Run("success - flush resets all to genesis files", func(t *testing.T) {
+		cs := newService(t)
+
+		cs.Flush()
+
+		entries := cs.Entries()
+		require.Len(t, entries, 2)
+
+		for _, entry := range entries {
+			require.False(t, entry.Updated)
+		}
+	})
+}
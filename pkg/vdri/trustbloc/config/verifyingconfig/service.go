@@ -7,37 +7,60 @@ package verifyingconfig
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/square/go-jose/v3"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 type config interface {
-	GetConsortium(string, string) (*models.ConsortiumFileData, error)
-	GetStakeholder(string, string) (*models.StakeholderFileData, error)
-	GetSidetreeConfig(url string) (*models.SidetreeConfig, error)
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetConsortiumHistoryWithContext(context.Context, string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetStakeholderHistoryWithContext(context.Context, string, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
 }
 
 // ConfigService fetches consortium and stakeholder configs over http
+// Consortium configs are checked against the copies served by their stakeholders. A config whose JWS was checked
+// before is not checked again, so stakeholders are only queried when a consortium config is modified.
 type ConfigService struct {
 	config config
+
+	// lock guards verified
+	lock     sync.Mutex
+	verified map[stringPair]*jose.JSONWebSignature
+}
+
+type stringPair struct {
+	url, domain string
 }
 
 // NewService create new ConfigService
 func NewService(config config) *ConfigService {
 	configService := &ConfigService{
-		config: config,
+		config:   config,
+		verified: map[stringPair]*jose.JSONWebSignature{},
 	}
 
 	return configService
 }
 
-// GetConsortium fetches and parses the consortium file at the given domain
+// GetConsortium calls GetConsortiumWithContext with a background context
 func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFileData, error) {
-	consortiumData, err := cs.config.GetConsortium(url, domain)
+	return cs.GetConsortiumWithContext(context.Background(), url, domain)
+}
+
+// GetConsortiumWithContext fetches and parses the consortium file at the given domain
+func (cs *ConfigService) GetConsortiumWithContext(ctx context.Context, url, domain string,
+) (*models.ConsortiumFileData, error) {
+	consortiumData, err := cs.config.GetConsortiumWithContext(ctx, url, domain)
 	if err != nil {
 		return nil, fmt.Errorf("wrapped config service: %w", err)
 	}
@@ -47,6 +70,27 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 		return nil, fmt.Errorf("consortium is nil")
 	}
 
+	key := stringPair{url: url, domain: domain}
+
+	if cs.isVerified(key, consortiumData.JWS) {
+		return consortiumData, nil
+	}
+
+	err = cs.checkStakeholderCopies(ctx, domain, consortiumData)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.setVerified(key, consortiumData.JWS)
+
+	return consortiumData, nil
+}
+
+// checkStakeholderCopies checks that the consortium config matches the copies served by the consortium stakeholders
+func (cs *ConfigService) checkStakeholderCopies(ctx context.Context, domain string,
+	consortiumData *models.ConsortiumFileData) error {
+	consortium := consortiumData.Config
+
 	n := consortium.Policy.NumQueries
 
 	// if ds.numStakeholders is 0, then we use all stakeholders
@@ -59,22 +103,29 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 	// number of stakeholders that have verified
 	verifiedCount := 0
 
-	verificationErrors := ""
+	var verificationErrors []error
 
 	for i := 0; i < n; i++ {
 		stakeholder := consortium.Members[perm[i]].Domain
 		// get consortium file from stakeholder server
-		file, err := cs.config.GetConsortium(stakeholder, domain)
+		file, err := cs.config.GetConsortiumWithContext(ctx, stakeholder, domain)
 		if err != nil {
-			msg := "stakeholder peer failed to return consortium config: " + err.Error()
-			log.Warn(msg)
-			verificationErrors += msg + ", "
+			log.Warn("stakeholder peer failed to return consortium config: " + err.Error())
+
+			verificationErrors = append(verificationErrors, &models.StakeholderDown{
+				Domain: stakeholder,
+				Err:    fmt.Errorf("stakeholder peer failed to return consortium config: %w", err),
+			})
 
 			continue // skip failed stakeholders
 		}
 
 		if !bytes.Equal(file.JWS.UnsafePayloadWithoutVerification(), consortiumData.JWS.UnsafePayloadWithoutVerification()) {
-			verificationErrors += "stakeholder copy of consortium file does not match, "
+			verificationErrors = append(verificationErrors, &models.InconsistentConfiguration{
+				Domain: stakeholder,
+				Err:    errors.New("stakeholder copy of consortium file does not match"),
+			})
+
 			continue
 		}
 
@@ -82,20 +133,73 @@ func (cs *ConfigService) GetConsortium(url, domain string) (*models.ConsortiumFi
 	}
 
 	if verifiedCount < n {
-		return nil, fmt.Errorf(
-			"insufficient stakeholder endorsement of consortium config file. errors are: [%s]",
-			verificationErrors)
+		return &models.InsufficientEndorsement{
+			Domain:       domain,
+			Required:     n,
+			Endorsed:     verifiedCount,
+			Stakeholders: verificationErrors,
+		}
 	}
 
-	return consortiumData, nil
+	return nil
+}
+
+// isVerified returns whether the given JWS is the last JWS checked for the given url and domain.
+// The wrapped service returns the same JWS again for a config that wasn't modified.
+func (cs *ConfigService) isVerified(key stringPair, jws *jose.JSONWebSignature) bool {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	return jws != nil && cs.verified[key] == jws
+}
+
+// setVerified records the given JWS as the last JWS checked for the given url and domain
+func (cs *ConfigService) setVerified(key stringPair, jws *jose.JSONWebSignature) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	cs.verified[key] = jws
 }
 
-// GetStakeholder returns the stakeholder config file fetched by the wrapped config service
+// GetConsortiumHistory calls GetConsortiumHistoryWithContext with a background context
+func (cs *ConfigService) GetConsortiumHistory(url, hash, hashAlgorithm string) (*models.ConsortiumFileData, error) {
+	return cs.GetConsortiumHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
+}
+
+// GetConsortiumHistoryWithContext returns the historical consortium config file fetched by the wrapped config service
+func (cs *ConfigService) GetConsortiumHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.ConsortiumFileData, error) {
+	return cs.config.GetConsortiumHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetStakeholder calls GetStakeholderWithContext with a background context
 func (cs *ConfigService) GetStakeholder(url, domain string) (*models.StakeholderFileData, error) {
-	return cs.config.GetStakeholder(url, domain)
+	return cs.GetStakeholderWithContext(context.Background(), url, domain)
+}
+
+// GetStakeholderWithContext returns the stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderWithContext(ctx context.Context, url, domain string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderWithContext(ctx, url, domain)
+}
+
+// GetStakeholderHistory calls GetStakeholderHistoryWithContext with a background context
+func (cs *ConfigService) GetStakeholderHistory(url, hash, hashAlgorithm string) (*models.StakeholderFileData, error) {
+	return cs.GetStakeholderHistoryWithContext(context.Background(), url, hash, hashAlgorithm)
 }
 
-// GetSidetreeConfig returns the sidetree config
+// GetStakeholderHistoryWithContext returns the historical stakeholder config file fetched by the wrapped config service
+func (cs *ConfigService) GetStakeholderHistoryWithContext(ctx context.Context, url, hash, hashAlgorithm string,
+) (*models.StakeholderFileData, error) {
+	return cs.config.GetStakeholderHistoryWithContext(ctx, url, hash, hashAlgorithm)
+}
+
+// GetSidetreeConfig calls GetSidetreeConfigWithContext with a background context
 func (cs *ConfigService) GetSidetreeConfig(url string) (*models.SidetreeConfig, error) {
-	return cs.config.GetSidetreeConfig(url)
+	return cs.GetSidetreeConfigWithContext(context.Background(), url)
+}
+
+// GetSidetreeConfigWithContext returns the sidetree config
+func (cs *ConfigService) GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error) {
+	return cs.config.GetSidetreeConfigWithContext(ctx, url)
 }
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package verifyingconfig
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -95,6 +96,61 @@ func TestConfigService_GetConsortium(t *testing.T) {
 		require.Error(t, err)
 
 		require.Contains(t, err.Error(), "endorsement")
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Equal(t, "foo.bar", insufficient.Domain)
+		require.Equal(t, 2, insufficient.Required)
+		require.Equal(t, 1, insufficient.Endorsed)
+		require.Len(t, insufficient.Stakeholders, 1)
+
+		var inconsistent *models.InconsistentConfiguration
+		require.True(t, errors.As(insufficient.Stakeholders[0], &inconsistent))
+		require.Equal(t, s2Serv.URL, inconsistent.Domain)
+	})
+
+	t.Run("failure - stakeholder server down", func(t *testing.T) {
+		consortiumFile := ""
+
+		cServ := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer cServ.Close()
+
+		s1Serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer s1Serv.Close()
+
+		s2Serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer s2Serv.Close()
+
+		var err error
+
+		consortiumFile, err = mockmodels.DummyConsortiumJSON("foo.bar", []*models.StakeholderListElement{
+			{
+				Domain: s1Serv.URL,
+			},
+			{
+				Domain: s2Serv.URL,
+			},
+		})
+		require.NoError(t, err)
+
+		cs := NewService(httpconfig.NewService())
+
+		_, err = cs.GetConsortium(cServ.URL, "foo.bar")
+		require.Error(t, err)
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Len(t, insufficient.Stakeholders, 1)
+
+		var down *models.StakeholderDown
+		require.True(t, errors.As(insufficient.Stakeholders[0], &down))
+		require.Equal(t, s2Serv.URL, down.Domain)
 	})
 
 	t.Run("success - one stakeholder server disagrees, only one needs to agree", func(t *testing.T) {
@@ -139,6 +195,49 @@ func TestConfigService_GetConsortium(t *testing.T) {
 		require.Contains(t, err.Error(), "endorsement")
 	})
 
+	t.Run("success - stakeholders aren't queried for an unmodified config", func(t *testing.T) {
+		consortiumFile := ""
+		stakeholderRequests := 0
+
+		cServ := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer cServ.Close()
+
+		sServ := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stakeholderRequests++
+
+			fmt.Fprint(w, consortiumFile)
+		}))
+		defer sServ.Close()
+
+		var err error
+
+		consortiumFile, err = mockmodels.DummyConsortiumJSON("foo.bar", []*models.StakeholderListElement{
+			{
+				Domain: sServ.URL,
+			},
+		})
+		require.NoError(t, err)
+
+		cs := NewService(httpconfig.NewService())
+
+		for i := 0; i < 3; i++ {
+			conf, err := cs.GetConsortium(cServ.URL, "foo.bar")
+			require.NoError(t, err)
+			require.Equal(t, "foo.bar", conf.Config.Domain)
+		}
+
+		require.Equal(t, 1, stakeholderRequests)
+	})
+
 	t.Run("failure - errors fetching consortium", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
 			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
@@ -160,6 +259,30 @@ func TestConfigService_GetConsortium(t *testing.T) {
 	})
 }
 
+func TestConfigService_GetConsortiumHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return &models.ConsortiumFileData{Config: &models.Consortium{Domain: "foo.bar"}}, nil
+			}})
+
+		c, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", c.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetConsortiumHistoryFunc: func(u, h, a string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetConsortiumHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
+
 func TestConfigService_GetStakeholder(t *testing.T) {
 	t.Run("pass through", func(t *testing.T) {
 		cs := NewService(&mockconfig.MockConfigService{
@@ -178,3 +301,27 @@ func TestConfigService_GetStakeholder(t *testing.T) {
 		require.Equal(t, conf.Config.Domain, "foo")
 	})
 }
+
+func TestConfigService_GetStakeholderHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return &models.StakeholderFileData{Config: &models.Stakeholder{Domain: "foo.bar"}}, nil
+			}})
+
+		sh, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.NoError(t, err)
+		require.Equal(t, "foo.bar", sh.Config.Domain)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		cs := NewService(&mockconfig.MockConfigService{
+			GetStakeholderHistoryFunc: func(u, h, a string) (*models.StakeholderFileData, error) {
+				return nil, fmt.Errorf("error error")
+			}})
+
+		_, err := cs.GetStakeholderHistory("foo", "hash", "SHA256")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error error")
+	})
+}
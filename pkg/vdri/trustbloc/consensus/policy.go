@@ -0,0 +1,221 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Policy names
+const (
+	AllMustAgreeName = "all-must-agree"
+	MajorityName     = "majority"
+	FirstSuccessName = "first-success"
+	QuorumName       = "quorum"
+)
+
+// Response is the result of resolving a DID at one stakeholder endpoint
+type Response struct {
+	// Domain is the domain of the stakeholder owning the endpoint
+	Domain string
+	// Doc is the canonicalized resolved DID doc
+	Doc []byte
+	// Err is the error resolving the DID, if resolution failed
+	Err error
+}
+
+// Policy decides which of the responses from several stakeholder endpoints to accept
+type Policy struct {
+	name string
+	// quorum is the number of agreeing responses required, or 0 if all responses must agree
+	quorum int
+	// majority requires more than half of the responses to agree
+	majority bool
+}
+
+// AllMustAgree returns a policy that accepts a response only if all endpoints succeed and agree
+func AllMustAgree() Policy {
+	return Policy{name: AllMustAgreeName}
+}
+
+// Majority returns a policy that accepts a response that more than half of the endpoints agree on. Responses that
+// disagree with it are logged.
+func Majority() Policy {
+	return Policy{name: MajorityName, majority: true}
+}
+
+// FirstSuccess returns a policy that accepts the first successful response, in endpoint order, provided no other
+// successful response differs from it
+func FirstSuccess() Policy {
+	return Policy{name: FirstSuccessName, quorum: 1}
+}
+
+// Quorum returns a policy that accepts a response that at least n endpoints agree on, provided no successful response
+// differs from it. n must be at least 1.
+func Quorum(n int) (Policy, error) {
+	if n < 1 {
+		return Policy{}, fmt.Errorf("quorum must be at least 1, got %d", n)
+	}
+
+	return Policy{name: fmt.Sprintf("%s %d", QuorumName, n), quorum: n}, nil
+}
+
+// String returns the name of the policy
+func (p Policy) String() string {
+	if p.name == "" {
+		return AllMustAgreeName
+	}
+
+	return p.name
+}
+
+// required returns the number of agreeing responses required out of the given number of responses
+func (p Policy) required(total int) int {
+	switch {
+	case p.majority:
+		return total/2 + 1
+	case p.quorum > 0:
+		return p.quorum
+	default:
+		return total
+	}
+}
+
+// Decide returns the index of the accepted response, or a *MismatchError if the responses don't satisfy the policy.
+// Failed responses count against consensus. So that a forged doc can't be accepted because it happens to be
+// returned first, or by as many endpoints as the genuine doc, responses that disagree are a mismatch unless the
+// policy requires a majority, and then only the majority's doc is accepted.
+func (p Policy) Decide(responses []*Response) (int, error) {
+	if len(responses) == 0 {
+		return 0, fmt.Errorf("no responses")
+	}
+
+	groups := groupResponses(responses)
+
+	// the accepted group is the largest, or the earliest for ties
+	accepted := -1
+
+	for i, group := range groups {
+		if accepted == -1 || len(group) > len(groups[accepted]) {
+			accepted = i
+		}
+	}
+
+	if accepted == -1 || len(groups[accepted]) < p.required(len(responses)) ||
+		(len(groups) > 1 && !p.majority) {
+		return 0, newMismatchError(p, responses, groups, accepted)
+	}
+
+	if len(groups) > 1 {
+		log.Warnf("%s", newMismatchError(p, responses, groups, accepted).disagreement())
+	}
+
+	return groups[accepted][0], nil
+}
+
+// groupResponses groups the indices of successful responses by document, in order of first appearance
+func groupResponses(responses []*Response) [][]int {
+	var groups [][]int
+
+	for i, r := range responses {
+		if r.Err != nil {
+			continue
+		}
+
+		found := false
+
+		for g, group := range groups {
+			if bytes.Equal(responses[group[0]].Doc, r.Doc) {
+				groups[g] = append(groups[g], i)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			groups = append(groups, []int{i})
+		}
+	}
+
+	return groups
+}
+
+// MismatchError is returned when the responses from stakeholder endpoints don't satisfy the consensus policy
+type MismatchError struct {
+	// Policy is the name of the consensus policy
+	Policy string
+	// Agreed lists the stakeholder domains in the largest group of matching responses
+	Agreed []string
+	// Disagreed lists the stakeholder domains whose responses differ from the largest group
+	Disagreed []string
+	// Failed lists the stakeholder domains whose endpoints failed to resolve
+	Failed []string
+	// Errors holds the resolution errors of the failed endpoints, in the same order as Failed
+	Errors []error
+}
+
+func newMismatchError(p Policy, responses []*Response, groups [][]int, accepted int) *MismatchError {
+	e := &MismatchError{Policy: p.String()}
+
+	for i, group := range groups {
+		for _, idx := range group {
+			if i == accepted {
+				e.Agreed = append(e.Agreed, responses[idx].Domain)
+			} else {
+				e.Disagreed = append(e.Disagreed, responses[idx].Domain)
+			}
+		}
+	}
+
+	for _, r := range responses {
+		if r.Err != nil {
+			e.Failed = append(e.Failed, r.Domain)
+			e.Errors = append(e.Errors, r.Err)
+		}
+	}
+
+	return e
+}
+
+// Is reports whether no endpoint resolved the DID and every endpoint's error is the target, so that, for example,
+// a DID that no endpoint knows is reported as not found
+func (e *MismatchError) Is(target error) bool {
+	if len(e.Agreed) > 0 || len(e.Disagreed) > 0 || len(e.Errors) == 0 {
+		return false
+	}
+
+	for _, err := range e.Errors {
+		if !errors.Is(err, target) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// disagreement describes the responses that disagree with the accepted response
+func (e *MismatchError) disagreement() string {
+	return fmt.Sprintf("consensus policy %s accepted the response of [%s] over the different response of [%s]",
+		e.Policy, strings.Join(e.Agreed, ", "), strings.Join(e.Disagreed, ", "))
+}
+
+func (e *MismatchError) Error() string {
+	var errs []string
+
+	for i, err := range e.Errors {
+		errs = append(errs, fmt.Sprintf("%s: %s", e.Failed[i], err.Error()))
+	}
+
+	return fmt.Sprintf("resolution mismatch, consensus policy %s not satisfied: agreed [%s], disagreed [%s], "+
+		"failed [%s]", e.Policy, strings.Join(e.Agreed, ", "), strings.Join(e.Disagreed, ", "),
+		strings.Join(errs, ", "))
+}
@@ -0,0 +1,251 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func responses(docs ...string) []*Response {
+	var out []*Response
+
+	for i, doc := range docs {
+		domain := fmt.Sprintf("d%d", i+1)
+
+		if doc == "" {
+			out = append(out, &Response{Domain: domain, Err: fmt.Errorf("resolve error")})
+		} else {
+			out = append(out, &Response{Domain: domain, Doc: []byte(doc)})
+		}
+	}
+
+	return out
+}
+
+func TestPolicy_Decide(t *testing.T) {
+	tests := []struct {
+		testName  string
+		policy    Policy
+		responses []*Response
+		accepted  int
+		isErr     bool
+		agreed    []string
+		disagreed []string
+		failed    []string
+	}{
+		{
+			testName:  "all must agree - success",
+			policy:    AllMustAgree(),
+			responses: responses("a", "a", "a"),
+			accepted:  0,
+		}, {
+			testName:  "all must agree - mismatch",
+			policy:    AllMustAgree(),
+			responses: responses("a", "b", "a"),
+			isErr:     true,
+			agreed:    []string{"d1", "d3"},
+			disagreed: []string{"d2"},
+		}, {
+			testName:  "all must agree - failure",
+			policy:    AllMustAgree(),
+			responses: responses("a", "", "a"),
+			isErr:     true,
+			agreed:    []string{"d1", "d3"},
+			failed:    []string{"d2"},
+		}, {
+			testName:  "zero value policy - all must agree",
+			policy:    Policy{},
+			responses: responses("a", "b"),
+			isErr:     true,
+			agreed:    []string{"d1"},
+			disagreed: []string{"d2"},
+		}, {
+			testName:  "majority - success",
+			policy:    Majority(),
+			responses: responses("b", "a", "a"),
+			accepted:  1,
+		}, {
+			testName:  "majority - failures count against consensus",
+			policy:    Majority(),
+			responses: responses("a", "a", "", ""),
+			isErr:     true,
+			agreed:    []string{"d1", "d2"},
+			failed:    []string{"d3", "d4"},
+		}, {
+			testName:  "majority - minority disagrees",
+			policy:    Majority(),
+			responses: responses("b", "a", "a", "a"),
+			accepted:  1,
+		}, {
+			testName:  "majority - conflicting groups of half",
+			policy:    Majority(),
+			responses: responses("b", "a", "a", "b"),
+			isErr:     true,
+			agreed:    []string{"d1", "d4"},
+			disagreed: []string{"d2", "d3"},
+		}, {
+			testName:  "first success - success",
+			policy:    FirstSuccess(),
+			responses: responses("", "a", "", "a"),
+			accepted:  1,
+		}, {
+			testName:  "first success - conflicting responses",
+			policy:    FirstSuccess(),
+			responses: responses("", "b", "a"),
+			isErr:     true,
+			agreed:    []string{"d2"},
+			disagreed: []string{"d3"},
+			failed:    []string{"d1"},
+		}, {
+			testName:  "first success - all failed",
+			policy:    FirstSuccess(),
+			responses: responses("", ""),
+			isErr:     true,
+			failed:    []string{"d1", "d2"},
+		}, {
+			testName:  "quorum - success",
+			policy:    quorum(t, 2),
+			responses: responses("", "b", "", "b"),
+			accepted:  1,
+		}, {
+			testName:  "quorum - conflicting groups each reach quorum",
+			policy:    quorum(t, 2),
+			responses: responses("a", "b", "a", "b"),
+			isErr:     true,
+			agreed:    []string{"d1", "d3"},
+			disagreed: []string{"d2", "d4"},
+		}, {
+			testName:  "quorum - reached with a disagreeing response",
+			policy:    quorum(t, 2),
+			responses: responses("a", "b", "", "b"),
+			isErr:     true,
+			agreed:    []string{"d2", "d4"},
+			disagreed: []string{"d1"},
+			failed:    []string{"d3"},
+		}, {
+			testName:  "quorum of one - one forged response",
+			policy:    quorum(t, 1),
+			responses: responses("forged", "a"),
+			isErr:     true,
+			agreed:    []string{"d1"},
+			disagreed: []string{"d2"},
+		}, {
+			testName:  "quorum - not reached",
+			policy:    quorum(t, 3),
+			responses: responses("a", "b", "", "b"),
+			isErr:     true,
+			agreed:    []string{"d2", "d4"},
+			disagreed: []string{"d1"},
+			failed:    []string{"d3"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.testName, func(t *testing.T) {
+			accepted, err := test.policy.Decide(test.responses)
+
+			if !test.isErr {
+				require.NoError(t, err)
+				require.Equal(t, test.accepted, accepted)
+
+				return
+			}
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "resolution mismatch")
+
+			mismatch := &MismatchError{}
+			require.True(t, errors.As(err, &mismatch))
+			require.Equal(t, test.policy.String(), mismatch.Policy)
+			require.Equal(t, test.agreed, mismatch.Agreed)
+			require.Equal(t, test.disagreed, mismatch.Disagreed)
+			require.Equal(t, test.failed, mismatch.Failed)
+			require.Len(t, mismatch.Errors, len(test.failed))
+		})
+	}
+
+	t.Run("no responses", func(t *testing.T) {
+		_, err := AllMustAgree().Decide(nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no responses")
+	})
+
+	t.Run("mismatch error lists failures", func(t *testing.T) {
+		_, err := AllMustAgree().Decide(responses("a", ""))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "d2: resolve error")
+	})
+}
+
+func TestMismatchError_Is(t *testing.T) {
+	errNotFound := errors.New("not found")
+
+	failed := func(errs ...error) []*Response {
+		var out []*Response
+
+		for i, err := range errs {
+			out = append(out, &Response{Domain: fmt.Sprintf("d%d", i+1), Err: err})
+		}
+
+		return out
+	}
+
+	t.Run("every endpoint failed with the target", func(t *testing.T) {
+		_, err := Majority().Decide(failed(fmt.Errorf("d1: %w", errNotFound), errNotFound))
+		require.True(t, errors.Is(err, errNotFound))
+	})
+
+	t.Run("an endpoint failed with another error", func(t *testing.T) {
+		_, err := Majority().Decide(failed(errNotFound, errors.New("timed out")))
+		require.False(t, errors.Is(err, errNotFound))
+	})
+
+	t.Run("an endpoint resolved", func(t *testing.T) {
+		_, err := AllMustAgree().Decide(append(failed(errNotFound), &Response{Domain: "d2", Doc: []byte("a")}))
+		require.Error(t, err)
+		require.False(t, errors.Is(err, errNotFound))
+	})
+}
+
+func TestPolicy_String(t *testing.T) {
+	require.Equal(t, AllMustAgreeName, AllMustAgree().String())
+	require.Equal(t, AllMustAgreeName, Policy{}.String())
+	require.Equal(t, MajorityName, Majority().String())
+	require.Equal(t, FirstSuccessName, FirstSuccess().String())
+	require.Equal(t, "quorum 3", quorum(t, 3).String())
+}
+
+func TestQuorum(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		policy, err := Quorum(1)
+		require.NoError(t, err)
+
+		accepted, err := policy.Decide(responses("", "a", ""))
+		require.NoError(t, err)
+		require.Equal(t, 1, accepted)
+	})
+
+	t.Run("failure - quorum less than one", func(t *testing.T) {
+		for _, n := range []int{0, -1} {
+			_, err := Quorum(n)
+			require.EqualError(t, err, fmt.Sprintf("quorum must be at least 1, got %d", n))
+		}
+	})
+}
+
+func quorum(t *testing.T, n int) Policy {
+	t.Helper()
+
+	policy, err := Quorum(n)
+	require.NoError(t, err)
+
+	return policy
+}
@@ -7,8 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package didconfiguration
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
@@ -19,6 +26,15 @@ import (
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
+var (
+	// ErrKeyNotFound is returned when a key is not a verification method of a DID doc
+	ErrKeyNotFound = errors.New("key id not found in DID doc")
+	// ErrKeyMismatch is returned when a key does not match the verification method with the same id in a DID doc
+	ErrKeyMismatch = errors.New("key does not match DID doc verification method")
+	// ErrUnsupportedKeyType is returned when a key can't be compared to a verification method holding a raw key value
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
+
 // CreateDIDConfiguration creates a DID Configuration asserting a given DID's ownership over a given domain
 //   using the given signing keys (which are assumed to belong to the DID)
 // Implements https://identity.foundation/specs/did-configuration/
@@ -208,3 +224,91 @@ func getJWKs(doc *did.Doc) []*jose2.JWK {
 
 	return jwkList
 }
+
+// VerifyDIDKey verifies that the given key is expressed by the DID doc, as the verification method identified by the
+// given DID URL. Returns ErrKeyNotFound if the DID doc has no such verification method, or ErrKeyMismatch if the
+// verification method has a different key.
+func VerifyDIDKey(keyID string, jwk json.RawMessage, doc *did.Doc) error {
+	method := findVerificationMethod(keyID, doc)
+	if method == nil {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+
+	key := jose.JSONWebKey{}
+
+	err := key.UnmarshalJSON(jwk)
+	if err != nil {
+		return fmt.Errorf("failed to parse key %s: %w", keyID, err)
+	}
+
+	match, err := keyMatches(&key, method)
+	if err != nil {
+		return fmt.Errorf("failed to compare key %s: %w", keyID, err)
+	}
+
+	if !match {
+		return fmt.Errorf("%w: %s", ErrKeyMismatch, keyID)
+	}
+
+	return nil
+}
+
+func findVerificationMethod(keyID string, doc *did.Doc) *did.VerificationMethod {
+	for i := range doc.VerificationMethod {
+		if didURLMatches(doc.ID, doc.VerificationMethod[i].ID, keyID) {
+			return &doc.VerificationMethod[i]
+		}
+	}
+
+	for i := range doc.Authentication {
+		if didURLMatches(doc.ID, doc.Authentication[i].VerificationMethod.ID, keyID) {
+			return &doc.Authentication[i].VerificationMethod
+		}
+	}
+
+	return nil
+}
+
+// didURLMatches compares two DID URLs, resolving relative DID URLs against the DID doc's ID
+func didURLMatches(docID, a, b string) bool {
+	if strings.HasPrefix(a, "#") {
+		a = docID + a
+	}
+
+	if strings.HasPrefix(b, "#") {
+		b = docID + b
+	}
+
+	return a == b
+}
+
+func keyMatches(key *jose.JSONWebKey, method *did.VerificationMethod) (bool, error) {
+	if methodJWK := method.JSONWebKey(); methodJWK != nil && methodJWK.Key != nil {
+		keyPublic, methodPublic := key.Public(), methodJWK.Public()
+
+		keyThumbprint, err := keyPublic.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return false, err
+		}
+
+		methodThumbprint, err := methodPublic.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return false, err
+		}
+
+		return bytes.Equal(keyThumbprint, methodThumbprint), nil
+	}
+
+	// verification methods without a JWK hold the raw public key value
+	switch publicKey := key.Public().Key.(type) {
+	case ed25519.PublicKey:
+		return bytes.Equal(publicKey, method.Value), nil
+	case *ecdsa.PublicKey:
+		// raw EC keys are either compressed or uncompressed curve points
+		return bytes.Equal(elliptic.Marshal(publicKey.Curve, publicKey.X, publicKey.Y), method.Value) ||
+			bytes.Equal(elliptic.MarshalCompressed(publicKey.Curve, publicKey.X, publicKey.Y), method.Value), nil
+	default:
+		return false, fmt.Errorf("%w %T for verification method %s without a JWK", ErrUnsupportedKeyType,
+			key.Key, method.ID)
+	}
+}
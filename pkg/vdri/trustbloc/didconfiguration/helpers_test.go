@@ -7,7 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package didconfiguration
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
@@ -473,3 +480,121 @@ func TestVerifyDIDSignature(t *testing.T) {
 		require.Contains(t, err.Error(), "failed to verify")
 	})
 }
+
+func TestVerifyDIDKey(t *testing.T) {
+	doc, err := did.ParseDocument([]byte(testDoc))
+	require.NoError(t, err)
+
+	pubKey := json.RawMessage(`{
+  "kty": "OKP",
+  "crv": "Ed25519",
+  "x": "bWRCy8DtNhRO3HdKTFB2eEG5Ac1J00D0DQPffOwtAD0"
+}`)
+
+	t.Run("success - authentication key", func(t *testing.T) {
+		err := VerifyDIDKey("did:example:123456789abcdefghi#key-1", pubKey, doc)
+		require.NoError(t, err)
+	})
+
+	t.Run("success - relative key id", func(t *testing.T) {
+		err := VerifyDIDKey("#key-1", pubKey, doc)
+		require.NoError(t, err)
+	})
+
+	t.Run("success - verification method", func(t *testing.T) {
+		err := VerifyDIDKey("did:example:123456789abcdefghi#key-2", json.RawMessage(`{
+  "kty": "OKP",
+  "crv": "Ed25519",
+  "x": "8rfXFZNHZs9GYzGbQLYDasGUAm1brAgTLI0jrD4KheU"
+}`), doc)
+		require.NoError(t, err)
+	})
+
+	t.Run("failure - key id not in doc", func(t *testing.T) {
+		err := VerifyDIDKey("did:example:123456789abcdefghi#key-3", pubKey, doc)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrKeyNotFound))
+	})
+
+	t.Run("failure - key doesn't match", func(t *testing.T) {
+		err := VerifyDIDKey("did:example:123456789abcdefghi#key-2", pubKey, doc)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrKeyMismatch))
+	})
+
+	t.Run("failure - can't parse key", func(t *testing.T) {
+		err := VerifyDIDKey("did:example:123456789abcdefghi#key-1", json.RawMessage(`[]`), doc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse key")
+	})
+
+	t.Run("success - raw key value", func(t *testing.T) {
+		var key jose.JSONWebKey
+		require.NoError(t, key.UnmarshalJSON(pubKey))
+
+		rawDoc := &did.Doc{
+			ID: "did:example:raw",
+			VerificationMethod: []did.VerificationMethod{
+				*did.NewVerificationMethodFromBytes("#key-1", "Ed25519VerificationKey2018", "did:example:raw",
+					key.Key.(ed25519.PublicKey)),
+			},
+		}
+
+		require.NoError(t, VerifyDIDKey("did:example:raw#key-1", pubKey, rawDoc))
+	})
+
+	t.Run("success - raw EC key value", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		ecKey, err := json.Marshal(&jose.JSONWebKey{Key: &privateKey.PublicKey})
+		require.NoError(t, err)
+
+		point := elliptic.Marshal(elliptic.P256(), privateKey.X, privateKey.Y)
+		compressed := elliptic.MarshalCompressed(elliptic.P256(), privateKey.X, privateKey.Y)
+
+		for _, value := range [][]byte{point, compressed} {
+			rawDoc := &did.Doc{
+				ID: "did:example:raw",
+				VerificationMethod: []did.VerificationMethod{
+					*did.NewVerificationMethodFromBytes("#key-1", "EcdsaSecp256r1VerificationKey2019",
+						"did:example:raw", value),
+				},
+			}
+
+			require.NoError(t, VerifyDIDKey("did:example:raw#key-1", ecKey, rawDoc))
+		}
+
+		rawDoc := &did.Doc{
+			ID: "did:example:raw",
+			VerificationMethod: []did.VerificationMethod{
+				*did.NewVerificationMethodFromBytes("#key-1", "EcdsaSecp256r1VerificationKey2019",
+					"did:example:raw", []byte("other key")),
+			},
+		}
+
+		err = VerifyDIDKey("did:example:raw#key-1", ecKey, rawDoc)
+		require.True(t, errors.Is(err, ErrKeyMismatch))
+	})
+
+	t.Run("failure - raw value of unsupported key type", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		rsaKey, err := json.Marshal(&jose.JSONWebKey{Key: &privateKey.PublicKey})
+		require.NoError(t, err)
+
+		rawDoc := &did.Doc{
+			ID: "did:example:raw",
+			VerificationMethod: []did.VerificationMethod{
+				*did.NewVerificationMethodFromBytes("#key-1", "RsaVerificationKey2018", "did:example:raw",
+					x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)),
+			},
+		}
+
+		err = VerifyDIDKey("did:example:raw#key-1", rsaKey, rawDoc)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrUnsupportedKeyType))
+		require.False(t, errors.Is(err, ErrKeyMismatch))
+	})
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package didconfiguration
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -17,32 +18,39 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 // Service fetches and verifies DID-configurations
 type Service struct {
-	httpClient *http.Client
-	tlsConfig  *tls.Config
+	httpClient  *http.Client
+	tlsConfig   *tls.Config
+	retryPolicy *retry.Policy
 }
 
 // NewService create new didconfiguration Service
 func NewService(opts ...Option) *Service {
-	service := &Service{
-		httpClient: &http.Client{},
-	}
+	service := &Service{}
 
 	for _, opt := range opts {
 		opt(service)
 	}
 
-	service.httpClient.Transport = &http.Transport{TLSClientConfig: service.tlsConfig}
+	if service.httpClient == nil {
+		service.httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: service.tlsConfig}}
+	}
 
 	return service
 }
 
-// VerifyStakeholder verify the DID configuration on a stakeholder server
+// VerifyStakeholder calls VerifyStakeholderWithContext with a background context
 func (s *Service) VerifyStakeholder(domain string, doc *did.Doc) error {
-	conf, err := s.getConfiguration(domain)
+	return s.VerifyStakeholderWithContext(context.Background(), domain, doc)
+}
+
+// VerifyStakeholderWithContext verify the DID configuration on a stakeholder server
+func (s *Service) VerifyStakeholderWithContext(ctx context.Context, domain string, doc *did.Doc) error {
+	conf, err := s.getConfiguration(ctx, domain)
 	if err != nil {
 		return fmt.Errorf("can't get stakeholder `%s` did configuration: %w", domain, err)
 	}
@@ -55,7 +63,7 @@ func (s *Service) VerifyStakeholder(domain string, doc *did.Doc) error {
 	return nil
 }
 
-func (s *Service) getConfiguration(domain string) (*models.DIDConfiguration, error) {
+func (s *Service) getConfiguration(ctx context.Context, domain string) (*models.DIDConfiguration, error) {
 	var url string
 	if strings.HasPrefix(domain, "http") {
 		url = domain
@@ -65,7 +73,12 @@ func (s *Service) getConfiguration(domain string) (*models.DIDConfiguration, err
 
 	url += "/.well-known/did-configuration.json"
 
-	res, err := s.httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.retryPolicy.Do(s.httpClient, req)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +92,6 @@ func (s *Service) getConfiguration(domain string) (*models.DIDConfiguration, err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		// TODO retry https://github.com/trustbloc/trustbloc-did-method/issues/159
 		return nil, fmt.Errorf("stakeholder did-configuration request failed: error %d, `%s`", res.StatusCode, string(body))
 	}
 
@@ -102,3 +114,17 @@ func WithTLSConfig(tlsConfig *tls.Config) Option {
 		opts.tlsConfig = tlsConfig
 	}
 }
+
+// WithHTTPClient sets the http client used to fetch did-configurations, in place of a client using the TLS config
+func WithHTTPClient(client *http.Client) Option {
+	return func(opts *Service) {
+		opts.httpClient = client
+	}
+}
+
+// WithRetryPolicy sets the policy for retrying failed did-configuration requests. By default, requests are not retried.
+func WithRetryPolicy(policy *retry.Policy) Option {
+	return func(opts *Service) {
+		opts.retryPolicy = policy
+	}
+}
@@ -7,8 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package didconfiguration
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +19,8 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/square/go-jose/v3"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 func TestService_VerifyStakeholder(t *testing.T) {
@@ -62,6 +66,24 @@ func TestService_VerifyStakeholder(t *testing.T) {
 		require.Contains(t, err.Error(), "did-configuration request failed")
 	})
 
+	t.Run("failure - retries exhausted", func(t *testing.T) {
+		requests := 0
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer serv.Close()
+
+		s := NewService(WithRetryPolicy(&retry.Policy{MaxAttempts: 2, RetryableStatusClasses: []int{5}}))
+
+		err := s.VerifyStakeholder(serv.URL, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did-configuration request failed: error 502")
+		require.Equal(t, 2, requests)
+	})
+
 	t.Run("failure - bad config file", func(t *testing.T) {
 		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprint(w, "%^$&^Bad data")
@@ -104,6 +126,22 @@ func TestService_VerifyStakeholder(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "did configuration invalid")
 	})
+
+	t.Run("failure - context canceled", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "{}")
+		}))
+		defer serv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := NewService()
+
+		err := s.VerifyStakeholderWithContext(ctx, serv.URL, nil)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
 }
 
 func TestOpts(t *testing.T) {
@@ -121,4 +159,11 @@ func TestOpts(t *testing.T) {
 
 		require.Equal(t, "test", s.tlsConfig.ServerName)
 	})
+
+	t.Run("test http client", func(t *testing.T) {
+		client := &http.Client{}
+
+		s := NewService(WithHTTPClient(client), WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+		require.Same(t, client, s.httpClient)
+	})
 }
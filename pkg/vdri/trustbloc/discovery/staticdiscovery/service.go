@@ -7,14 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package staticdiscovery
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 type config interface {
-	GetConsortium(url, domain string) (*models.ConsortiumFileData, error)
-	GetStakeholder(url, domain string) (*models.StakeholderFileData, error)
+	GetConsortiumWithContext(ctx context.Context, url, domain string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(ctx context.Context, url, domain string) (*models.StakeholderFileData, error)
 }
 
 // DiscoveryService fetches endpoints for a consortium
@@ -31,9 +32,15 @@ func NewService(c config) *DiscoveryService {
 	return endpointService
 }
 
-// GetEndpoints get a list of endpoints to use from a consortium domain
+// GetEndpoints calls GetEndpointsWithContext with a background context
 func (ds *DiscoveryService) GetEndpoints(consortiumDomain string) ([]*models.Endpoint, error) {
-	consortiumData, err := ds.config.GetConsortium(consortiumDomain, consortiumDomain)
+	return ds.GetEndpointsWithContext(context.Background(), consortiumDomain)
+}
+
+// GetEndpointsWithContext get a list of endpoints to use from a consortium domain
+func (ds *DiscoveryService) GetEndpointsWithContext(ctx context.Context, consortiumDomain string,
+) ([]*models.Endpoint, error) {
+	consortiumData, err := ds.config.GetConsortiumWithContext(ctx, consortiumDomain, consortiumDomain)
 	if err != nil {
 		return nil, fmt.Errorf("getting consortium: %w", err)
 	}
@@ -43,7 +50,9 @@ func (ds *DiscoveryService) GetEndpoints(consortiumDomain string) ([]*models.End
 		return nil, fmt.Errorf("consortium config is nil")
 	}
 
-	stakeholders, err := ds.getStakeholderConfigs(consortium)
+	// the stakeholder configs are validated against what this consortium's config says about them
+	stakeholders, err := ds.getStakeholderConfigs(models.WithConsortium(ctx, consortiumDomain, consortiumDomain),
+		consortium)
 	if err != nil {
 		return nil, fmt.Errorf("stakeholder config: %w", err)
 	}
@@ -52,11 +61,12 @@ func (ds *DiscoveryService) GetEndpoints(consortiumDomain string) ([]*models.End
 }
 
 // getStakeholderConfigs gets the list of stakeholder configs
-func (ds *DiscoveryService) getStakeholderConfigs(consortium *models.Consortium) ([]models.StakeholderFileData, error) { // nolint: lll
+func (ds *DiscoveryService) getStakeholderConfigs(ctx context.Context, consortium *models.Consortium,
+) ([]models.StakeholderFileData, error) {
 	var stakeholders []models.StakeholderFileData
 
 	for _, s := range consortium.Members {
-		stakeholderConfig, err := ds.config.GetStakeholder(s.Domain, s.Domain)
+		stakeholderConfig, err := ds.config.GetStakeholderWithContext(ctx, s.Domain, s.Domain)
 		if err != nil {
 			return nil, err
 		}
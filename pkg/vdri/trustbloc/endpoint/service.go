@@ -7,17 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package endpoint
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 type discovery interface {
-	GetEndpoints(domain string) ([]*models.Endpoint, error)
+	GetEndpointsWithContext(ctx context.Context, domain string) ([]*models.Endpoint, error)
 }
 
 type selection interface {
-	SelectEndpoints(domain string, endpoints []*models.Endpoint) ([]*models.Endpoint, error)
+	SelectEndpointsWithContext(ctx context.Context, domain string, endpoints []*models.Endpoint,
+	) ([]*models.Endpoint, error)
 }
 
 // EndpointService uses discovery service and selection service to fetch and filter endpoints
@@ -36,14 +38,19 @@ func NewService(d discovery, s selection) *EndpointService {
 	return endpointService
 }
 
-// GetEndpoints get a list of endpoints to use from a consortium at a given domain
+// GetEndpoints calls GetEndpointsWithContext with a background context
 func (es *EndpointService) GetEndpoints(domain string) ([]*models.Endpoint, error) {
-	eps, err := es.discovery.GetEndpoints(domain)
+	return es.GetEndpointsWithContext(context.Background(), domain)
+}
+
+// GetEndpointsWithContext get a list of endpoints to use from a consortium at a given domain
+func (es *EndpointService) GetEndpointsWithContext(ctx context.Context, domain string) ([]*models.Endpoint, error) {
+	eps, err := es.discovery.GetEndpointsWithContext(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf("discovery: %w", err)
 	}
 
-	out, err := es.selection.SelectEndpoints(domain, eps)
+	out, err := es.selection.SelectEndpointsWithContext(ctx, domain, eps)
 	if err != nil {
 		return nil, fmt.Errorf("selection: %w", err)
 	}
@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package historyhash
+
+import (
+	"crypto"
+	_ "crypto/sha256" // register SHA-256 with crypto
+	_ "crypto/sha512" // register SHA-384 and SHA-512 with crypto
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// Hash algorithm identifiers, as used in the historyHash consortium policy element
+const (
+	SHA256          = "SHA256"
+	SHA384          = "SHA384"
+	SHA512          = "SHA512"
+	MultihashSHA256 = "MULTIHASH-SHA256"
+	MultihashSHA384 = "MULTIHASH-SHA384"
+	MultihashSHA512 = "MULTIHASH-SHA512"
+
+	// Default is the hash algorithm used when a config does not declare one
+	Default = SHA256
+)
+
+// multihash codes, see https://github.com/multiformats/multicodec/blob/master/table.csv
+const (
+	multihashSHA2256 = 0x12
+	multihashSHA2512 = 0x13
+	multihashSHA2384 = 0x20
+)
+
+// HashFunc computes the string identifying a history file from the file's contents
+type HashFunc func(data []byte) (string, error)
+
+// Registry holds the hash algorithms that can be used for identifying history files
+// Hash algorithms should be registered before the registry is shared between goroutines.
+type Registry struct {
+	hashes map[string]HashFunc
+}
+
+// NewRegistry creates a Registry containing the SHA-256, SHA-384 and SHA-512 hashes, both as plain hashes and
+// multihash-encoded, each base64url encoded
+func NewRegistry() *Registry {
+	r := &Registry{hashes: map[string]HashFunc{}}
+
+	r.Register(SHA256, plainHash(crypto.SHA256))
+	r.Register(SHA384, plainHash(crypto.SHA384))
+	r.Register(SHA512, plainHash(crypto.SHA512))
+	r.Register(MultihashSHA256, multihash(crypto.SHA256, multihashSHA2256))
+	r.Register(MultihashSHA384, multihash(crypto.SHA384, multihashSHA2384))
+	r.Register(MultihashSHA512, multihash(crypto.SHA512, multihashSHA2512))
+
+	return r
+}
+
+// Register adds a hash algorithm to the registry under the given name, replacing any existing algorithm of that name
+func (r *Registry) Register(name string, hash HashFunc) {
+	r.hashes[name] = hash
+}
+
+// Hash computes the hash of the given data using the named algorithm, or the default algorithm if name is empty
+func (r *Registry) Hash(name string, data []byte) (string, error) {
+	if name == "" {
+		name = Default
+	}
+
+	hash, ok := r.hashes[name]
+	if !ok {
+		return "", fmt.Errorf("history hash algorithm %s not supported", name)
+	}
+
+	return hash(data)
+}
+
+// Verify checks that the given hash is the hash of the given data using the named algorithm
+func (r *Registry) Verify(name, hash string, data []byte) error {
+	computed, err := r.Hash(name, data)
+	if err != nil {
+		return err
+	}
+
+	if computed != hash {
+		return fmt.Errorf("data does not match hash %s", hash)
+	}
+
+	return nil
+}
+
+func digest(h crypto.Hash, data []byte) ([]byte, error) {
+	hasher := h.New()
+
+	_, err := hasher.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+func plainHash(h crypto.Hash) HashFunc {
+	return func(data []byte) (string, error) {
+		sum, err := digest(h, data)
+		if err != nil {
+			return "", err
+		}
+
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	}
+}
+
+func multihash(h crypto.Hash, code uint64) HashFunc {
+	return func(data []byte) (string, error) {
+		sum, err := digest(h, data)
+		if err != nil {
+			return "", err
+		}
+
+		buf := make([]byte, 2*binary.MaxVarintLen64, 2*binary.MaxVarintLen64+len(sum))
+
+		n := binary.PutUvarint(buf, code)
+		n += binary.PutUvarint(buf[n:], uint64(len(sum)))
+
+		return base64.RawURLEncoding.EncodeToString(append(buf[:n], sum...)), nil
+	}
+}
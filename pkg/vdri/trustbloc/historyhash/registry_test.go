@@ -0,0 +1,124 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package historyhash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Hash(t *testing.T) {
+	data := []byte("abc")
+
+	t.Run("success - default is SHA256", func(t *testing.T) {
+		r := NewRegistry()
+
+		h1, err := r.Hash("", data)
+		require.NoError(t, err)
+
+		h2, err := r.Hash(SHA256, data)
+		require.NoError(t, err)
+
+		require.Equal(t, h1, h2)
+		require.Equal(t, "ungWv48Bz-pBQUDeXa4iI7ADYaOWF3qctBD_YfIAFa0", h1)
+	})
+
+	t.Run("success - digest lengths", func(t *testing.T) {
+		r := NewRegistry()
+
+		tests := map[string]int{
+			SHA256:          32,
+			SHA384:          48,
+			SHA512:          64,
+			MultihashSHA256: 34,
+			MultihashSHA384: 50,
+			MultihashSHA512: 66,
+		}
+
+		for name, length := range tests {
+			h, err := r.Hash(name, data)
+			require.NoError(t, err)
+
+			raw, err := base64.RawURLEncoding.DecodeString(h)
+			require.NoError(t, err)
+			require.Len(t, raw, length, name)
+		}
+	})
+
+	t.Run("success - multihash prefix", func(t *testing.T) {
+		r := NewRegistry()
+
+		h, err := r.Hash(MultihashSHA256, data)
+		require.NoError(t, err)
+
+		raw, err := base64.RawURLEncoding.DecodeString(h)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x12, 0x20}, raw[:2])
+
+		plain, err := r.Hash(SHA256, data)
+		require.NoError(t, err)
+		require.Equal(t, plain, base64.RawURLEncoding.EncodeToString(raw[2:]))
+	})
+
+	t.Run("success - custom algorithm", func(t *testing.T) {
+		r := NewRegistry()
+
+		r.Register("CUSTOM", func(data []byte) (string, error) {
+			return "custom-" + string(data), nil
+		})
+
+		h, err := r.Hash("CUSTOM", data)
+		require.NoError(t, err)
+		require.Equal(t, "custom-abc", h)
+	})
+
+	t.Run("failure - unsupported algorithm", func(t *testing.T) {
+		r := NewRegistry()
+
+		_, err := r.Hash("MD5", data)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not supported")
+	})
+}
+
+func TestRegistry_Verify(t *testing.T) {
+	data := []byte("abc")
+
+	t.Run("success", func(t *testing.T) {
+		r := NewRegistry()
+
+		h, err := r.Hash(SHA512, data)
+		require.NoError(t, err)
+
+		require.NoError(t, r.Verify(SHA512, h, data))
+	})
+
+	t.Run("failure - mismatch", func(t *testing.T) {
+		r := NewRegistry()
+
+		h, err := r.Hash(SHA512, data)
+		require.NoError(t, err)
+
+		err = r.Verify(SHA384, h, data)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match hash")
+	})
+
+	t.Run("failure - hash error", func(t *testing.T) {
+		r := NewRegistry()
+
+		r.Register("BAD", func(data []byte) (string, error) {
+			return "", fmt.Errorf("hash error")
+		})
+
+		err := r.Verify("BAD", "", data)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "hash error")
+	})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+)
+
+// Option configures the http client
+type Option func(opts *options)
+
+type options struct {
+	tlsConfig *tls.Config
+	authToken string
+	header    http.Header
+	proxyURL  *url.URL
+}
+
+// New creates an http client which adds the configured auth token and headers to every request it sends, unless the
+// request sets them itself, and sends requests through the configured proxy.
+// Without a proxy, requests are sent directly, ignoring proxy environment variables.
+func New(opts ...Option) *http.Client {
+	o := &options{header: http.Header{}}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	transport := &http.Transport{TLSClientConfig: o.tlsConfig}
+
+	if o.proxyURL != nil {
+		transport.Proxy = http.ProxyURL(o.proxyURL)
+	}
+
+	if o.authToken == "" && len(o.header) == 0 {
+		return &http.Client{Transport: transport}
+	}
+
+	return &http.Client{Transport: &headerTransport{base: transport, authToken: o.authToken, header: o.header}}
+}
+
+// WithTLSConfig option is for definition of secured HTTP transport using a tls.Config instance
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(opts *options) {
+		opts.tlsConfig = tlsConfig
+	}
+}
+
+// WithAuthToken adds the auth token as a bearer token to requests. An empty token adds nothing.
+func WithAuthToken(authToken string) Option {
+	return func(opts *options) {
+		if authToken != "" {
+			opts.authToken = "Bearer " + authToken
+		}
+	}
+}
+
+// WithHeader adds a header to requests. Headers added more than once are sent with all their values.
+func WithHeader(name, value string) Option {
+	return func(opts *options) {
+		opts.header.Add(name, value)
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(opts *options) {
+		opts.header.Set("User-Agent", userAgent)
+	}
+}
+
+// WithProxy sends requests through the proxy at the given url
+func WithProxy(proxyURL *url.URL) Option {
+	return func(opts *options) {
+		opts.proxyURL = proxyURL
+	}
+}
+
+// headerTransport adds the auth token and headers to requests which don't set them
+type headerTransport struct {
+	base      http.RoundTripper
+	authToken string
+	header    http.Header
+}
+
+// RoundTrip sends a copy of the request with the auth token and headers added.
+// As for an Authorization header set on a request, the auth token isn't sent when redirected to another host.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
+	for name, values := range t.header {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = append([]string(nil), values...)
+		}
+	}
+
+	if t.authToken != "" && req.Header.Get("Authorization") == "" && !redirectedToOtherHost(req) {
+		req.Header.Set("Authorization", t.authToken)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// redirectedToOtherHost returns whether the request follows redirects from a request to a different host
+func redirectedToOtherHost(req *http.Request) bool {
+	first := req
+
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+
+	return first.URL.Host != req.URL.Host
+}
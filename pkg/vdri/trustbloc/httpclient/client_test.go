@@ -0,0 +1,113 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, client *http.Client, url string, header http.Header) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("success - adds auth token and headers", func(t *testing.T) {
+		var received http.Header
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+		}))
+		defer serv.Close()
+
+		client := New(WithAuthToken("tk1"), WithHeader("X-Api-Key", "a"), WithHeader("X-Api-Key", "b"),
+			WithUserAgent("resolver/1.0"))
+
+		get(t, client, serv.URL, nil)
+
+		require.Equal(t, "Bearer tk1", received.Get("Authorization"))
+		require.Equal(t, []string{"a", "b"}, received.Values("X-Api-Key"))
+		require.Equal(t, "resolver/1.0", received.Get("User-Agent"))
+
+		// headers set on the request take precedence
+		get(t, client, serv.URL, http.Header{"Authorization": {"Bearer tk2"}, "X-Api-Key": {"c"}})
+
+		require.Equal(t, "Bearer tk2", received.Get("Authorization"))
+		require.Equal(t, []string{"c"}, received.Values("X-Api-Key"))
+		require.Equal(t, "resolver/1.0", received.Get("User-Agent"))
+	})
+
+	t.Run("success - no auth token or headers", func(t *testing.T) {
+		var received http.Header
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+		}))
+		defer serv.Close()
+
+		client := New(WithAuthToken(""), WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+
+		_, ok := client.Transport.(*http.Transport)
+		require.True(t, ok)
+
+		get(t, client, serv.URL, nil)
+
+		require.Empty(t, received.Get("Authorization"))
+	})
+
+	t.Run("success - auth token isn't sent when redirected to another host", func(t *testing.T) {
+		var received http.Header
+
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+		}))
+		defer other.Close()
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		}))
+		defer serv.Close()
+
+		get(t, New(WithAuthToken("tk1"), WithHeader("X-Api-Key", "a")), serv.URL, nil)
+
+		require.Empty(t, received.Get("Authorization"))
+		require.Equal(t, "a", received.Get("X-Api-Key"))
+	})
+
+	t.Run("success - sends requests through proxy", func(t *testing.T) {
+		var proxied string
+
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.String()
+		}))
+		defer proxy.Close()
+
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+
+		get(t, New(WithProxy(proxyURL)), "http://stakeholder.example/.well-known/did-configuration.json", nil)
+
+		require.Equal(t, "http://stakeholder.example/.well-known/did-configuration.json", proxied)
+	})
+}
@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	sidetreedoc "github.com/hyperledger/aries-framework-go-ext/component/vdr/sidetree/doc"
+	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
+	log "github.com/sirupsen/logrus"
+	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/client"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/longform"
+)
+
+// buildCreateRequest builds the create request for the create options, as the sidetree client does
+func buildCreateRequest(createDIDOpts *create.Opts) ([]byte, error) {
+	docBytes, err := (&sidetreedoc.Doc{PublicKey: createDIDOpts.PublicKeys, Service: createDIDOpts.Services}).JSONBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document bytes: %w", err)
+	}
+
+	recoveryKey, err := pubkey.GetPublicKeyJWK(createDIDOpts.RecoveryPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recovery key: %w", err)
+	}
+
+	updateKey, err := pubkey.GetPublicKeyJWK(createDIDOpts.UpdatePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get update key: %w", err)
+	}
+
+	recoveryCommitment, err := commitment.GetCommitment(recoveryKey, createDIDOpts.MultiHashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	updateCommitment, err := commitment.GetCommitment(updateKey, createDIDOpts.MultiHashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.NewCreateRequest(&client.CreateRequestInfo{
+		OpaqueDocument:     string(docBytes),
+		RecoveryCommitment: recoveryCommitment,
+		UpdateCommitment:   updateCommitment,
+		MultihashCode:      createDIDOpts.MultiHashAlgorithm,
+	})
+}
+
+// buildLongFormDID creates a DID with the create options, returning the resolution of its long-form DID.
+// The sidetree client doesn't return the create request it sends, and create requests built from the same options
+// may differ in patch order, so the create request the long-form DID embeds is built and sent here.
+func (v *VDRI) buildLongFormDID(ctx context.Context, opts ...create.Option) (*docdid.DocResolution, error) {
+	docResolution, createDIDOpts, req, err := v.sendCreate(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	docResolution, err = v.waitUntilPublished(ctx, docResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	did := docResolution.DIDDocument.ID
+
+	longFormDID, err := longform.FromCreateRequest(did, req)
+	if err != nil {
+		return nil, err
+	}
+
+	longFormResolution, err := longform.NewResolver(
+		longform.WithMultiHashAlgorithm(createDIDOpts.MultiHashAlgorithm)).Read(longFormDID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve long-form did of %s: %w", did, err)
+	}
+
+	return longFormResolution, nil
+}
+
+// sendCreateRequest sends a create request to the sidetree endpoint, returning the resolution of the created DID
+func (v *VDRI) sendCreateRequest(ctx context.Context, endpointURL string, req []byte) (*docdid.DocResolution, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL+"/operations", bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send create sidetree request: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			log.Warnf("failed to close response body: %s", e)
+		}
+	}()
+
+	responseBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to send create sidetree request: got unexpected response from %s status '%d' "+
+			"body %s", endpointURL, resp.StatusCode, responseBytes)
+	}
+
+	docResolution, err := docdid.ParseDocumentResolution(responseBytes)
+	if err == nil {
+		return docResolution, nil
+	}
+
+	if !errors.Is(err, docdid.ErrDIDDocumentNotExist) {
+		return nil, fmt.Errorf("failed to parse document resolution: %w", err)
+	}
+
+	didDoc, err := docdid.ParseDocument(responseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse did document: %w", err)
+	}
+
+	return &docdid.DocResolution{DIDDocument: didDoc}, nil
+}
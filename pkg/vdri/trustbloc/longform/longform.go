@@ -0,0 +1,84 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package longform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/canonicalizer"
+	"github.com/trustbloc/sidetree-core-go/pkg/encoder"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+)
+
+// long-form DIDs are did:trustbloc:<domain>:<suffix>:<initial-state>, where the initial state is the base64url
+// encoded JCS of the create request's suffix data and delta
+const (
+	expectedDIDParts = 5
+	separator        = ":"
+)
+
+// IsLongForm returns whether the DID is a long-form DID
+func IsLongForm(did string) bool {
+	return len(strings.Split(did, separator)) == expectedDIDParts
+}
+
+// FromCreateRequest returns the long-form DID of a DID, given the create request that created it
+func FromCreateRequest(did string, createRequest []byte) (string, error) {
+	req := &model.CreateRequest{}
+
+	err := json.Unmarshal(createRequest, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse create request: %w", err)
+	}
+
+	initialState, err := canonicalizer.MarshalCanonical(&model.CreateRequest{SuffixData: req.SuffixData, Delta: req.Delta})
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize initial state: %w", err)
+	}
+
+	return did + separator + encoder.EncodeToString(initialState), nil
+}
+
+// Parse splits a long-form DID into its short-form DID, its suffix, and the create request of its initial state.
+// The initial state must be canonical, but isn't validated against the suffix.
+func Parse(did string) (string, string, *model.CreateRequest, error) {
+	if !IsLongForm(did) {
+		return "", "", nil, fmt.Errorf("%s is not a long-form did", did)
+	}
+
+	pos := strings.LastIndex(did, separator)
+	shortFormDID, initialState := did[:pos], did[pos+1:]
+
+	decoded, err := encoder.DecodeString(initialState)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to decode initial state: %w", err)
+	}
+
+	req := &model.CreateRequest{}
+
+	err = json.Unmarshal(decoded, req)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to parse initial state: %w", err)
+	}
+
+	if req.SuffixData == nil || req.Delta == nil {
+		return "", "", nil, errors.New("initial state is missing suffix data or delta")
+	}
+
+	expected, err := canonicalizer.MarshalCanonical(req)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to canonicalize initial state: %w", err)
+	}
+
+	if encoder.EncodeToString(expected) != initialState {
+		return "", "", nil, errors.New("initial state is not canonical")
+	}
+
+	return shortFormDID, shortFormDID[strings.LastIndex(shortFormDID, separator)+1:], req, nil
+}
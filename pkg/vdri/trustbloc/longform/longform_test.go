@@ -0,0 +1,161 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package longform
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/canonicalizer"
+	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
+	"github.com/trustbloc/sidetree-core-go/pkg/encoder"
+	"github.com/trustbloc/sidetree-core-go/pkg/patch"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/client"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+)
+
+const didPrefix = "did:trustbloc:testnet:"
+
+// newCreateRequest returns a create request adding a key and a service, with commitments and hashes computed with
+// the multihash algorithm
+func newCreateRequest(t *testing.T, multiHashAlgorithm uint) []byte {
+	newCommitment := func() string {
+		publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		jwk, err := pubkey.GetPublicKeyJWK(publicKey)
+		require.NoError(t, err)
+
+		c, err := commitment.GetCommitment(jwk, multiHashAlgorithm)
+		require.NoError(t, err)
+
+		return c
+	}
+
+	authKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	authJWK, err := pubkey.GetPublicKeyJWK(authKey)
+	require.NoError(t, err)
+
+	authJWKBytes, err := json.Marshal(authJWK)
+	require.NoError(t, err)
+
+	addPublicKeys, err := patch.NewAddPublicKeysPatch(fmt.Sprintf(
+		`[{"id":"key1","type":"JwsVerificationKey2020","purposes":["authentication"],"publicKeyJwk":%s}]`,
+		authJWKBytes))
+	require.NoError(t, err)
+
+	addServices, err := patch.NewAddServiceEndpointsPatch(
+		`[{"id":"svc1","type":"type","serviceEndpoint":"http://example.com"}]`)
+	require.NoError(t, err)
+
+	req, err := client.NewCreateRequest(&client.CreateRequestInfo{
+		Patches:            []patch.Patch{addPublicKeys, addServices},
+		RecoveryCommitment: newCommitment(),
+		UpdateCommitment:   newCommitment(),
+		MultihashCode:      multiHashAlgorithm,
+	})
+	require.NoError(t, err)
+
+	return req
+}
+
+// newLongFormDID returns a long-form DID and its short-form DID, for a new create request with commitments and
+// hashes computed with the multihash algorithm
+func newLongFormDID(t *testing.T, multiHashAlgorithm uint) (string, string) {
+	req := newCreateRequest(t, multiHashAlgorithm)
+
+	createRequest := &model.CreateRequest{}
+	require.NoError(t, json.Unmarshal(req, createRequest))
+
+	suffix, err := model.GetUniqueSuffix(createRequest.SuffixData, []uint{multiHashAlgorithm})
+	require.NoError(t, err)
+
+	longFormDID, err := FromCreateRequest(didPrefix+suffix, req)
+	require.NoError(t, err)
+
+	return longFormDID, didPrefix + suffix
+}
+
+// initialState returns the initial state of a long-form DID with the create request
+func initialState(t *testing.T, req interface{}) string {
+	b, err := canonicalizer.MarshalCanonical(req)
+	require.NoError(t, err)
+
+	return encoder.EncodeToString(b)
+}
+
+func TestIsLongForm(t *testing.T) {
+	require.True(t, IsLongForm("did:trustbloc:testnet:suffix:state"))
+	require.False(t, IsLongForm("did:trustbloc:testnet:suffix"))
+}
+
+func TestFromCreateRequest(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		longFormDID, shortFormDID := newLongFormDID(t, 18)
+		require.True(t, IsLongForm(longFormDID))
+
+		parsedDID, suffix, req, err := Parse(longFormDID)
+		require.NoError(t, err)
+		require.Equal(t, shortFormDID, parsedDID)
+		require.Equal(t, shortFormDID, didPrefix+suffix)
+		require.Len(t, req.Delta.Patches, 2)
+		require.Empty(t, req.Operation)
+	})
+
+	t.Run("test invalid create request", func(t *testing.T) {
+		_, err := FromCreateRequest(didPrefix+"suffix", []byte("{"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse create request")
+	})
+}
+
+func TestParse(t *testing.T) {
+	_, shortFormDID := newLongFormDID(t, 18)
+
+	t.Run("test not long-form", func(t *testing.T) {
+		_, _, _, err := Parse(shortFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not a long-form did")
+	})
+
+	t.Run("test invalid encoding", func(t *testing.T) {
+		_, _, _, err := Parse(shortFormDID + ":!")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decode initial state")
+	})
+
+	t.Run("test invalid json", func(t *testing.T) {
+		_, _, _, err := Parse(shortFormDID + ":" + encoder.EncodeToString([]byte("{")))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse initial state")
+	})
+
+	t.Run("test missing delta", func(t *testing.T) {
+		_, _, _, err := Parse(shortFormDID + ":" +
+			initialState(t, &model.CreateRequest{SuffixData: &model.SuffixDataModel{DeltaHash: "hash"}}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initial state is missing suffix data or delta")
+	})
+
+	t.Run("test not canonical", func(t *testing.T) {
+		b, err := json.MarshalIndent(&model.CreateRequest{
+			SuffixData: &model.SuffixDataModel{DeltaHash: "hash"},
+			Delta:      &model.DeltaModel{UpdateCommitment: "commitment"},
+		}, "", " ")
+		require.NoError(t, err)
+
+		_, _, _, err = Parse(shortFormDID + ":" + encoder.EncodeToString(b))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initial state is not canonical")
+	})
+}
@@ -0,0 +1,173 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package longform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
+	vdrdoc "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/doc"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/resolve"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+	"github.com/trustbloc/sidetree-core-go/pkg/hashing"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/doccomposer"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/doctransformer/didtransformer"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+)
+
+// DefaultMultiHashAlgorithm is the multihash algorithm of DIDs, unless configured otherwise: sha2-256
+const DefaultMultiHashAlgorithm = 18
+
+// Resolver resolves long-form DIDs from the create request embedded in the DID. It needs no consortium discovery and
+// makes no network requests, so the DIDs it resolves are never reported as published, and any later operations on
+// the DIDs aren't applied.
+type Resolver struct {
+	multiHashAlgorithm uint
+	methodContext      []string
+}
+
+// Option is a long-form resolver option
+type Option func(opts *Resolver)
+
+// NewResolver returns a long-form DID resolver
+func NewResolver(opts ...Option) *Resolver {
+	r := &Resolver{multiHashAlgorithm: DefaultMultiHashAlgorithm}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithMultiHashAlgorithm sets the multihash algorithm DID suffixes, delta hashes and commitments must be computed with
+func WithMultiHashAlgorithm(multiHashAlgorithm uint) Option {
+	return func(opts *Resolver) {
+		opts.multiHashAlgorithm = multiHashAlgorithm
+	}
+}
+
+// WithMethodContext sets contexts added to resolved documents, after the DID context
+func WithMethodContext(methodContext ...string) Option {
+	return func(opts *Resolver) {
+		opts.methodContext = methodContext
+	}
+}
+
+// Accept did method
+func (r *Resolver) Accept(method string) bool {
+	return method == "trustbloc"
+}
+
+// Close resolver
+func (r *Resolver) Close() error {
+	return nil
+}
+
+// Store did doc
+func (r *Resolver) Store(doc *docdid.Doc, by *[]vdrdoc.ModifiedBy) error {
+	return nil
+}
+
+// Build isn't supported: DIDs are created at the sidetree endpoints of a consortium
+func (r *Resolver) Build(keyManager kms.KeyManager, opts ...create.Option) (*docdid.DocResolution, error) {
+	return nil, errors.New("long-form resolver doesn't create dids")
+}
+
+// Read resolves a long-form DID from its initial state. The DID's suffix must be the multihash of the initial state's
+// suffix data, which must hold the multihash of the initial state's delta.
+func (r *Resolver) Read(did string, _ ...resolve.Option) (*docdid.DocResolution, error) {
+	_, suffix, req, err := Parse(did)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.validate(suffix, req)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := doccomposer.New().ApplyPatches(make(document.Document), req.Delta.Patches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply initial state patches: %w", err)
+	}
+
+	if len(doc) == 0 {
+		return nil, errors.New("initial state patches resulted in an empty document")
+	}
+
+	result, err := didtransformer.New(didtransformer.WithMethodContext(r.methodContext)).TransformDocument(
+		&protocol.ResolutionModel{
+			Doc:                doc,
+			UpdateCommitment:   req.Delta.UpdateCommitment,
+			RecoveryCommitment: req.SuffixData.RecoveryCommitment,
+		}, protocol.TransformationInfo{document.IDProperty: did, document.PublishedProperty: false})
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform initial state document: %w", err)
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resolution: %w", err)
+	}
+
+	docResolution, err := docdid.ParseDocumentResolution(resultBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resolution: %w", err)
+	}
+
+	docResolution.DocumentMetadata = &docdid.DocumentMetadata{Method: &docdid.MethodMetadata{
+		UpdateCommitment:   req.Delta.UpdateCommitment,
+		RecoveryCommitment: req.SuffixData.RecoveryCommitment,
+	}}
+
+	return docResolution, nil
+}
+
+// validate validates the initial state of a DID against its suffix, using the resolver's multihash algorithm
+func (r *Resolver) validate(suffix string, req *model.CreateRequest) error {
+	err := r.validateMultihash(req.SuffixData, suffix)
+	if err != nil {
+		return fmt.Errorf("did suffix doesn't match initial state: %w", err)
+	}
+
+	err = r.validateMultihash(req.Delta, req.SuffixData.DeltaHash)
+	if err != nil {
+		return fmt.Errorf("delta doesn't match suffix data: %w", err)
+	}
+
+	algorithms := []uint{r.multiHashAlgorithm}
+
+	if !hashing.IsComputedUsingMultihashAlgorithms(req.SuffixData.RecoveryCommitment, algorithms) {
+		return fmt.Errorf("recovery commitment isn't computed with multihash algorithm %d", r.multiHashAlgorithm)
+	}
+
+	if !hashing.IsComputedUsingMultihashAlgorithms(req.Delta.UpdateCommitment, algorithms) {
+		return fmt.Errorf("update commitment isn't computed with multihash algorithm %d", r.multiHashAlgorithm)
+	}
+
+	return nil
+}
+
+// validateMultihash validates the multihash is of the model, computed with the resolver's multihash algorithm
+func (r *Resolver) validateMultihash(value interface{}, multihash string) error {
+	expected, err := hashing.CalculateModelMultihash(value, r.multiHashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	if expected != multihash {
+		return fmt.Errorf("multihash %s isn't the multihash of the content with algorithm %d",
+			multihash, r.multiHashAlgorithm)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package longform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/hashing"
+	"github.com/trustbloc/sidetree-core-go/pkg/patch"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+)
+
+func TestResolver_Read(t *testing.T) {
+	longFormDID, shortFormDID := newLongFormDID(t, 18)
+
+	_, _, req, err := Parse(longFormDID)
+	require.NoError(t, err)
+
+	t.Run("test success", func(t *testing.T) {
+		docResolution, err := NewResolver().Read(longFormDID)
+		require.NoError(t, err)
+		require.Equal(t, longFormDID, docResolution.DIDDocument.ID)
+		require.Equal(t, []string{"https://www.w3.org/ns/did/v1"}, docResolution.DIDDocument.Context)
+		require.Len(t, docResolution.DIDDocument.VerificationMethod, 1)
+		require.Equal(t, longFormDID+"#key1", docResolution.DIDDocument.VerificationMethod[0].ID)
+		require.Len(t, docResolution.DIDDocument.Authentication, 1)
+		require.Len(t, docResolution.DIDDocument.Service, 1)
+		require.False(t, docResolution.DocumentMetadata.Method.Published)
+		require.Equal(t, req.Delta.UpdateCommitment, docResolution.DocumentMetadata.Method.UpdateCommitment)
+		require.Equal(t, req.SuffixData.RecoveryCommitment, docResolution.DocumentMetadata.Method.RecoveryCommitment)
+	})
+
+	t.Run("test method context", func(t *testing.T) {
+		docResolution, err := NewResolver(WithMethodContext("https://example.com/context/v1")).Read(longFormDID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://www.w3.org/ns/did/v1", "https://example.com/context/v1"},
+			docResolution.DIDDocument.Context)
+	})
+
+	t.Run("test configured multihash algorithm", func(t *testing.T) {
+		sha512DID, _ := newLongFormDID(t, 19)
+
+		docResolution, err := NewResolver(WithMultiHashAlgorithm(19)).Read(sha512DID)
+		require.NoError(t, err)
+		require.Equal(t, sha512DID, docResolution.DIDDocument.ID)
+
+		_, err = NewResolver().Read(sha512DID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did suffix doesn't match initial state")
+
+		_, err = NewResolver(WithMultiHashAlgorithm(19)).Read(longFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did suffix doesn't match initial state")
+
+		_, err = NewResolver(WithMultiHashAlgorithm(1)).Read(longFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did suffix doesn't match initial state")
+	})
+
+	t.Run("test invalid long-form did", func(t *testing.T) {
+		_, err := NewResolver().Read(shortFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not a long-form did")
+	})
+
+	t.Run("test suffix mismatch", func(t *testing.T) {
+		otherLongFormDID, otherShortFormDID := newLongFormDID(t, 18)
+
+		_, err := NewResolver().Read(shortFormDID + strings.TrimPrefix(otherLongFormDID, otherShortFormDID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did suffix doesn't match initial state")
+	})
+
+	t.Run("test delta mismatch", func(t *testing.T) {
+		delta := *req.Delta
+		delta.UpdateCommitment = "other"
+
+		_, err := NewResolver().Read(shortFormDID + ":" +
+			initialState(t, &model.CreateRequest{SuffixData: req.SuffixData, Delta: &delta}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "delta doesn't match suffix data")
+	})
+
+	t.Run("test commitments computed with other algorithm", func(t *testing.T) {
+		sha512DID, _ := newLongFormDID(t, 19)
+
+		_, _, sha512Req, err := Parse(sha512DID)
+		require.NoError(t, err)
+
+		// a create request with sha2-256 hashes, but sha2-512 commitments
+		delta := &model.DeltaModel{Patches: req.Delta.Patches, UpdateCommitment: sha512Req.Delta.UpdateCommitment}
+		suffixData := &model.SuffixDataModel{RecoveryCommitment: sha512Req.SuffixData.RecoveryCommitment}
+
+		_, err = NewResolver().Read(longFormDIDOf(t, delta, suffixData))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "recovery commitment isn't computed with multihash algorithm 18")
+
+		suffixData.RecoveryCommitment = req.SuffixData.RecoveryCommitment
+
+		_, err = NewResolver().Read(longFormDIDOf(t, delta, suffixData))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "update commitment isn't computed with multihash algorithm 18")
+	})
+
+	t.Run("test empty document", func(t *testing.T) {
+		delta := &model.DeltaModel{Patches: []patch.Patch{}, UpdateCommitment: req.Delta.UpdateCommitment}
+		suffixData := &model.SuffixDataModel{RecoveryCommitment: req.SuffixData.RecoveryCommitment}
+
+		_, err := NewResolver().Read(longFormDIDOf(t, delta, suffixData))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "initial state patches resulted in an empty document")
+	})
+}
+
+func TestResolver_VDR(t *testing.T) {
+	r := NewResolver()
+
+	require.True(t, r.Accept("trustbloc"))
+	require.False(t, r.Accept("other"))
+	require.NoError(t, r.Store(nil, nil))
+	require.NoError(t, r.Close())
+
+	_, err := r.Build(nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "doesn't create dids")
+}
+
+// longFormDIDOf returns the long-form DID with the delta and suffix data, setting the suffix data's delta hash and
+// the DID's suffix with sha2-256
+func longFormDIDOf(t *testing.T, delta *model.DeltaModel, suffixData *model.SuffixDataModel) string {
+	var err error
+
+	suffixData.DeltaHash, err = hashing.CalculateModelMultihash(delta, 18)
+	require.NoError(t, err)
+
+	suffix, err := hashing.CalculateModelMultihash(suffixData, 18)
+	require.NoError(t, err)
+
+	return didPrefix + suffix + ":" + initialState(t, &model.CreateRequest{SuffixData: suffixData, Delta: delta})
+}
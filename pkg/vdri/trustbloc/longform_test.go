@@ -0,0 +1,359 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
+	vdrdoc "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/doc"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/resolve"
+	mockvdr "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
+	gojose "github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions/0_1/model"
+
+	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
+	mockendpoint "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/endpoint"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/longform"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+const testDIDPrefix = "did:trustbloc:testnet:"
+
+// createOpts returns the options of a DID create
+func createOpts(t *testing.T) []create.Option {
+	recoveryKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	authKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return []create.Option{
+		create.WithRecoveryPublicKey(recoveryKey),
+		create.WithUpdatePublicKey(updateKey),
+		create.WithPublicKey(&vdrdoc.PublicKey{
+			ID: "key1", Type: "JwsVerificationKey2020", Purposes: []string{"authentication"},
+			JWK: gojose.JSONWebKey{Key: authKey},
+		}),
+		create.WithService(&did.Service{ID: "svc1", Type: "type", ServiceEndpoint: "http://example.com"}),
+		create.WithMultiHashAlgorithm(18),
+	}
+}
+
+// createdDID returns the short-form DID a create request creates
+func createdDID(t *testing.T, req []byte) string {
+	createRequest := &model.CreateRequest{}
+	require.NoError(t, json.Unmarshal(req, createRequest))
+
+	suffix, err := model.GetUniqueSuffix(createRequest.SuffixData, []uint{18})
+	require.NoError(t, err)
+
+	return testDIDPrefix + suffix
+}
+
+// newLongFormDID returns a new long-form DID and its short-form DID
+func newLongFormDID(t *testing.T) (string, string) {
+	createDIDOpts := &create.Opts{}
+	for _, opt := range createOpts(t) {
+		opt(createDIDOpts)
+	}
+
+	req, err := buildCreateRequest(createDIDOpts)
+	require.NoError(t, err)
+
+	shortFormDID := createdDID(t, req)
+
+	longFormDID, err := longform.FromCreateRequest(shortFormDID, req)
+	require.NoError(t, err)
+
+	return longFormDID, shortFormDID
+}
+
+// createServer returns a sidetree server responding to create requests with the document of the created DID, or of
+// the DID returned by createdDID if it's set
+func createServer(t *testing.T, createdDIDFunc func(req []byte) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/operations", r.URL.Path)
+		require.Equal(t, "Bearer tk1", r.Header.Get("Authorization"))
+
+		req, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		id := createdDID(t, req)
+		if createdDIDFunc != nil {
+			id = createdDIDFunc(req)
+		}
+
+		_, err = fmt.Fprintf(w, `{"@context":"https://www.w3.org/ns/did-resolution/v1",`+
+			`"didDocument":{"id":"%s","@context":"https://www.w3.org/ns/did/v1"}}`, id)
+		require.NoError(t, err)
+	}))
+}
+
+func TestVDRI_BuildLongFormDID(t *testing.T) {
+	newVDRI := func(url string) *VDRI {
+		v := New(EnableLongFormDID(true), WithAuthToken("tk1"))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: url}}, nil
+			}}
+
+		v.configService = &mockconfig.MockConfigService{
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			}}
+
+		return v
+	}
+
+	t.Run("test success", func(t *testing.T) {
+		var shortFormDID string
+
+		serv := createServer(t, func(req []byte) string {
+			shortFormDID = createdDID(t, req)
+
+			return shortFormDID
+		})
+		defer serv.Close()
+
+		docResolution, err := newVDRI(serv.URL).Build(nil, createOpts(t)...)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(docResolution.DIDDocument.ID, shortFormDID+":"))
+		require.True(t, longform.IsLongForm(docResolution.DIDDocument.ID))
+		require.Len(t, docResolution.DIDDocument.VerificationMethod, 1)
+		require.Len(t, docResolution.DIDDocument.Authentication, 1)
+		require.Len(t, docResolution.DIDDocument.Service, 1)
+		require.False(t, docResolution.DocumentMetadata.Method.Published)
+		require.NotEmpty(t, docResolution.DocumentMetadata.Method.UpdateCommitment)
+		require.NotEmpty(t, docResolution.DocumentMetadata.Method.RecoveryCommitment)
+	})
+
+	t.Run("test created did doesn't match create request", func(t *testing.T) {
+		serv := createServer(t, func(req []byte) string {
+			return testDIDPrefix + "EiDahaOGH-liLLdDtTxEAdc8i-cfCz-WUcQdRJheMVNn3A"
+		})
+		defer serv.Close()
+
+		_, err := newVDRI(serv.URL).Build(nil, createOpts(t)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did suffix doesn't match initial state")
+	})
+
+	t.Run("test error from sidetree", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer serv.Close()
+
+		_, err := newVDRI(serv.URL).Build(nil, createOpts(t)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send create sidetree request")
+
+		_, err = newVDRI("://url").Build(nil, createOpts(t)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create http request")
+	})
+
+	t.Run("test invalid response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := fmt.Fprint(w, "{")
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		_, err := newVDRI(serv.URL).Build(nil, createOpts(t)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse")
+	})
+
+	t.Run("test document response", func(t *testing.T) {
+		var shortFormDID string
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			shortFormDID = createdDID(t, req)
+
+			_, err = fmt.Fprintf(w, `{"id":"%s","@context":"https://www.w3.org/ns/did/v1"}`, shortFormDID)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		docResolution, err := newVDRI(serv.URL).Build(nil, createOpts(t)...)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(docResolution.DIDDocument.ID, shortFormDID+":"))
+	})
+
+	t.Run("test error building create request", func(t *testing.T) {
+		opts := createOpts(t)
+
+		_, err := newVDRI("url").Build(nil, append(opts, create.WithRecoveryPublicKey("key"))...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get recovery key")
+
+		_, err = newVDRI("url").Build(nil, append(opts, create.WithUpdatePublicKey("key"))...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get update key")
+
+		_, err = newVDRI("url").Build(nil, append(opts, create.WithPublicKey(&vdrdoc.PublicKey{ID: "key2"}))...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get document bytes")
+	})
+}
+
+func TestVDRI_ReadLongFormDID(t *testing.T) {
+	longFormDID, shortFormDID := newLongFormDID(t)
+
+	newVDRI := func(endpointErr error, opts ...Option) *VDRI {
+		v := New(opts...)
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: "url"}}, nil
+			}}
+
+		v.getHTTPVDRI = httpVdriFunc(&did.DocResolution{DIDDocument: &did.Doc{ID: "resolved"}}, endpointErr)
+
+		v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+			return []byte(doc.ID), nil
+		}
+
+		return v
+	}
+
+	t.Run("test resolved at endpoints", func(t *testing.T) {
+		docResolution, err := newVDRI(nil).Read(longFormDID)
+		require.NoError(t, err)
+		require.Equal(t, "resolved", docResolution.DIDDocument.ID)
+	})
+
+	unreachable := fmt.Errorf("HTTP Get request failed: %w",
+		&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+
+	t.Run("test resolved from initial state", func(t *testing.T) {
+		for _, endpointErr := range []error{
+			fmt.Errorf("DID does not exist for request: url: %w", vdrapi.ErrNotFound), unreachable,
+		} {
+			docResolution, err := newVDRI(endpointErr).Read(longFormDID)
+			require.NoError(t, err)
+			require.Equal(t, longFormDID, docResolution.DIDDocument.ID)
+			require.Len(t, docResolution.DIDDocument.VerificationMethod, 1)
+			require.Equal(t, longFormDID+"#key1", docResolution.DIDDocument.VerificationMethod[0].ID)
+		}
+	})
+
+	t.Run("test resolved from initial state when endpoints time out", func(t *testing.T) {
+		v := newVDRI(nil, WithEndpointTimeout(10*time.Millisecond))
+		v.getHTTPVDRI = func(url string) (vdri, error) {
+			return &mockvdr.MockVDR{ReadFunc: func(string, ...resolve.Option) (*did.DocResolution, error) {
+				time.Sleep(100 * time.Millisecond)
+
+				return &did.DocResolution{DIDDocument: &did.Doc{ID: "resolved"}}, nil
+			}}, nil
+		}
+
+		docResolution, err := v.Read(longFormDID)
+		require.NoError(t, err)
+		require.Equal(t, longFormDID, docResolution.DIDDocument.ID)
+	})
+
+	t.Run("test resolved from initial state with resolver url", func(t *testing.T) {
+		docResolution, err := newVDRI(unreachable, WithResolverURL("url")).Read(longFormDID)
+		require.NoError(t, err)
+		require.Equal(t, longFormDID, docResolution.DIDDocument.ID)
+	})
+
+	t.Run("test deactivated did isn't resolved from initial state", func(t *testing.T) {
+		_, err := newVDRI(fmt.Errorf("%w for request: url", ErrDIDDeactivated)).Read(longFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "DID is deactivated")
+		require.NotContains(t, err.Error(), "initial state")
+	})
+
+	t.Run("test resolution mismatch isn't resolved from initial state", func(t *testing.T) {
+		v := newVDRI(nil, WithConsensusPolicy(consensus.AllMustAgree()))
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: "url1", Domain: "d1"}, {URL: "url2", Domain: "d2"}}, nil
+			}}
+		v.getHTTPVDRI = func(url string) (vdri, error) {
+			if url == "url2/identifiers" {
+				return httpVdriFunc(nil, unreachable)(url)
+			}
+
+			return httpVdriFunc(&did.DocResolution{DIDDocument: &did.Doc{ID: "resolved"}}, nil)(url)
+		}
+
+		_, err := v.Read(longFormDID)
+		require.Error(t, err)
+
+		var mismatch *consensus.MismatchError
+		require.True(t, errors.As(err, &mismatch))
+		require.Equal(t, []string{"d1"}, mismatch.Agreed)
+		require.Equal(t, []string{"d2"}, mismatch.Failed)
+		require.NotContains(t, err.Error(), "initial state")
+	})
+
+	t.Run("test endpoint discovery failure isn't resolved from initial state", func(t *testing.T) {
+		v := newVDRI(nil)
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return nil, unreachable
+			}}
+
+		_, err := v.Read(longFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get endpoints")
+		require.NotContains(t, err.Error(), "initial state")
+	})
+
+	t.Run("test other endpoint errors aren't resolved from initial state", func(t *testing.T) {
+		_, err := newVDRI(errors.New("unsupported response from DID resolver [500]")).Read(longFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported response")
+		require.NotContains(t, err.Error(), "initial state")
+	})
+
+	t.Run("test long-form resolver", func(t *testing.T) {
+		_, err := newVDRI(unreachable,
+			WithLongFormResolver(longform.NewResolver(longform.WithMultiHashAlgorithm(19)))).Read(longFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did suffix doesn't match initial state")
+	})
+
+	t.Run("test short-form did isn't resolved from initial state", func(t *testing.T) {
+		_, err := newVDRI(unreachable).Read(shortFormDID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("test invalid initial state", func(t *testing.T) {
+		_, err := newVDRI(unreachable).Read(shortFormDID + ":e30")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection refused")
+		require.Contains(t, err.Error(), "failed to resolve from initial state")
+	})
+}
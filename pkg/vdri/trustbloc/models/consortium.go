@@ -42,6 +42,8 @@ type Consortium struct {
 type ConsortiumPolicy struct {
 	Cache      CacheControl `json:"cache"`
 	NumQueries int          `json:"numQueries"`
+	// HistoryHash is the hash algorithm used for identifying history files. Optional, defaults to SHA256.
+	HistoryHash string `json:"historyHash,omitempty"`
 }
 
 // CacheControl holds cache settings for this file,
@@ -72,6 +74,8 @@ type PublicKey struct {
 type ConsortiumFileData struct {
 	Config *Consortium
 	JWS    *jose.JSONWebSignature
+	// ServerMaxAge is the max-age the file was served with, if any, which bounds its cache lifetime
+	ServerMaxAge *time.Duration
 }
 
 // CacheLifetime returns the cache lifetime of the consortium file before it needs to be checked for an update
@@ -80,7 +84,16 @@ func (c ConsortiumFileData) CacheLifetime() (time.Duration, error) {
 		return 0, fmt.Errorf("missing config object")
 	}
 
-	return time.Duration(c.Config.Policy.Cache.MaxAge) * time.Second, nil
+	return boundLifetime(time.Duration(c.Config.Policy.Cache.MaxAge)*time.Second, c.ServerMaxAge), nil
+}
+
+// boundLifetime returns the given cache lifetime, bounded by the max-age the file was served with, if any
+func boundLifetime(lifetime time.Duration, serverMaxAge *time.Duration) time.Duration {
+	if serverMaxAge != nil && *serverMaxAge < lifetime {
+		return *serverMaxAge
+	}
+
+	return lifetime
 }
 
 // ParseConsortium parses the contents of a consortium file into a ConsortiumFileData object
@@ -87,6 +87,27 @@ func TestConsortiumFileData_CacheLifetime(t *testing.T) {
 		require.Equal(t, time.Duration(12345)*time.Second, d)
 	})
 
+	t.Run("success - bounded by server max-age", func(t *testing.T) {
+		serverMaxAge := time.Minute
+
+		cfd := ConsortiumFileData{
+			Config: &Consortium{
+				Policy: ConsortiumPolicy{Cache: CacheControl{MaxAge: 12345}},
+			},
+			ServerMaxAge: &serverMaxAge,
+		}
+
+		d, err := cfd.CacheLifetime()
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, d)
+
+		serverMaxAge = time.Duration(20000) * time.Second
+
+		d, err = cfd.CacheLifetime()
+		require.NoError(t, err)
+		require.Equal(t, time.Duration(12345)*time.Second, d)
+	})
+
 	t.Run("failure", func(t *testing.T) {
 		cfd := ConsortiumFileData{
 			Config: nil,
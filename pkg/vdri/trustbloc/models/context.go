@@ -0,0 +1,30 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models
+
+import "context"
+
+// consortiumKey is the context key of the consortium a config is fetched for
+type consortiumKey struct{}
+
+// ConsortiumRef identifies a consortium config by the url and domain it is fetched with
+type ConsortiumRef struct {
+	URL    string
+	Domain string
+}
+
+// WithConsortium returns a context for fetching the configs of the stakeholders of the consortium fetched with the
+// given url and domain, so they are validated against what that consortium's config says about them
+func WithConsortium(ctx context.Context, url, domain string) context.Context {
+	return context.WithValue(ctx, consortiumKey{}, ConsortiumRef{URL: url, Domain: domain})
+}
+
+// ConsortiumFromContext returns the consortium set on the context by WithConsortium, if any
+func ConsortiumFromContext(ctx context.Context) (ConsortiumRef, bool) {
+	ref, ok := ctx.Value(consortiumKey{}).(ConsortiumRef)
+
+	return ref, ok
+}
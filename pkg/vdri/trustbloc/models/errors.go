@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Errors for the error cases of consortium discovery and verification, as listed in the did:trustbloc spec.
+
+ConsortiumUnavailable and InsufficientEndorsement terminate discovery.
+StakeholderDown, InvalidStakeholderSignature and InconsistentConfiguration are failures of a single stakeholder,
+which are ignored if sufficient other stakeholders endorse the consortium. When they aren't, they're reported as
+the Stakeholders of an InsufficientEndorsement.
+*/
+
+// ConsortiumUnavailable is returned when the consortium config can't be fetched from the consortium domain
+type ConsortiumUnavailable struct {
+	// Domain is the consortium domain
+	Domain string
+	// Err is the error fetching the consortium config
+	Err error
+}
+
+func (e *ConsortiumUnavailable) Error() string {
+	return fmt.Sprintf("consortium %s unavailable: %s", e.Domain, e.Err)
+}
+
+// Unwrap returns the error fetching the consortium config
+func (e *ConsortiumUnavailable) Unwrap() error {
+	return e.Err
+}
+
+// InsufficientEndorsement is returned when fewer stakeholders than the consortium policy requires endorse the
+// consortium
+type InsufficientEndorsement struct {
+	// Domain is the consortium domain
+	Domain string
+	// Required is the number of stakeholders required to endorse the consortium
+	Required int
+	// Endorsed is the number of stakeholders that endorse the consortium
+	Endorsed int
+	// Stakeholders holds the errors of the stakeholders that failed, usually a StakeholderDown,
+	// InvalidStakeholderSignature or InconsistentConfiguration
+	Stakeholders []error
+}
+
+func (e *InsufficientEndorsement) Error() string {
+	errs := make([]string, len(e.Stakeholders))
+
+	for i, err := range e.Stakeholders {
+		errs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("insufficient stakeholder endorsement of consortium %s: %d of %d stakeholders verified, "+
+		"errors are: [%s]", e.Domain, e.Endorsed, e.Required, strings.Join(errs, ", "))
+}
+
+// StakeholderDown is returned when a stakeholder's config servers or sidetree endpoints can't be reached
+type StakeholderDown struct {
+	// Domain is the stakeholder domain
+	Domain string
+	// Err is the error reaching the stakeholder
+	Err error
+}
+
+func (e *StakeholderDown) Error() string {
+	return fmt.Sprintf("stakeholder %s down: %s", e.Domain, e.Err)
+}
+
+// Unwrap returns the error reaching the stakeholder
+func (e *StakeholderDown) Unwrap() error {
+	return e.Err
+}
+
+// InvalidStakeholderSignature is returned when a stakeholder hasn't signed a config it's expected to, or its
+// signature fails to verify against the stakeholder's keys
+type InvalidStakeholderSignature struct {
+	// Domain is the stakeholder domain
+	Domain string
+	// Err is the signature verification error
+	Err error
+}
+
+func (e *InvalidStakeholderSignature) Error() string {
+	return fmt.Sprintf("invalid signature of stakeholder %s: %s", e.Domain, e.Err)
+}
+
+// Unwrap returns the signature verification error
+func (e *InvalidStakeholderSignature) Unwrap() error {
+	return e.Err
+}
+
+// InconsistentConfiguration is returned when a stakeholder's copy of a consortium file differs from the consortium's
+type InconsistentConfiguration struct {
+	// Domain is the stakeholder domain
+	Domain string
+	// Err describes the inconsistency
+	Err error
+}
+
+func (e *InconsistentConfiguration) Error() string {
+	return fmt.Sprintf("inconsistent configuration at stakeholder %s: %s", e.Domain, e.Err)
+}
+
+// Unwrap returns the error describing the inconsistency
+func (e *InconsistentConfiguration) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+func TestErrors(t *testing.T) {
+	cause := errors.New("cause")
+
+	t.Run("stakeholder errors", func(t *testing.T) {
+		tests := []struct {
+			err     error
+			message string
+		}{
+			{&ConsortiumUnavailable{Domain: "foo.bar", Err: cause}, "consortium foo.bar unavailable: cause"},
+			{&StakeholderDown{Domain: "bar.baz", Err: cause}, "stakeholder bar.baz down: cause"},
+			{&InvalidStakeholderSignature{Domain: "bar.baz", Err: cause}, "invalid signature of stakeholder bar.baz: cause"},
+			{
+				&InconsistentConfiguration{Domain: "bar.baz", Err: cause},
+				"inconsistent configuration at stakeholder bar.baz: cause",
+			},
+		}
+
+		for _, test := range tests {
+			require.Equal(t, test.message, test.err.Error())
+			require.True(t, errors.Is(test.err, cause))
+		}
+	})
+
+	t.Run("insufficient endorsement", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", &InsufficientEndorsement{
+			Domain:   "foo.bar",
+			Required: 2,
+			Endorsed: 0,
+			Stakeholders: []error{
+				&StakeholderDown{Domain: "bar.baz", Err: cause},
+				&InconsistentConfiguration{Domain: "baz.qux", Err: cause},
+			},
+		})
+
+		require.Equal(t, "wrapped: insufficient stakeholder endorsement of consortium foo.bar: 0 of 2 stakeholders "+
+			"verified, errors are: [stakeholder bar.baz down: cause, inconsistent configuration at stakeholder baz.qux: cause]",
+			err.Error())
+
+		var insufficient *InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Len(t, insufficient.Stakeholders, 2)
+
+		var down *StakeholderDown
+		require.True(t, errors.As(insufficient.Stakeholders[0], &down))
+		require.Equal(t, "bar.baz", down.Domain)
+
+		var inconsistent *InconsistentConfiguration
+		require.True(t, errors.As(insufficient.Stakeholders[1], &inconsistent))
+		require.Equal(t, "baz.qux", inconsistent.Domain)
+	})
+}
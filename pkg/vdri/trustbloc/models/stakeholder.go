@@ -49,6 +49,8 @@ type StakeholderSettings struct {
 type StakeholderFileData struct {
 	Config *Stakeholder
 	JWS    *jose.JSONWebSignature
+	// ServerMaxAge is the max-age the file was served with, if any, which bounds its cache lifetime
+	ServerMaxAge *time.Duration
 }
 
 // CacheLifetime returns the cache lifetime of the stakeholder file before it needs to be checked for an update
@@ -57,7 +59,7 @@ func (s StakeholderFileData) CacheLifetime() (time.Duration, error) {
 		return 0, fmt.Errorf("missing config object")
 	}
 
-	return time.Duration(s.Config.Policy.Cache.MaxAge) * time.Second, nil
+	return boundLifetime(time.Duration(s.Config.Policy.Cache.MaxAge)*time.Second, s.ServerMaxAge), nil
 }
 
 // ParseStakeholder parses a stakeholder config within a JWS
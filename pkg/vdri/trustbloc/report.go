@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"time"
+)
+
+// ConsortiumReport is the outcome of validating a consortium, with a breakdown for each stakeholder queried
+type ConsortiumReport struct {
+	// Domain is the consortium domain
+	Domain string
+	// Valid is whether the consortium validated
+	Valid bool
+	// Error is the validation error, if the consortium didn't validate
+	Error string
+	// Required is the number of stakeholders required to endorse the consortium
+	Required int
+	// Endorsed is the number of stakeholders verified to endorse the consortium
+	Endorsed int
+	// Lifetime is the duration after which the consortium config expires, if the consortium validated
+	Lifetime time.Duration
+	// Latency is the time taken to validate the consortium
+	Latency time.Duration
+	// Stakeholders holds the reports of the stakeholders queried, in the order they were queried
+	Stakeholders []*StakeholderReport
+}
+
+// consortiumValidation is a validation of a consortium, shared by the Reads needing it while it is in progress.
+// Its fields are set before done is closed.
+type consortiumValidation struct {
+	done   chan struct{}
+	report *ConsortiumReport
+	err    error
+	// expiry is the time the validation expires. A failed validation expires after minValidationLifetime.
+	expiry time.Time
+}
+
+// expired returns whether the validation completed, and either expired or, unless reuseFailure is true, failed
+func (cv *consortiumValidation) expired(now time.Time, reuseFailure bool) bool {
+	select {
+	case <-cv.done:
+		return (cv.err != nil && !reuseFailure) || !now.Before(cv.expiry)
+	default:
+		return false
+	}
+}
+
+// StakeholderReport is the outcome of verifying a stakeholder's endorsement of a consortium.
+// Checks are made in the order of the fields, and stop at the first failure.
+type StakeholderReport struct {
+	// Domain is the stakeholder domain
+	Domain string
+	// ConfigFetched is whether the stakeholder config was fetched
+	ConfigFetched bool
+	// DIDResolved is whether the stakeholder DID was resolved
+	DIDResolved bool
+	// ResolvedVia is the sidetree endpoint the stakeholder DID was resolved at
+	ResolvedVia string
+	// DIDConfigurationValid is whether the stakeholder's did-configuration links its DID to its domain
+	DIDConfigurationValid bool
+	// ConsortiumSignatureVerified is whether the stakeholder's signature on the consortium config verified
+	ConsortiumSignatureVerified bool
+	// SelfSignatureVerified is whether the stakeholder's signature on its own config verified
+	SelfSignatureVerified bool
+	// Latency is the time taken to fetch and verify the stakeholder
+	Latency time.Duration
+	// Error is the error that stopped verification, if the stakeholder wasn't verified
+	Error string
+}
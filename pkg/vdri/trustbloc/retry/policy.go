@@ -0,0 +1,147 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retry
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Policy decides whether and when a failed http request is retried.
+// A nil *Policy sends each request once.
+type Policy struct {
+	// MaxAttempts is the total number of attempts for a request, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	// MaxElapsed is the time budget for a request, including all its attempts and backoffs.
+	// A retry is not attempted if its backoff would exceed the budget. Zero means no budget.
+	MaxElapsed time.Duration
+	// InitialBackoff is the backoff before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after each retry. Values below 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, by which each backoff is randomly reduced
+	Jitter float64
+	// RetryableStatusClasses lists the classes of http status that are retried, e.g. 5 for all 5xx statuses
+	RetryableStatusClasses []int
+	// RetryableStatuses lists individual http statuses that are retried, e.g. 429
+	RetryableStatuses []int
+}
+
+// Default returns a policy making up to 3 attempts within 10 seconds, with exponential backoff starting at
+// 200ms and jitter, retrying connection errors, 5xx statuses, 408 Request Timeout and 429 Too Many Requests
+func Default() *Policy {
+	return &Policy{
+		MaxAttempts:            3,
+		MaxElapsed:             10 * time.Second,
+		InitialBackoff:         200 * time.Millisecond,
+		MaxBackoff:             2 * time.Second,
+		Multiplier:             2,
+		Jitter:                 0.5,
+		RetryableStatusClasses: []int{5},
+		RetryableStatuses:      []int{http.StatusRequestTimeout, http.StatusTooManyRequests},
+	}
+}
+
+// Do sends the request using the given client, retrying according to the policy.
+// The request must be safe to resend, so it must not have a body.
+// Returns the response or error of the last attempt. If the request's context is done while backing off,
+// the context's error is returned.
+func (p *Policy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if p == nil {
+		return client.Do(req)
+	}
+
+	ctx := req.Context()
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		res, err := client.Do(req.Clone(ctx))
+		if attempt >= p.MaxAttempts || !p.retryable(res, err) || ctx.Err() != nil {
+			return res, err
+		}
+
+		backoff := p.backoff(attempt)
+		if p.MaxElapsed > 0 && time.Since(start)+backoff > p.MaxElapsed {
+			return res, err
+		}
+
+		if err != nil {
+			log.Debugf("attempt %d of request to %s failed, retrying in %s: %s", attempt, req.URL, backoff, err)
+		} else {
+			log.Debugf("attempt %d of request to %s returned status %d, retrying in %s",
+				attempt, req.URL, res.StatusCode, backoff)
+
+			discardResponse(res)
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryable returns whether an attempt with the given result should be retried
+func (p *Policy) retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	for _, status := range p.RetryableStatuses {
+		if res.StatusCode == status {
+			return true
+		}
+	}
+
+	for _, class := range p.RetryableStatusClasses {
+		if res.StatusCode/100 == class {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the time to wait after the given attempt
+func (p *Policy) backoff(attempt int) time.Duration {
+	multiplier := math.Max(p.Multiplier, 1)
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		backoff = math.Min(backoff, float64(p.MaxBackoff))
+	}
+
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	backoff -= backoff * jitter * mathrand.Float64() // nolint: gosec
+
+	return time.Duration(backoff)
+}
+
+// discardResponse reads and closes the body of a response that won't be used, so its connection can be reused
+func discardResponse(res *http.Response) {
+	_, err := io.Copy(ioutil.Discard, res.Body)
+	if err != nil {
+		log.Debugf("failed to discard response body: %s", err)
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		log.Errorf("Failed to close response body: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer returns a server that responds with the given failure status to the first `failures` requests
+func flakyServer(failures int32, status int) (*httptest.Server, *int32) {
+	var count int32
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= failures {
+			w.WriteHeader(status)
+
+			return
+		}
+
+		fmt.Fprint(w, "ok")
+	}))
+
+	return serv, &count
+}
+
+func testPolicy() *Policy {
+	p := Default()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+
+	return p
+}
+
+func get(t *testing.T, ctx context.Context, p *Policy, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	res, err := p.Do(http.DefaultClient, req)
+	if err == nil {
+		t.Cleanup(func() {
+			require.NoError(t, res.Body.Close())
+		})
+	}
+
+	return res, err
+}
+
+func TestPolicy_Do(t *testing.T) {
+	t.Run("success after intermittent failures", func(t *testing.T) {
+		serv, count := flakyServer(2, http.StatusServiceUnavailable)
+		defer serv.Close()
+
+		res, err := get(t, context.Background(), testPolicy(), serv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.EqualValues(t, 3, atomic.LoadInt32(count))
+	})
+
+	t.Run("retryable status", func(t *testing.T) {
+		serv, count := flakyServer(1, http.StatusTooManyRequests)
+		defer serv.Close()
+
+		res, err := get(t, context.Background(), testPolicy(), serv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.EqualValues(t, 2, atomic.LoadInt32(count))
+	})
+
+	t.Run("non-retryable status", func(t *testing.T) {
+		serv, count := flakyServer(1, http.StatusNotFound)
+		defer serv.Close()
+
+		res, err := get(t, context.Background(), testPolicy(), serv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.EqualValues(t, 1, atomic.LoadInt32(count))
+	})
+
+	t.Run("attempts exhausted returns last response", func(t *testing.T) {
+		serv, count := flakyServer(5, http.StatusInternalServerError)
+		defer serv.Close()
+
+		res, err := get(t, context.Background(), testPolicy(), serv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		require.EqualValues(t, 3, atomic.LoadInt32(count))
+	})
+
+	t.Run("time budget exhausted", func(t *testing.T) {
+		serv, count := flakyServer(5, http.StatusInternalServerError)
+		defer serv.Close()
+
+		p := testPolicy()
+		p.InitialBackoff = time.Second
+		p.MaxBackoff = 0
+		p.Jitter = 0
+		p.MaxElapsed = 100 * time.Millisecond
+
+		res, err := get(t, context.Background(), p, serv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		require.EqualValues(t, 1, atomic.LoadInt32(count))
+	})
+
+	t.Run("connection error", func(t *testing.T) {
+		_, err := get(t, context.Background(), testPolicy(), "http://0.0.0.0:0")
+		require.Error(t, err)
+	})
+
+	t.Run("context canceled while backing off", func(t *testing.T) {
+		serv, _ := flakyServer(5, http.StatusInternalServerError)
+		defer serv.Close()
+
+		p := testPolicy()
+		p.InitialBackoff = time.Minute
+		p.MaxBackoff = 0
+		p.MaxElapsed = 0
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := get(t, ctx, p, serv.URL)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("nil policy sends once", func(t *testing.T) {
+		serv, count := flakyServer(1, http.StatusInternalServerError)
+		defer serv.Close()
+
+		var p *Policy
+
+		res, err := get(t, context.Background(), p, serv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		require.EqualValues(t, 1, atomic.LoadInt32(count))
+	})
+}
+
+func TestPolicy_backoff(t *testing.T) {
+	p := &Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+
+	require.Equal(t, 100*time.Millisecond, p.backoff(1))
+	require.Equal(t, 200*time.Millisecond, p.backoff(2))
+	require.Equal(t, 300*time.Millisecond, p.backoff(3))
+
+	p.Multiplier = 0
+	require.Equal(t, 100*time.Millisecond, p.backoff(3))
+
+	p.Jitter = 0.5
+
+	for i := 0; i < 10; i++ {
+		backoff := p.backoff(1)
+		require.True(t, backoff > 50*time.Millisecond && backoff <= 100*time.Millisecond)
+	}
+}
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package staticselection
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"math/big"
@@ -15,8 +16,7 @@ import (
 )
 
 type config interface {
-	GetConsortium(url, domain string) (*models.ConsortiumFileData, error)
-	GetStakeholder(url, domain string) (*models.StakeholderFileData, error)
+	GetConsortiumWithContext(ctx context.Context, url, domain string) (*models.ConsortiumFileData, error)
 }
 
 // SelectionService implements a static selection service
@@ -29,10 +29,16 @@ func NewService(config config) *SelectionService {
 	return &SelectionService{config: config}
 }
 
-// SelectEndpoints select a random endpoint for each of N random stakeholders in a consortium
-// Where N is the numQueries parameter in the consortium's policy configuration
+// SelectEndpoints calls SelectEndpointsWithContext with a background context
 func (ds *SelectionService) SelectEndpoints(consortiumDomain string, endpoints []*models.Endpoint) ([]*models.Endpoint, error) { // nolint: lll
-	consortiumData, err := ds.config.GetConsortium(consortiumDomain, consortiumDomain)
+	return ds.SelectEndpointsWithContext(context.Background(), consortiumDomain, endpoints)
+}
+
+// SelectEndpointsWithContext select a random endpoint for each of N random stakeholders in a consortium
+// Where N is the numQueries parameter in the consortium's policy configuration
+func (ds *SelectionService) SelectEndpointsWithContext(ctx context.Context, consortiumDomain string,
+	endpoints []*models.Endpoint) ([]*models.Endpoint, error) {
+	consortiumData, err := ds.config.GetConsortiumWithContext(ctx, consortiumDomain, consortiumDomain)
 	if err != nil {
 		return nil, fmt.Errorf("getting consortium: %w", err)
 	}
@@ -0,0 +1,186 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/resolve"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	didLDJSON = "application/did+ld+json"
+
+	// defaultMultiHashAlgorithm is the multihash code of SHA2-256, which the sidetree client uses by default
+	defaultMultiHashAlgorithm = 18
+)
+
+// sidetreeClientFunc adapts a function to the sidetreeClient interface
+type sidetreeClientFunc func(ctx context.Context, opts ...create.Option) (*docdid.DocResolution, error)
+
+// CreateDIDWithContext calls f(ctx, opts...)
+func (f sidetreeClientFunc) CreateDIDWithContext(ctx context.Context, opts ...create.Option,
+) (*docdid.DocResolution, error) {
+	return f(ctx, opts...)
+}
+
+// createDID sends the create request for the create options to the first sidetree endpoint, as the sidetree client
+// does, but using the VDRI's http client and the given context. The sidetree client replaces the transport of its
+// http client with its own and sends requests without a context, so it can't be used.
+func (v *VDRI) createDID(ctx context.Context, opts ...create.Option) (*docdid.DocResolution, error) {
+	docResolution, _, _, err := v.sendCreate(ctx, opts...)
+
+	return docResolution, err
+}
+
+// sendCreate validates the create options and sends their create request to the first sidetree endpoint, returning
+// the resolution of the created DID, the options and the create request
+func (v *VDRI) sendCreate(ctx context.Context, opts ...create.Option,
+) (*docdid.DocResolution, *create.Opts, []byte, error) {
+	createDIDOpts := &create.Opts{MultiHashAlgorithm: defaultMultiHashAlgorithm}
+
+	for _, opt := range opts {
+		opt(createDIDOpts)
+	}
+
+	switch {
+	case createDIDOpts.RecoveryPublicKey == nil:
+		return nil, nil, nil, fmt.Errorf("recovery public key is required")
+	case createDIDOpts.UpdatePublicKey == nil:
+		return nil, nil, nil, fmt.Errorf("update public key is required")
+	case createDIDOpts.GetEndpoints == nil:
+		return nil, nil, nil, fmt.Errorf("sidetree get endpoints func is required")
+	}
+
+	endpoints, err := createDIDOpts.GetEndpoints()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(endpoints) == 0 {
+		return nil, nil, nil, errors.New("list of endpoints is empty")
+	}
+
+	req, err := buildCreateRequest(createDIDOpts)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to build sidetree request: %w", err)
+	}
+
+	docResolution, err := v.sendCreateRequest(ctx, endpoints[0], req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return docResolution, createDIDOpts, req, nil
+}
+
+// sidetreeResolver resolves DIDs at a sidetree endpoint, as the http binding VDR does, but using the given http client
+// and aborting requests when the context is done. The http binding VDR can't be given an http client or a context,
+// and reports deactivated DIDs as unsupported responses, so it can't be used.
+type sidetreeResolver struct {
+	endpointURL string
+	client      *http.Client
+}
+
+// newSidetreeResolver creates a resolver for the sidetree endpoint at the given url
+func newSidetreeResolver(endpointURL string, client *http.Client) (*sidetreeResolver, error) {
+	_, err := url.ParseRequestURI(endpointURL)
+	if err != nil {
+		return nil, fmt.Errorf("base URL invalid: %w", err)
+	}
+
+	return &sidetreeResolver{endpointURL: endpointURL, client: client}, nil
+}
+
+// Read calls ReadWithContext with a background context
+func (r *sidetreeResolver) Read(didID string, opts ...resolve.Option) (*docdid.DocResolution, error) {
+	return r.ReadWithContext(context.Background(), didID, opts...)
+}
+
+// ReadWithContext resolves the DID at the sidetree endpoint. The request is aborted when the context is done.
+func (r *sidetreeResolver) ReadWithContext(ctx context.Context, didID string, _ ...resolve.Option,
+) (*docdid.DocResolution, error) {
+	reqURL, err := url.ParseRequestURI(r.endpointURL)
+	if err != nil {
+		return nil, fmt.Errorf("url parse request uri failed: %w", err)
+	}
+
+	reqURL.Path = path.Join(reqURL.Path, didID)
+
+	data, err := r.resolveDID(ctx, reqURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, vdrapi.ErrNotFound
+	}
+
+	docResolution, err := docdid.ParseDocumentResolution(data)
+	if err == nil {
+		return docResolution, nil
+	}
+
+	if !errors.Is(err, docdid.ErrDIDDocumentNotExist) {
+		return nil, err
+	}
+
+	didDoc, err := docdid.ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &docdid.DocResolution{DIDDocument: didDoc}, nil
+}
+
+// resolveDID fetches the resolution of a DID from the given url
+func (r *sidetreeResolver) resolveDID(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP create get request failed: %w", err)
+	}
+
+	req.Header.Set("Accept", didLDJSON)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP Get request failed: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			log.Warnf("failed to close response body: %s", e)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body failed: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK && strings.Contains(resp.Header.Get("Content-type"), didLDJSON):
+		return body, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("DID does not exist for request: %s: %w", uri, vdrapi.ErrNotFound)
+	case resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%w for request: %s", ErrDIDDeactivated, uri)
+	default:
+		return nil, fmt.Errorf("unsupported response from DID resolver [%v] header [%s] body [%s]",
+			resp.StatusCode, resp.Header.Get("Content-type"), body)
+	}
+}
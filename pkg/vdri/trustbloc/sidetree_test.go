@@ -0,0 +1,273 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustbloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVDRI_createDID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serv := createServer(t, nil)
+		defer serv.Close()
+
+		v := New(WithAuthToken("tk1"))
+
+		docResolution, err := v.sidetreeClient.CreateDIDWithContext(context.Background(), append(createOpts(t),
+			create.WithEndpoints(func() ([]string, error) {
+				return []string{serv.URL}, nil
+			}))...)
+		require.NoError(t, err)
+		require.Contains(t, docResolution.DIDDocument.ID, testDIDPrefix)
+	})
+
+	t.Run("failure - missing options", func(t *testing.T) {
+		v := New()
+
+		_, err := v.createDID(context.Background())
+		require.EqualError(t, err, "recovery public key is required")
+
+		_, err = v.createDID(context.Background(), create.WithRecoveryPublicKey([]byte("key")))
+		require.EqualError(t, err, "update public key is required")
+
+		_, err = v.createDID(context.Background(), create.WithRecoveryPublicKey([]byte("key")),
+			create.WithUpdatePublicKey([]byte("key")))
+		require.EqualError(t, err, "sidetree get endpoints func is required")
+	})
+
+	t.Run("failure - endpoints", func(t *testing.T) {
+		v := New()
+
+		_, err := v.createDID(context.Background(), append(createOpts(t), create.WithEndpoints(func() ([]string, error) {
+			return nil, fmt.Errorf("no endpoints")
+		}))...)
+		require.EqualError(t, err, "no endpoints")
+
+		_, err = v.createDID(context.Background(), append(createOpts(t), create.WithEndpoints(func() ([]string, error) {
+			return nil, nil
+		}))...)
+		require.EqualError(t, err, "list of endpoints is empty")
+	})
+
+	t.Run("failure - canceled context aborts the request", func(t *testing.T) {
+		release := make(chan struct{})
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer serv.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := New().createDID(ctx, append(createOpts(t), create.WithEndpoints(func() ([]string, error) {
+			return []string{serv.URL}, nil
+		}))...)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("failure - invalid key", func(t *testing.T) {
+		v := New()
+
+		_, err := v.createDID(context.Background(), create.WithRecoveryPublicKey([]byte("key")),
+			create.WithUpdatePublicKey([]byte("key")), create.WithEndpoints(func() ([]string, error) {
+				return []string{"http://localhost"}, nil
+			}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to build sidetree request")
+	})
+}
+
+// recordedResponse is a response recorded from the resolve handler of sidetree-core-go's REST API
+type recordedResponse struct {
+	status      int
+	contentType string
+	body        string
+}
+
+// recordedResolveResponses are the responses of sidetree-core-go's resolve handler for a published DID, an unknown
+// DID and a deactivated DID
+// nolint: gochecknoglobals
+var recordedResolveResponses = map[string]recordedResponse{
+	"published": {http.StatusOK, "application/did+ld+json", `{"@context":"https://www.w3.org/ns/did-resolution/v1",` +
+		`"didDocument":{"@context":["https://www.w3.org/ns/did/v1"],"id":"did:trustbloc:testnet:123"},` +
+		`"methodMetadata":{"published":true,"recoveryCommitment":"rc","updateCommitment":"uc"}}` + "\n"},
+	"unknown":     {http.StatusNotFound, "text/plain", "document not found"},
+	"deactivated": {http.StatusGone, "text/plain", "document is no longer available"},
+}
+
+func TestSidetreeResolver_RecordedResponses(t *testing.T) {
+	var recorded recordedResponse
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", recorded.contentType)
+		w.WriteHeader(recorded.status)
+		fmt.Fprint(w, recorded.body)
+	}))
+	defer serv.Close()
+
+	resolver, err := newSidetreeResolver(serv.URL+"/sidetree/0.0.1/identifiers", http.DefaultClient)
+	require.NoError(t, err)
+
+	t.Run("test published DID", func(t *testing.T) {
+		recorded = recordedResolveResponses["published"]
+
+		resolution, err := resolver.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:123", resolution.DIDDocument.ID)
+	})
+
+	t.Run("test unknown DID", func(t *testing.T) {
+		recorded = recordedResolveResponses["unknown"]
+
+		_, err := resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, vdrapi.ErrNotFound))
+		require.False(t, errors.Is(err, ErrDIDDeactivated))
+	})
+
+	t.Run("test deactivated DID", func(t *testing.T) {
+		recorded = recordedResolveResponses["deactivated"]
+
+		_, err := resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrDIDDeactivated))
+		require.False(t, errors.Is(err, vdrapi.ErrNotFound))
+	})
+}
+
+func TestSidetreeResolver_Read(t *testing.T) {
+	const docResolution = `{"@context":"https://www.w3.org/ns/did-resolution/v1",` +
+		`"didDocument":{"id":"did:trustbloc:testnet:123","@context":"https://www.w3.org/ns/did/v1"}}`
+
+	t.Run("success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/sidetree/0.0.1/identifiers/did:trustbloc:testnet:123", r.URL.Path)
+			require.Equal(t, didLDJSON, r.Header.Get("Accept"))
+			require.Equal(t, "Bearer tk1", r.Header.Get("Authorization"))
+			require.Equal(t, "resolver/1.0", r.Header.Get("User-Agent"))
+			require.Equal(t, "a", r.Header.Get("X-Api-Key"))
+
+			w.Header().Set("Content-Type", didLDJSON)
+			fmt.Fprint(w, docResolution)
+		}))
+		defer serv.Close()
+
+		v := New(WithAuthToken("tk1"), WithUserAgent("resolver/1.0"), WithHTTPHeader("X-Api-Key", "a"))
+
+		resolver, err := v.getHTTPVDRI(serv.URL + "/sidetree/0.0.1/identifiers")
+		require.NoError(t, err)
+
+		resolution, err := resolver.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:123", resolution.DIDDocument.ID)
+	})
+
+	t.Run("success - DID document", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", didLDJSON)
+			fmt.Fprint(w, `{"id":"did:trustbloc:testnet:123","@context":"https://www.w3.org/ns/did/v1"}`)
+		}))
+		defer serv.Close()
+
+		resolver, err := newSidetreeResolver(serv.URL, http.DefaultClient)
+		require.NoError(t, err)
+
+		resolution, err := resolver.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:123", resolution.DIDDocument.ID)
+	})
+
+	t.Run("failure - invalid url", func(t *testing.T) {
+		_, err := newSidetreeResolver("not a url", http.DefaultClient)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "base URL invalid")
+	})
+
+	t.Run("failure - responses", func(t *testing.T) {
+		status := http.StatusNotFound
+		body := ""
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", didLDJSON)
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}))
+		defer serv.Close()
+
+		resolver, err := newSidetreeResolver(serv.URL, http.DefaultClient)
+		require.NoError(t, err)
+
+		_, err = resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "DID does not exist")
+		require.True(t, errors.Is(err, vdrapi.ErrNotFound))
+
+		status = http.StatusGone
+
+		_, err = resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrDIDDeactivated))
+		require.False(t, errors.Is(err, vdrapi.ErrNotFound))
+
+		status = http.StatusInternalServerError
+
+		_, err = resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported response from DID resolver [500]")
+
+		status = http.StatusOK
+
+		_, err = resolver.Read("did:trustbloc:testnet:123")
+		require.True(t, errors.Is(err, vdrapi.ErrNotFound))
+
+		body = "{"
+
+		_, err = resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+	})
+
+	t.Run("failure - canceled context aborts the request", func(t *testing.T) {
+		release := make(chan struct{})
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer serv.Close()
+		defer close(release)
+
+		resolver, err := newSidetreeResolver(serv.URL, http.DefaultClient)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = resolver.ReadWithContext(ctx, "did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("failure - server unreachable", func(t *testing.T) {
+		resolver, err := newSidetreeResolver("http://0.0.0.0:0", http.DefaultClient)
+		require.NoError(t, err)
+
+		_, err = resolver.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "HTTP Get request failed")
+	})
+}
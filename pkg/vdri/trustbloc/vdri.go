@@ -6,7 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package trustbloc
 
 import (
-	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"encoding/json"
@@ -14,54 +14,83 @@ import (
 	"fmt"
 	"math/big"
 	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/hyperledger/aries-framework-go-ext/component/vdr/sidetree"
 	docdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
 	vdrdoc "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/doc"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/resolve"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
-	"github.com/hyperledger/aries-framework-go/pkg/vdr/httpbinding"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/filecacheconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/httpconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/memorycacheconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/signatureconfig"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/stakeholdervalidationconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/updatevalidationconfig"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/config/verifyingconfig"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/didconfiguration"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/discovery/staticdiscovery"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/endpoint"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/historyhash"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/httpclient"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/longform"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/selection/staticselection"
 )
 
 type configService interface {
-	GetConsortium(string, string) (*models.ConsortiumFileData, error)
-	GetStakeholder(string, string) (*models.StakeholderFileData, error)
-	GetSidetreeConfig(url string) (*models.SidetreeConfig, error)
+	GetConsortiumWithContext(context.Context, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderWithContext(context.Context, string, string) (*models.StakeholderFileData, error)
+	GetSidetreeConfigWithContext(ctx context.Context, url string) (*models.SidetreeConfig, error)
+}
+
+// fetchingConfigService fetches configs and their history, without verifying them
+type fetchingConfigService interface {
+	configService
+	GetConsortiumHistoryWithContext(context.Context, string, string, string) (*models.ConsortiumFileData, error)
+	GetStakeholderHistoryWithContext(context.Context, string, string, string) (*models.StakeholderFileData, error)
 }
 
 type sidetreeClient interface {
-	CreateDID(opts ...create.Option) (*docdid.DocResolution, error)
+	CreateDIDWithContext(ctx context.Context, opts ...create.Option) (*docdid.DocResolution, error)
 }
 
 type endpointService interface {
-	GetEndpoints(domain string) ([]*models.Endpoint, error)
+	GetEndpointsWithContext(ctx context.Context, domain string) ([]*models.Endpoint, error)
+}
+
+// endpointServiceFunc adapts a function to the endpointService interface
+type endpointServiceFunc func(ctx context.Context, domain string) ([]*models.Endpoint, error)
+
+// GetEndpointsWithContext calls f(ctx, domain)
+func (f endpointServiceFunc) GetEndpointsWithContext(ctx context.Context, domain string) ([]*models.Endpoint, error) {
+	return f(ctx, domain)
 }
 
 type didConfigService interface {
-	VerifyStakeholder(domain string, doc *docdid.Doc) error
+	VerifyStakeholderWithContext(ctx context.Context, domain string, doc *docdid.Doc) error
 }
 
 type vdri interface {
-	Build(keyManager kms.KeyManager, opts ...create.Option) (*docdid.DocResolution, error)
 	Read(id string, opts ...resolve.Option) (*docdid.DocResolution, error)
 }
 
+// contextVDRI is a vdri that can resolve with a context, so a resolution is aborted when the context is done
+type contextVDRI interface {
+	ReadWithContext(ctx context.Context, id string, opts ...resolve.Option) (*docdid.DocResolution, error)
+}
+
 // VDRI bloc
 type VDRI struct {
 	resolverURL      string
@@ -69,18 +98,90 @@ type VDRI struct {
 	configService    configService
 	endpointService  endpointService
 	didConfigService didConfigService
-	getHTTPVDRI      func(url string) (vdri, error) // needed for unit test
+	getHTTPVDRI      func(url string) (vdri, error)        // needed for unit test
+	canonicalize     func(doc *docdid.Doc) ([]byte, error) // needed for unit test
 	tlsConfig        *tls.Config
 	authToken        string
-
-	validatedConsortium map[string]bool
+	httpClientOpts   []httpclient.Option
+	cacheDir         string
+	cacheDirMaxStale time.Duration
+	maxStaleConfig   time.Duration
+	configFailureTTL time.Duration
+	memoryCache      *memorycacheconfig.ConfigService
+	fileCache        *filecacheconfig.ConfigService
+	httpClient       *http.Client
+	historyHash      *historyhash.Registry
+	retryPolicy      *retry.Policy
+
+	// validatedConsortium maps the domains of consortia to their last validation, or the validation in progress
+	validatedConsortium map[string]*consortiumValidation
+
+	consensusPolicy consensus.Policy
+	endpointTimeout time.Duration
+	readTimeout     time.Duration
+
+	trustedStakeholder          string
+	validatedTrustedStakeholder string
+
+	// validatedLock guards validatedConsortium and validatedTrustedStakeholder
+	validatedLock sync.RWMutex
 
 	enableSignatureVerification bool
 
 	useUpdateValidation     bool
+	useLastValidConsortium  bool
 	updateValidationService *updatevalidationconfig.ConfigService
 	genesisFiles            []genesisFileData
+	genesisErr              error
 	sidetreeClient          sidetreeClient
+
+	publishTimeout      time.Duration
+	publishPollInterval time.Duration
+
+	useLongFormDID   bool
+	longFormResolver *longform.Resolver
+}
+
+// fileCacheConfig wraps the fetching config service in the on-disk cache if a cache directory is set. The on-disk
+// cache is below the verifying config services, so configs loaded from disk are verified as fetched ones are.
+// If the cache directory can't be used, configs are only cached in memory.
+func (v *VDRI) fileCacheConfig(fetching fetchingConfigService) fetchingConfigService {
+	if v.cacheDir == "" {
+		return fetching
+	}
+
+	var opts []filecacheconfig.Option
+	if v.cacheDirMaxStale > 0 {
+		opts = append(opts, filecacheconfig.WithMaxStale(v.cacheDirMaxStale))
+	}
+
+	fileCache, err := filecacheconfig.NewService(fetching, v.cacheDir, opts...)
+	if err != nil {
+		log.Warnf("caching configs in memory only: %s", err)
+
+		return fetching
+	}
+
+	v.fileCache = fileCache
+
+	return fileCache
+}
+
+// cacheConfig wraps the verifying config service in the in-memory cache. A config fetch shared by concurrent reads
+// is bounded by the read timeout, as no read waits for it longer.
+func (v *VDRI) cacheConfig(verified configService) *memorycacheconfig.ConfigService {
+	opts := []memorycacheconfig.Option{
+		memorycacheconfig.WithStaleWhileRevalidate(v.maxStaleConfig),
+		memorycacheconfig.WithNegativeCaching(v.configFailureTTL),
+	}
+
+	if v.readTimeout > 0 {
+		opts = append(opts, memorycacheconfig.WithLoadTimeout(v.readTimeout))
+	}
+
+	v.memoryCache = memorycacheconfig.NewService(verified, opts...)
+
+	return v.memoryCache
 }
 
 type genesisFileData struct {
@@ -89,42 +190,97 @@ type genesisFileData struct {
 	fileData []byte
 }
 
+const (
+	defaultEndpointTimeout     = 10 * time.Second
+	defaultReadTimeout         = 30 * time.Second
+	defaultPublishPollInterval = time.Second
+
+	// minValidationLifetime is the least time a consortium validation is used for, so a consortium whose config
+	// isn't cached isn't validated by every Read
+	minValidationLifetime = 5 * time.Second
+	// validationTimeout bounds a shared consortium validation, which doesn't use the context of any of the Reads
+	// waiting for it
+	validationTimeout = 30 * time.Second
+)
+
+// ErrPublishTimeout is returned by Build when the created DID isn't published before the wait timeout elapses
+var ErrPublishTimeout = errors.New("DID not published before timeout")
+
+// ErrDIDDeactivated is matched with errors.Is by the error of a Read of a DID which every endpoint reports as
+// deactivated
+var ErrDIDDeactivated = errors.New("DID is deactivated")
+
+// errEndpointTimeout is the error of an endpoint that doesn't respond before the resolution deadline
+var errEndpointTimeout = errors.New("timed out")
+
 // New creates new bloc vdri
 func New(opts ...Option) *VDRI {
-	v := &VDRI{}
+	v := &VDRI{endpointTimeout: defaultEndpointTimeout, readTimeout: defaultReadTimeout, retryPolicy: retry.Default()}
 
 	for _, opt := range opts {
 		opt(v)
 	}
 
-	v.sidetreeClient = sidetree.New(sidetree.WithAuthToken(v.authToken), sidetree.WithTLSConfig(v.tlsConfig))
+	// every outbound request is sent with the same client, so it has the same auth token, headers and proxy
+	v.httpClient = httpclient.New(append([]httpclient.Option{
+		httpclient.WithTLSConfig(v.tlsConfig), httpclient.WithAuthToken(v.authToken),
+	}, v.httpClientOpts...)...)
+
+	v.sidetreeClient = sidetreeClientFunc(v.createDID)
 
 	v.getHTTPVDRI = func(url string) (vdri, error) {
-		return httpbinding.New(url,
-			httpbinding.WithTLSConfig(v.tlsConfig), httpbinding.WithResolveAuthToken(v.authToken))
+		return newSidetreeResolver(url, v.httpClient)
+	}
+
+	v.canonicalize = canonicalizeDoc
+
+	if v.longFormResolver == nil {
+		v.longFormResolver = longform.NewResolver()
 	}
 
-	configService := httpconfig.NewService(httpconfig.WithTLSConfig(v.tlsConfig))
+	configOpts := []httpconfig.Option{
+		httpconfig.WithHTTPClient(v.httpClient), httpconfig.WithRetryPolicy(v.retryPolicy),
+	}
+
+	if v.historyHash != nil {
+		configOpts = append(configOpts, httpconfig.WithHistoryHashRegistry(v.historyHash))
+	}
+
+	configService := v.fileCacheConfig(httpconfig.NewService(configOpts...))
 
 	switch {
+	case v.trustedStakeholder != "":
+		// the trusted stakeholder is verified directly, without bootstrapping from its consortium
+		v.configService = v.cacheConfig(configService)
+		v.genesisFiles = nil
 	case v.useUpdateValidation:
 		verifyingService := signatureconfig.NewService(verifyingconfig.NewService(configService))
-		v.updateValidationService = updatevalidationconfig.NewService(verifyingService)
-		v.configService = memorycacheconfig.NewService(v.updateValidationService)
+		v.updateValidationService = updatevalidationconfig.NewService(verifyingService,
+			updatevalidationconfig.WithLastValidFallback(v.useLastValidConsortium))
+		v.configService = v.cacheConfig(stakeholdervalidationconfig.NewService(v.updateValidationService,
+			stakeholdervalidationconfig.WithLastValidFallback(v.useLastValidConsortium)))
 	case v.enableSignatureVerification:
 		verifyingService := signatureconfig.NewService(verifyingconfig.NewService(configService))
-		v.configService = memorycacheconfig.NewService(verifyingService)
+		v.configService = v.cacheConfig(verifyingService)
 	default:
-		v.configService = memorycacheconfig.NewService(verifyingconfig.NewService(configService))
+		v.configService = v.cacheConfig(verifyingconfig.NewService(configService))
+	}
+
+	if v.trustedStakeholder != "" {
+		v.endpointService = endpointServiceFunc(v.getTrustedStakeholderEndpoints)
+	} else {
+		v.endpointService = endpoint.NewService(
+			staticdiscovery.NewService(v.configService),
+			staticselection.NewService(v.configService))
 	}
 
-	v.endpointService = endpoint.NewService(
-		staticdiscovery.NewService(v.configService),
-		staticselection.NewService(v.configService))
+	v.didConfigService = didconfiguration.NewService(didconfiguration.WithHTTPClient(v.httpClient),
+		didconfiguration.WithRetryPolicy(v.retryPolicy))
 
-	v.didConfigService = didconfiguration.NewService(didconfiguration.WithTLSConfig(v.tlsConfig))
+	v.validatedConsortium = map[string]*consortiumValidation{}
 
-	v.validatedConsortium = map[string]bool{}
+	// a genesis file that fails to load fails every Read
+	v.genesisErr = v.loadGenesisFiles()
 
 	return v
 }
@@ -144,8 +300,15 @@ func (v *VDRI) Store(doc *docdid.Doc, by *[]vdrdoc.ModifiedBy) error {
 	return nil
 }
 
-// Build did doc
+// Build calls BuildWithContext with a background context
 func (v *VDRI) Build(keyManager kms.KeyManager, opts ...create.Option) (*docdid.DocResolution, error) {
+	return v.BuildWithContext(context.Background(), keyManager, opts...)
+}
+
+// BuildWithContext creates a DID at the first endpoint of the VDRI's consortium. Requests are aborted when the context
+// is done.
+func (v *VDRI) BuildWithContext(ctx context.Context, _ kms.KeyManager, opts ...create.Option,
+) (*docdid.DocResolution, error) {
 	createDIDOpts := &create.Opts{}
 
 	// Apply options
@@ -157,7 +320,7 @@ func (v *VDRI) Build(keyManager kms.KeyManager, opts ...create.Option) (*docdid.
 		createDIDOpts.GetEndpoints = func() ([]string, error) {
 			var result []string
 
-			endpoints, err := v.endpointService.GetEndpoints(v.domain)
+			endpoints, err := v.endpointService.GetEndpointsWithContext(ctx, v.domain)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get endpoints: %w", err)
 			}
@@ -178,7 +341,7 @@ func (v *VDRI) Build(keyManager kms.KeyManager, opts ...create.Option) (*docdid.
 			return nil, err
 		}
 
-		sidetreeConfig, err := v.configService.GetSidetreeConfig(endpoints[0])
+		sidetreeConfig, err := v.configService.GetSidetreeConfigWithContext(ctx, endpoints[0])
 		if err != nil {
 			return nil, err
 		}
@@ -186,9 +349,56 @@ func (v *VDRI) Build(keyManager kms.KeyManager, opts ...create.Option) (*docdid.
 		opts = append(opts, create.WithMultiHashAlgorithm(sidetreeConfig.MultiHashAlgorithm))
 	}
 
-	return v.sidetreeClient.CreateDID(opts...)
+	if v.useLongFormDID {
+		return v.buildLongFormDID(ctx, opts...)
+	}
+
+	docResolution, err := v.sidetreeClient.CreateDIDWithContext(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.waitUntilPublished(ctx, docResolution)
+}
+
+// waitUntilPublished polls the resolution of a created DID until it resolves, if the VDRI waits for created DIDs to be
+// published. Returns the resolution of the published DID, or the given resolution if the VDRI doesn't wait.
+func (v *VDRI) waitUntilPublished(ctx context.Context, created *docdid.DocResolution,
+) (*docdid.DocResolution, error) {
+	if v.publishTimeout <= 0 {
+		return created, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.publishTimeout)
+	defer cancel()
+
+	interval := v.publishPollInterval
+	if interval <= 0 {
+		interval = defaultPublishPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	id := created.DIDDocument.ID
+
+	for {
+		docResolution, err := v.ReadWithContext(ctx, id)
+		if err == nil {
+			return docResolution, nil
+		}
+
+		log.Debugf("created did %s not resolved yet: %s", id, err)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %w", id, ErrPublishTimeout)
+		}
+	}
 }
 
+// loadGenesisFiles adds the genesis files to the update validation service. Called once, by New.
 func (v *VDRI) loadGenesisFiles() error {
 	for _, genesisFile := range v.genesisFiles {
 		err := v.updateValidationService.AddGenesisFile(genesisFile.url, genesisFile.domain, genesisFile.fileData)
@@ -202,13 +412,25 @@ func (v *VDRI) loadGenesisFiles() error {
 	return nil
 }
 
-func (v *VDRI) sidetreeResolve(url, did string, opts ...resolve.Option) (*docdid.DocResolution, error) {
+func (v *VDRI) sidetreeResolve(ctx context.Context, url, did string, opts ...resolve.Option,
+) (*docdid.DocResolution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to resolve did: %w", err)
+	}
+
 	resolver, err := v.getHTTPVDRI(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new sidetree vdri: %w", err)
 	}
 
-	docResolution, err := resolver.Read(did, opts...)
+	var docResolution *docdid.DocResolution
+
+	if r, ok := resolver.(contextVDRI); ok {
+		docResolution, err = r.ReadWithContext(ctx, did, opts...)
+	} else {
+		docResolution, err = resolver.Read(did, opts...)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve did: %w", err)
 	}
@@ -221,19 +443,78 @@ const (
 	domainDIDPart             = 2
 )
 
-func (v *VDRI) Read(did string, opts ...resolve.Option) (*docdid.DocResolution, error) { //nolint: gocyclo,funlen
-	err := v.loadGenesisFiles()
-	if err != nil {
-		return nil, fmt.Errorf("invalid genesis file: %w", err)
+// Read calls ReadWithContext with a background context
+func (v *VDRI) Read(did string, opts ...resolve.Option) (*docdid.DocResolution, error) {
+	return v.ReadWithContext(context.Background(), did, opts...)
+}
+
+// ReadWithContext resolves the DID at the endpoints of the DID's consortium.
+// If the context is done before the endpoints respond, the resolution fails with the context's error.
+// Long-form DIDs that the endpoints don't know, or that can't be resolved because every endpoint is unreachable,
+// are resolved from their initial state. Deactivated DIDs, resolutions that don't reach consensus and consortium
+// validation failures are returned as errors.
+func (v *VDRI) ReadWithContext(ctx context.Context, did string,
+	opts ...resolve.Option) (*docdid.DocResolution, error) {
+	docResolution, err := v.readAtEndpoints(ctx, did, opts...)
+	if err == nil || ctx.Err() != nil || !longform.IsLongForm(did) || !v.unresolvedAtEndpoints(err) {
+		return docResolution, err
+	}
+
+	log.Debugf("resolving long-form did %s from its initial state: %s", did, err)
+
+	docResolution, e := v.longFormResolver.Read(did)
+	if e != nil {
+		return nil, fmt.Errorf("%w (failed to resolve from initial state: %s)", err, e)
+	}
+
+	return docResolution, nil
+}
+
+// unresolvedAtEndpoints returns whether a failed resolution failed only because the endpoints don't know the DID or
+// can't be reached
+func (v *VDRI) unresolvedAtEndpoints(err error) bool {
+	var mismatch *consensus.MismatchError
+	if errors.As(err, &mismatch) {
+		if len(mismatch.Agreed) > 0 || len(mismatch.Disagreed) > 0 {
+			return false
+		}
+
+		for _, e := range mismatch.Errors {
+			if !notFoundOrUnreachable(e) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	// without a resolver url, other errors come from validating the consortium or discovering its endpoints
+	return v.resolverURL != "" && notFoundOrUnreachable(err)
+}
+
+// notFoundOrUnreachable returns whether an endpoint's resolution error means the endpoint doesn't know the DID or
+// can't be reached
+func notFoundOrUnreachable(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, vdrapi.ErrNotFound) || errors.Is(err, errEndpointTimeout) || errors.As(err, &netErr)
+}
+
+func (v *VDRI) readAtEndpoints(ctx context.Context, did string, //nolint: gocyclo,funlen
+	opts ...resolve.Option) (*docdid.DocResolution, error) {
+	start := time.Now()
+
+	if v.genesisErr != nil {
+		return nil, fmt.Errorf("invalid genesis file: %w", v.genesisErr)
 	}
 
 	if v.resolverURL != "" {
-		return v.sidetreeResolve(v.resolverURL, did, opts...)
+		return v.sidetreeResolve(ctx, v.resolverURL, did, opts...)
 	}
 
 	// parse did
 	didParts := strings.Split(did, ":")
-	if len(didParts) != expectedTrustblocDIDParts {
+	if len(didParts) != expectedTrustblocDIDParts && !longform.IsLongForm(did) {
 		return nil, fmt.Errorf("wrong did %s", did)
 	}
 
@@ -242,18 +523,14 @@ func (v *VDRI) Read(did string, opts ...resolve.Option) (*docdid.DocResolution,
 		domain = v.domain
 	}
 
-	if v.enableSignatureVerification {
-		if _, ok := v.validatedConsortium[domain]; !ok {
-			_, err = v.ValidateConsortium(domain)
-			if err != nil {
-				return nil, fmt.Errorf("invalid consortium: %w", err)
-			}
-
-			v.validatedConsortium[domain] = true
+	if v.enableSignatureVerification && v.trustedStakeholder == "" {
+		err := v.ensureConsortiumValidated(ctx, domain)
+		if err != nil {
+			return nil, fmt.Errorf("invalid consortium: %w", err)
 		}
 	}
 
-	endpoints, err := v.endpointService.GetEndpoints(domain)
+	endpoints, err := v.endpointService.GetEndpointsWithContext(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get endpoints: %w", err)
 	}
@@ -262,145 +539,539 @@ func (v *VDRI) Read(did string, opts ...resolve.Option) (*docdid.DocResolution,
 		return nil, errors.New("list of endpoints is empty")
 	}
 
-	var docResolution *docdid.DocResolution
+	resolutions, responses := v.resolveAtEndpoints(ctx, endpoints, v.resolutionDeadline(start), did, opts...)
 
-	var docBytes []byte
+	if err = ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to resolve did %s: %w", did, err)
+	}
 
-	for _, e := range endpoints {
-		resp, err := v.sidetreeResolve(e.URL+"/identifiers", did, opts...)
-		if err != nil {
-			return nil, err
+	accepted, err := v.consensusPolicy.Decide(responses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve did %s: %w", did, err)
+	}
+
+	return resolutions[accepted], nil
+}
+
+// ensureConsortiumValidated validates the consortium, unless its last validation hasn't expired.
+// Validation expires after the consortium config's cache lifetime, or minValidationLifetime if that is shorter;
+// a consortium that fails re-validation is no longer considered validated. Reads needing the consortium validated
+// while it is being validated share that validation.
+func (v *VDRI) ensureConsortiumValidated(ctx context.Context, domain string) error {
+	_, err := v.awaitConsortiumValidation(ctx, domain, false)
+
+	return err
+}
+
+// ConsortiumReport calls ConsortiumReportWithContext with a background context
+func (v *VDRI) ConsortiumReport(consortiumDomain string) (*ConsortiumReport, error) {
+	return v.ConsortiumReportWithContext(context.Background(), consortiumDomain)
+}
+
+// ConsortiumReportWithContext returns the report of the consortium's last validation by Reads, validating the
+// consortium as Reads do if that validation expired. A failed validation is reported, without validating the
+// consortium again, for minValidationLifetime. The report is returned even if validation failed.
+func (v *VDRI) ConsortiumReportWithContext(ctx context.Context, consortiumDomain string,
+) (*ConsortiumReport, error) {
+	return v.awaitConsortiumValidation(ctx, consortiumDomain, true)
+}
+
+// awaitConsortiumValidation waits for the consortium's validation, returning its report. Failed validations that
+// haven't expired are reused if reuseFailure is true, and are otherwise retried.
+func (v *VDRI) awaitConsortiumValidation(ctx context.Context, domain string, reuseFailure bool,
+) (*ConsortiumReport, error) {
+	cv := v.consortiumValidation(domain, reuseFailure)
+
+	select {
+	case <-cv.done:
+		return cv.report, cv.err
+	case <-ctx.Done():
+		return &ConsortiumReport{Domain: domain, Error: ctx.Err().Error()},
+			fmt.Errorf("validating consortium %s: %w", domain, ctx.Err())
+	}
+}
+
+// consortiumValidation returns the consortium's validation if it is in progress or hasn't expired, or else starts
+// validating the consortium
+func (v *VDRI) consortiumValidation(domain string, reuseFailure bool) *consortiumValidation {
+	v.validatedLock.Lock()
+	defer v.validatedLock.Unlock()
+
+	cv, ok := v.validatedConsortium[domain]
+	if ok && !cv.expired(time.Now(), reuseFailure) {
+		return cv
+	}
+
+	cv = &consortiumValidation{done: make(chan struct{})}
+	v.validatedConsortium[domain] = cv
+
+	go v.runConsortiumValidation(domain, cv)
+
+	return cv
+}
+
+// runConsortiumValidation validates the consortium, bounded by validationTimeout, then completes the validation
+func (v *VDRI) runConsortiumValidation(domain string, cv *consortiumValidation) {
+	ctx, cancel := context.WithTimeout(context.Background(), validationTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	cv.report, cv.err = v.ValidateConsortiumReportWithContext(ctx, domain)
+
+	lifetime := cv.report.Lifetime
+	if lifetime < minValidationLifetime {
+		lifetime = minValidationLifetime
+	}
+
+	cv.expiry = start.Add(lifetime)
+
+	close(cv.done)
+
+	if cv.err != nil {
+		// the failure is kept for reports until it expires, then forgotten
+		time.AfterFunc(time.Until(cv.expiry), func() { v.forgetConsortiumValidation(domain, cv) })
+	}
+}
+
+// forgetConsortiumValidation removes the consortium's validation, unless it was replaced by another
+func (v *VDRI) forgetConsortiumValidation(domain string, cv *consortiumValidation) {
+	v.validatedLock.Lock()
+	defer v.validatedLock.Unlock()
+
+	if v.validatedConsortium[domain] == cv {
+		delete(v.validatedConsortium, domain)
+	}
+}
+
+// resolutionDeadline returns the deadline for endpoints to resolve a DID, for a Read that started at the given time.
+// Returns the zero time if there is no deadline.
+func (v *VDRI) resolutionDeadline(start time.Time) time.Time {
+	var deadline time.Time
+
+	if v.endpointTimeout > 0 {
+		deadline = time.Now().Add(v.endpointTimeout)
+	}
+
+	if v.readTimeout > 0 {
+		readDeadline := start.Add(v.readTimeout)
+		if deadline.IsZero() || readDeadline.Before(deadline) {
+			deadline = readDeadline
 		}
+	}
 
-		respBytes, err := canonicalizeDoc(resp.DIDDocument)
-		if err != nil {
-			return nil, fmt.Errorf("cannot canonicalize resolved doc: %w", err)
+	return deadline
+}
+
+type endpointResult struct {
+	index      int
+	resolution *docdid.DocResolution
+	response   *consensus.Response
+}
+
+// resolveAtEndpoints resolves the DID at all the given endpoints concurrently, returning the resolutions and their
+// consensus responses in endpoint order. Endpoints that don't respond before the deadline, or before the context is
+// done, are failed responses, and their requests are aborted. A zero deadline waits for all endpoints.
+func (v *VDRI) resolveAtEndpoints(ctx context.Context, endpoints []*models.Endpoint, deadline time.Time, did string,
+	opts ...resolve.Option) ([]*docdid.DocResolution, []*consensus.Response) {
+	resolutions := make([]*docdid.DocResolution, len(endpoints))
+	responses := make([]*consensus.Response, len(endpoints))
+
+	// buffered, so endpoints that respond after the timeout don't block
+	results := make(chan endpointResult, len(endpoints))
+
+	endpointCtx, cancel := ctx, context.CancelFunc(func() {})
+	if !deadline.IsZero() {
+		endpointCtx, cancel = context.WithDeadline(ctx, deadline)
+	}
+
+	defer cancel()
+
+	for i, e := range endpoints {
+		go func(i int, e *models.Endpoint) {
+			resolution, response := v.resolveAtEndpoint(endpointCtx, e, did, opts...)
+			results <- endpointResult{index: i, resolution: resolution, response: response}
+		}(i, e)
+	}
+
+	var expired <-chan time.Time
+
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+
+		expired = timer.C
+	}
+
+	for received := 0; received < len(endpoints); received++ {
+		select {
+		case r := <-results:
+			resolutions[r.index], responses[r.index] = r.resolution, r.response
+		case <-expired:
+			received = len(endpoints)
+		case <-ctx.Done():
+			received = len(endpoints)
 		}
+	}
 
-		if docResolution != nil && !bytes.Equal(docBytes, respBytes) {
-			log.Debugf("mismatch in document contents for did %s. Doc 1: %s, Doc 2: %s",
-				did, string(docBytes), string(respBytes))
+	for i, e := range endpoints {
+		if responses[i] != nil {
+			continue
 		}
 
-		docResolution = resp
-		docBytes = respBytes
+		if ctx.Err() != nil {
+			responses[i] = &consensus.Response{Domain: e.Domain, Err: fmt.Errorf("endpoint %s: %w", e.URL, ctx.Err())}
+		} else {
+			responses[i] = &consensus.Response{Domain: e.Domain, Err: fmt.Errorf("endpoint %s %w", e.URL, errEndpointTimeout)}
+		}
 	}
 
-	return docResolution, nil
+	return resolutions, responses
 }
 
-// ValidateConsortium validate the config and endorsement of a consortium and its stakeholders
-// returns the duration after which the consortium config expires and needs re-validation
+// resolveAtEndpoint resolves the DID at the given endpoint, returning the resolution and its consensus response
+func (v *VDRI) resolveAtEndpoint(ctx context.Context, e *models.Endpoint, did string, opts ...resolve.Option,
+) (*docdid.DocResolution, *consensus.Response) {
+	resp, err := v.sidetreeResolve(ctx, e.URL+"/identifiers", did, opts...)
+	if err != nil {
+		return nil, &consensus.Response{Domain: e.Domain, Err: err}
+	}
+
+	respBytes, err := v.canonicalize(resp.DIDDocument)
+	if err != nil {
+		return nil, &consensus.Response{Domain: e.Domain, Err: fmt.Errorf("cannot canonicalize resolved doc: %w", err)}
+	}
+
+	return resp, &consensus.Response{Domain: e.Domain, Doc: respBytes}
+}
+
+// ValidateConsortium calls ValidateConsortiumWithContext with a background context
 func (v *VDRI) ValidateConsortium(consortiumDomain string) (*time.Duration, error) {
-	consortiumConfig, err := v.configService.GetConsortium(consortiumDomain, consortiumDomain)
+	return v.ValidateConsortiumWithContext(context.Background(), consortiumDomain)
+}
+
+// ValidateConsortiumWithContext validate the config and endorsement of a consortium and its stakeholders
+// returns the duration after which the consortium config expires and needs re-validation
+func (v *VDRI) ValidateConsortiumWithContext(ctx context.Context, consortiumDomain string) (*time.Duration, error) {
+	report, err := v.ValidateConsortiumReportWithContext(ctx, consortiumDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report.Lifetime, nil
+}
+
+// ValidateConsortiumReport calls ValidateConsortiumReportWithContext with a background context
+func (v *VDRI) ValidateConsortiumReport(consortiumDomain string) (*ConsortiumReport, error) {
+	return v.ValidateConsortiumReportWithContext(context.Background(), consortiumDomain)
+}
+
+// ValidateConsortiumReportWithContext validates a consortium as ValidateConsortiumWithContext does, reporting the
+// outcome of each check for every stakeholder queried. The report is returned even if validation fails.
+func (v *VDRI) ValidateConsortiumReportWithContext(ctx context.Context, consortiumDomain string,
+) (*ConsortiumReport, error) {
+	start := time.Now()
+
+	report := &ConsortiumReport{Domain: consortiumDomain}
+
+	err := v.validateConsortium(ctx, report)
+
+	report.Latency = time.Since(start)
+	report.Valid = err == nil
+
 	if err != nil {
-		return nil, fmt.Errorf("consortium invalid: %w", err)
+		report.Error = err.Error()
 	}
 
-	stakeholders, err := v.selectStakeholders(consortiumConfig.Config)
+	return report, err
+}
+
+// validateConsortium validates the config and endorsement of a consortium and its stakeholders, recording the
+// outcome in the report
+func (v *VDRI) validateConsortium(ctx context.Context, report *ConsortiumReport) error {
+	consortiumConfig, err := v.configService.GetConsortiumWithContext(ctx, report.Domain, report.Domain)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch stakeholders: %w", err)
+		var insufficient *models.InsufficientEndorsement
+		if !errors.As(err, &insufficient) {
+			err = &models.ConsortiumUnavailable{Domain: report.Domain, Err: err}
+		}
+
+		return fmt.Errorf("consortium invalid: %w", err)
 	}
 
-	n := consortiumConfig.Config.Policy.NumQueries
-	if n == 0 || n > len(consortiumConfig.Config.Members) {
-		n = len(consortiumConfig.Config.Members)
+	stakeholders, stakeholderReports, err := v.selectStakeholders(ctx, consortiumConfig.Config, report)
+	if err != nil {
+		return fmt.Errorf("failed to fetch stakeholders: %w", err)
 	}
 
-	numVerifications := 0
+	var verificationErrors []error
+
+	for i, sfd := range stakeholders {
+		start := time.Now()
+
+		e := v.verifyStakeholder(ctx, consortiumConfig, sfd, stakeholderReports[i])
 
-	verificationErrors := ""
+		stakeholderReports[i].Latency += time.Since(start)
 
-	for _, sfd := range stakeholders {
-		e := v.verifyStakeholder(consortiumConfig, sfd)
 		if e != nil {
-			verificationErrors += e.Error() + ", "
+			stakeholderReports[i].Error = e.Error()
+			verificationErrors = append(verificationErrors, e)
+
 			continue
 		}
 
-		numVerifications++
+		report.Endorsed++
 	}
 
-	if numVerifications < n {
-		return nil, fmt.Errorf("insufficient stakeholders verified, all errors: [%s]", verificationErrors)
+	if report.Endorsed < report.Required {
+		return &models.InsufficientEndorsement{
+			Domain:       report.Domain,
+			Required:     report.Required,
+			Endorsed:     report.Endorsed,
+			Stakeholders: verificationErrors,
+		}
 	}
 
-	lifetime, err := consortiumConfig.CacheLifetime()
+	report.Lifetime, err = consortiumConfig.CacheLifetime()
 	if err != nil {
-		return nil, fmt.Errorf("consortium lifetime error: %w", err)
+		return fmt.Errorf("consortium lifetime error: %w", err)
 	}
 
-	return &lifetime, nil
+	return nil
 }
 
-func (v *VDRI) verifyStakeholder(cfd *models.ConsortiumFileData, sfd *models.StakeholderFileData) error {
+// verifyStakeholder verifies a stakeholder endorses the consortium, recording the outcome of each check in the report
+func (v *VDRI) verifyStakeholder(ctx context.Context, cfd *models.ConsortiumFileData,
+	sfd *models.StakeholderFileData, report *StakeholderReport) error {
 	s := sfd.Config
 	if s == nil {
 		return fmt.Errorf("stakeholder has nil config")
 	}
 
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(s.Endpoints))))
-	if err != nil {
-		return err
+	doc, endpoint, e := v.resolveStakeholderDID(ctx, s)
+	if e != nil {
+		return &models.StakeholderDown{Domain: s.Domain, Err: e}
 	}
 
-	ep := s.Endpoints[n.Uint64()]
+	report.DIDResolved = true
+	report.ResolvedVia = endpoint
 
-	docResolution, e := v.sidetreeResolve(ep+"/identifiers", s.DID)
+	e = verifyStakeholderKey(cfd.Config, s.Domain, doc)
 	if e != nil {
-		return fmt.Errorf("can't resolve stakeholder DID: %w", e)
+		return &models.InvalidStakeholderSignature{Domain: s.Domain, Err: e}
 	}
 
 	// verify did configuration
-	e = v.didConfigService.VerifyStakeholder(s.Domain, docResolution.DIDDocument)
+	e = v.didConfigService.VerifyStakeholderWithContext(ctx, s.Domain, doc)
 	if e != nil {
-		return fmt.Errorf("stakeholder did configuration failed to verify: %w", e)
+		return &models.InvalidStakeholderSignature{
+			Domain: s.Domain,
+			Err:    fmt.Errorf("stakeholder did configuration failed to verify: %w", e),
+		}
 	}
 
-	_, e = didconfiguration.VerifyDIDSignature(cfd.JWS, docResolution.DIDDocument)
+	report.DIDConfigurationValid = true
+
+	_, e = didconfiguration.VerifyDIDSignature(cfd.JWS, doc)
 	if e != nil {
-		return fmt.Errorf("stakeholder does not sign consortium: %w", e)
+		return &models.InvalidStakeholderSignature{
+			Domain: s.Domain,
+			Err:    fmt.Errorf("stakeholder does not sign consortium: %w", e),
+		}
 	}
 
-	_, e = didconfiguration.VerifyDIDSignature(sfd.JWS, docResolution.DIDDocument)
+	report.ConsortiumSignatureVerified = true
+
+	_, e = didconfiguration.VerifyDIDSignature(sfd.JWS, doc)
 	if e != nil {
-		return fmt.Errorf("stakeholder does not sign itself: %w", e)
+		return &models.InvalidStakeholderSignature{
+			Domain: s.Domain,
+			Err:    fmt.Errorf("stakeholder does not sign itself: %w", e),
+		}
+	}
+
+	report.SelfSignatureVerified = true
+
+	return nil
+}
+
+// resolveStakeholderDID resolves the stakeholder's DID using a random endpoint of the stakeholder, returning the DID
+// doc and the endpoint used
+func (v *VDRI) resolveStakeholderDID(ctx context.Context, s *models.Stakeholder) (*docdid.Doc, string, error) {
+	if len(s.Endpoints) == 0 {
+		return nil, "", fmt.Errorf("stakeholder %s has no endpoints", s.Domain)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(s.Endpoints))))
+	if err != nil {
+		return nil, "", err
+	}
+
+	ep := s.Endpoints[n.Uint64()]
+
+	docResolution, err := v.sidetreeResolve(ctx, ep+"/identifiers", s.DID)
+	if err != nil {
+		return nil, ep, fmt.Errorf("can't resolve stakeholder DID: %w", err)
+	}
+
+	return docResolution.DIDDocument, ep, nil
+}
+
+// getTrustedStakeholderEndpoints returns the endpoints of the trusted stakeholder, for any domain.
+// The stakeholder config is verified whenever it differs from the last verified config.
+func (v *VDRI) getTrustedStakeholderEndpoints(ctx context.Context, _ string) ([]*models.Endpoint, error) {
+	sfd, err := v.configService.GetStakeholderWithContext(ctx, v.trustedStakeholder, v.trustedStakeholder)
+	if err != nil {
+		return nil, fmt.Errorf("trusted stakeholder config: %w", err)
+	}
+
+	if sfd == nil || sfd.Config == nil || sfd.JWS == nil {
+		return nil, fmt.Errorf("trusted stakeholder has nil config")
+	}
+
+	serialized := sfd.JWS.FullSerialize()
+
+	v.validatedLock.RLock()
+	validated := serialized == v.validatedTrustedStakeholder
+	v.validatedLock.RUnlock()
+
+	if !validated {
+		err = v.verifyTrustedStakeholder(ctx, sfd)
+		if err != nil {
+			return nil, fmt.Errorf("trusted stakeholder invalid: %w", err)
+		}
+
+		v.validatedLock.Lock()
+		v.validatedTrustedStakeholder = serialized
+		v.validatedLock.Unlock()
+	}
+
+	var endpoints []*models.Endpoint
+
+	for _, ep := range sfd.Config.Endpoints {
+		endpoints = append(endpoints, &models.Endpoint{URL: ep, Domain: sfd.Config.Domain})
+	}
+
+	return endpoints, nil
+}
+
+// verifyTrustedStakeholder verifies a stakeholder config without a consortium config:
+// the stakeholder's did-configuration must link its DID to its domain, and the config must be signed by that DID
+func (v *VDRI) verifyTrustedStakeholder(ctx context.Context, sfd *models.StakeholderFileData) error {
+	s := sfd.Config
+
+	if s.Domain != v.trustedStakeholder {
+		return fmt.Errorf("stakeholder config domain %s does not match trusted stakeholder %s",
+			s.Domain, v.trustedStakeholder)
+	}
+
+	doc, _, err := v.resolveStakeholderDID(ctx, s)
+	if err != nil {
+		return &models.StakeholderDown{Domain: s.Domain, Err: err}
+	}
+
+	err = v.didConfigService.VerifyStakeholderWithContext(ctx, s.Domain, doc)
+	if err != nil {
+		return &models.InvalidStakeholderSignature{
+			Domain: s.Domain,
+			Err:    fmt.Errorf("stakeholder did configuration failed to verify: %w", err),
+		}
+	}
+
+	_, err = didconfiguration.VerifyDIDSignature(sfd.JWS, doc)
+	if err != nil {
+		return &models.InvalidStakeholderSignature{
+			Domain: s.Domain,
+			Err:    fmt.Errorf("stakeholder does not sign itself: %w", err),
+		}
 	}
 
 	return nil
 }
 
-// select n random stakeholders from the consortium (where n is the consortium's numQueries policy parameter)
-func (v *VDRI) selectStakeholders(consortium *models.Consortium) ([]*models.StakeholderFileData, error) {
+// verifyStakeholderKey verifies that the public key listed for a stakeholder in the consortium config is expressed by
+// the stakeholder's DID doc
+func verifyStakeholderKey(consortium *models.Consortium, stakeholderDomain string, doc *docdid.Doc) error {
+	if consortium == nil {
+		return fmt.Errorf("consortium has nil config")
+	}
+
+	for _, sle := range consortium.Members {
+		if sle == nil || sle.Domain != stakeholderDomain {
+			continue
+		}
+
+		err := didconfiguration.VerifyDIDKey(sle.PublicKey.ID, sle.PublicKey.JWK, doc)
+		if errors.Is(err, didconfiguration.ErrUnsupportedKeyType) {
+			return fmt.Errorf("can't compare stakeholder consortium key with stakeholder DID doc: %w", err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("stakeholder consortium key not in stakeholder DID doc: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("stakeholder %s is not a member of the consortium", stakeholderDomain)
+}
+
+// select n random stakeholders from the consortium (where n is the consortium's numQueries policy parameter),
+// returning the fetched stakeholder configs with their reports. The report records every stakeholder queried.
+func (v *VDRI) selectStakeholders(ctx context.Context, consortium *models.Consortium, report *ConsortiumReport,
+) ([]*models.StakeholderFileData, []*StakeholderReport, error) {
 	n := consortium.Policy.NumQueries
 	if n == 0 || n > len(consortium.Members) {
 		n = len(consortium.Members)
 	}
 
-	perm := mathrand.Perm(len(consortium.Members))
+	report.Required = n
 
-	successCount := 0
+	perm := mathrand.Perm(len(consortium.Members))
 
-	var out []*models.StakeholderFileData
+	var (
+		out     []*models.StakeholderFileData
+		reports []*StakeholderReport
+		errs    []error
+	)
 
-	for i := 0; i < len(consortium.Members) && successCount < n; i++ {
+	for i := 0; i < len(consortium.Members) && len(out) < n; i++ {
 		sle := consortium.Members[perm[i]]
 
-		s, err := v.configService.GetStakeholder(sle.Domain, sle.Domain)
+		start := time.Now()
+
+		s, err := v.configService.GetStakeholderWithContext(models.WithConsortium(ctx, report.Domain, report.Domain),
+			sle.Domain, sle.Domain)
+
+		r := &StakeholderReport{Domain: sle.Domain, ConfigFetched: err == nil, Latency: time.Since(start)}
+		report.Stakeholders = append(report.Stakeholders, r)
+
 		if err != nil {
+			var invalidSignature *models.InvalidStakeholderSignature
+			if !errors.As(err, &invalidSignature) {
+				err = &models.StakeholderDown{Domain: sle.Domain, Err: err}
+			}
+
+			r.Error = err.Error()
+			errs = append(errs, err)
+
 			continue
 		}
 
 		out = append(out, s)
-
-		successCount++
+		reports = append(reports, r)
 	}
 
-	if successCount < n {
-		return nil, fmt.Errorf("insufficient valid stakeholders")
+	if len(out) < n {
+		return nil, nil, &models.InsufficientEndorsement{
+			Domain:       consortium.Domain,
+			Required:     n,
+			Endorsed:     len(out),
+			Stakeholders: errs,
+		}
 	}
 
-	return out, nil
+	return out, reports, nil
 }
 
 // canonicalizeDoc canonicalizes a DID doc using json-ld canonicalization
@@ -446,13 +1117,50 @@ func WithTLSConfig(tlsConfig *tls.Config) Option {
 	}
 }
 
-// WithAuthToken add auth token
+// WithAuthToken add auth token, sent as a bearer token with every request the VDRI sends: to fetch configs and
+// did-configurations, and to resolve and create DIDs
 func WithAuthToken(authToken string) Option {
 	return func(opts *VDRI) {
 		opts.authToken = authToken
 	}
 }
 
+// WithHTTPHeader adds a header to every request the VDRI sends
+func WithHTTPHeader(name, value string) Option {
+	return func(opts *VDRI) {
+		opts.httpClientOpts = append(opts.httpClientOpts, httpclient.WithHeader(name, value))
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request the VDRI sends
+func WithUserAgent(userAgent string) Option {
+	return func(opts *VDRI) {
+		opts.httpClientOpts = append(opts.httpClientOpts, httpclient.WithUserAgent(userAgent))
+	}
+}
+
+// WithProxy sends every request the VDRI sends through the proxy at the given url
+func WithProxy(proxyURL *url.URL) Option {
+	return func(opts *VDRI) {
+		opts.httpClientOpts = append(opts.httpClientOpts, httpclient.WithProxy(proxyURL))
+	}
+}
+
+// WithHistoryHashRegistry sets the registry of hash algorithms used to verify consortium history files
+func WithHistoryHashRegistry(registry *historyhash.Registry) Option {
+	return func(opts *VDRI) {
+		opts.historyHash = registry
+	}
+}
+
+// WithRetryPolicy sets the policy for retrying failed consortium, stakeholder and did-configuration requests.
+// Defaults to retry.Default(). A nil policy disables retries.
+func WithRetryPolicy(policy *retry.Policy) Option {
+	return func(opts *VDRI) {
+		opts.retryPolicy = policy
+	}
+}
+
 // EnableSignatureVerification enables signature verification
 func EnableSignatureVerification(enable bool) Option {
 	return func(opts *VDRI) {
@@ -471,3 +1179,102 @@ func UseGenesisFile(url, domain string, genesisFile []byte) Option {
 		opts.useUpdateValidation = true
 	}
 }
+
+// WithConsensusPolicy sets the policy for accepting a resolved DID doc when the stakeholder endpoints queried return
+// different docs. By default, all endpoints must agree.
+func WithConsensusPolicy(policy consensus.Policy) Option {
+	return func(opts *VDRI) {
+		opts.consensusPolicy = policy
+	}
+}
+
+// WithEndpointTimeout sets how long Read waits for each stakeholder endpoint to resolve a DID.
+// Endpoints that don't respond in time count as failed under the consensus policy. Zero disables the timeout.
+func WithEndpointTimeout(timeout time.Duration) Option {
+	return func(opts *VDRI) {
+		opts.endpointTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets the overall deadline for Read, after which endpoints that haven't responded count as failed
+// under the consensus policy. Zero disables the deadline.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(opts *VDRI) {
+		opts.readTimeout = timeout
+	}
+}
+
+// WithWaitUntilPublished makes Build wait until the created DID is published, by polling its resolution at the
+// consortium's endpoints every pollInterval, until the timeout elapses. DIDs that aren't published in time fail to
+// build with ErrPublishTimeout, although they were created.
+func WithWaitUntilPublished(timeout, pollInterval time.Duration) Option {
+	return func(opts *VDRI) {
+		opts.publishTimeout = timeout
+		opts.publishPollInterval = pollInterval
+	}
+}
+
+// EnableLongFormDID makes Build return the long-form DID of created DIDs, which embeds the DID's initial state so
+// the DID can be resolved before it is anchored
+func EnableLongFormDID(enable bool) Option {
+	return func(opts *VDRI) {
+		opts.useLongFormDID = enable
+	}
+}
+
+// WithLongFormResolver sets the resolver of long-form DIDs the endpoints can't resolve
+func WithLongFormResolver(resolver *longform.Resolver) Option {
+	return func(opts *VDRI) {
+		opts.longFormResolver = resolver
+	}
+}
+
+// WithTrustedStakeholder skips consortium bootstrapping, and uses only the endpoints of the given stakeholder.
+// The stakeholder's config and did-configuration are verified, but no other consortium members are contacted,
+// so genesis files and consortium signature verification are not used.
+func WithTrustedStakeholder(domain string) Option {
+	return func(opts *VDRI) {
+		opts.trustedStakeholder = domain
+	}
+}
+
+// EnableLastValidConsortiumFallback enables falling back to the last valid consortium or stakeholder config in the
+// config history, when validating an update from a genesis file fails part-way instead of failing the update
+func EnableLastValidConsortiumFallback(enable bool) Option {
+	return func(opts *VDRI) {
+		opts.useLastValidConsortium = enable
+	}
+}
+
+// WithCacheDir caches consortium, stakeholder and sidetree configs in the given directory, so they survive a
+// restart. Cached configs are verified when loaded, as fetched ones are. They are used until their cache lifetime
+// expires, and after that for a limited time while their server can't be reached.
+func WithCacheDir(dir string) Option {
+	return func(opts *VDRI) {
+		opts.cacheDir = dir
+	}
+}
+
+// WithCacheDirMaxStale sets how long after it expires a config cached in the cache directory is used while its server
+// can't be reached. Defaults to a day.
+func WithCacheDirMaxStale(maxStale time.Duration) Option {
+	return func(opts *VDRI) {
+		opts.cacheDirMaxStale = maxStale
+	}
+}
+
+// WithStaleWhileRevalidate serves a cached config for up to maxStale after its cache lifetime expires, while it is
+// refreshed in the background, instead of refreshing it before resolving.
+func WithStaleWhileRevalidate(maxStale time.Duration) Option {
+	return func(opts *VDRI) {
+		opts.maxStaleConfig = maxStale
+	}
+}
+
+// WithConfigFailureCaching caches a failure to fetch a config for the given duration, so resolutions relying on an
+// unavailable consortium or stakeholder server fail fast instead of querying it again.
+func WithConfigFailureCaching(ttl time.Duration) Option {
+	return func(opts *VDRI) {
+		opts.configFailureTTL = ttl
+	}
+}
@@ -6,15 +6,24 @@ SPDX-License-Identifier: Apache-2.0
 package trustbloc
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/create"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr/resolve"
 	mockvdr "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
@@ -24,8 +33,10 @@ import (
 	mockconfig "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/config"
 	mockdidconf "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/didconfiguration"
 	mockendpoint "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/endpoint"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/consensus"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/didconfiguration"
 	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/retry"
 )
 
 func TestVDRI_Accept(t *testing.T) {
@@ -108,6 +119,60 @@ func TestVDRI_Build(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to get sidetree config")
 	})
+	t.Run("test wait until published", func(t *testing.T) {
+		newVDRI := func(published int) (*VDRI, *int) {
+			v := New(WithWaitUntilPublished(time.Second, time.Millisecond))
+
+			v.endpointService = &mockendpoint.MockEndpointService{
+				GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+					return []*models.Endpoint{{URL: "url"}}, nil
+				}}
+
+			v.configService = &mockconfig.MockConfigService{
+				GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+					return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+				}}
+
+			v.sidetreeClient = &mockSidetreeClient{createDIDValue: &did.DocResolution{
+				DIDDocument: &did.Doc{ID: "did:trustbloc:testnet:123"}}}
+
+			v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+				return []byte(doc.ID), nil
+			}
+
+			reads := 0
+
+			v.getHTTPVDRI = func(url string) (vdri, error) {
+				return &mockvdr.MockVDR{
+					ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+						reads++
+
+						if published < 0 || reads <= published {
+							return nil, fmt.Errorf("not found")
+						}
+
+						return &did.DocResolution{DIDDocument: &did.Doc{ID: didID, Context: []string{"published"}}}, nil
+					}}, nil
+			}
+
+			return v, &reads
+		}
+
+		v, reads := newVDRI(2)
+
+		docResolution, err := v.Build(nil, create.WithRecoveryPublicKey("key"))
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:123", docResolution.DIDDocument.ID)
+		require.Equal(t, []string{"published"}, docResolution.DIDDocument.Context)
+		require.Equal(t, 3, *reads)
+
+		v, _ = newVDRI(-1)
+		v.publishTimeout = 20 * time.Millisecond
+
+		_, err = v.Build(nil, create.WithRecoveryPublicKey("key"))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrPublishTimeout))
+	})
 }
 
 func httpVdriFunc(doc *did.DocResolution, err error) func(url string) (v vdri, err error) {
@@ -266,6 +331,25 @@ func TestVDRI_Read(t *testing.T) {
 		require.Nil(t, doc)
 	})
 
+	t.Run("test error validating consortium", func(t *testing.T) {
+		v := New(EnableSignatureVerification(true))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("consortium error")
+			},
+		}
+
+		doc, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid consortium")
+		require.Nil(t, doc)
+
+		var unavailable *models.ConsortiumUnavailable
+		require.True(t, errors.As(err, &unavailable))
+		require.Equal(t, "testnet", unavailable.Domain)
+	})
+
 	t.Run("test error from get endpoints", func(t *testing.T) {
 		v := New()
 
@@ -274,7 +358,7 @@ func TestVDRI_Read(t *testing.T) {
 				return nil, fmt.Errorf("discover error")
 			}}
 
-		v.validatedConsortium["testnet"] = true
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
 
 		doc, err := v.Read("did:trustbloc:testnet:123")
 		require.Error(t, err)
@@ -314,7 +398,7 @@ func TestVDRI_Read(t *testing.T) {
 			return nil, fmt.Errorf("get http vdri error")
 		}
 
-		v.validatedConsortium["testnet"] = true
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
 
 		doc, err := v.Read("did:trustbloc:testnet:123")
 		require.Error(t, err)
@@ -332,7 +416,7 @@ func TestVDRI_Read(t *testing.T) {
 
 		v.getHTTPVDRI = httpVdriFunc(nil, fmt.Errorf("read error"))
 
-		v.validatedConsortium["testnet"] = true
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
 
 		doc, err := v.Read("did:trustbloc:testnet:123")
 		require.Error(t, err)
@@ -340,29 +424,256 @@ func TestVDRI_Read(t *testing.T) {
 		require.Nil(t, doc)
 	})
 
-	//nolint:gocritic
-	// t.Run("test error from mismatch", func(t *testing.T) {
-	// 	v := New()
-	//
-	// 	v.endpointService = &mockendpoint.MockEndpointService{
-	// 		GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
-	// 			return []*models.Endpoint{{URL: "url"}, {URL: "url.2"}}, nil
-	// 		}}
-	//
-	// 	counter := 0
-	//
-	// 	v.getHTTPVDRI = func(url string) (v vdri, err error) {
-	// 		return &mockvdr.MockVDRI{
-	// 			ReadFunc: func(didID string, opts ...vdrapi.ResolveOpts) (*did.Doc, error) {
-	// 				counter++
-	// 				return generateDIDDoc("test:" + string(counter)), nil
-	// 			}}, nil
-	// 	}
-	//
-	// 	_, err := v.Read("did:trustbloc:testnet:123")
-	// 	require.Error(t, err)
-	// 	require.Contains(t, err.Error(), "mismatch")
-	// })
+	t.Run("test error from mismatch", func(t *testing.T) {
+		v := New()
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: "url", Domain: "d1"}, {URL: "url.2", Domain: "d2"}}, nil
+			}}
+
+		v.getHTTPVDRI = func(url string) (v vdri, err error) {
+			return &mockvdr.MockVDR{
+				ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+					id := "did:trustbloc:testnet:" + strings.TrimSuffix(url, "/identifiers")
+
+					return &did.DocResolution{DIDDocument: &did.Doc{ID: id}}, nil
+				}}, nil
+		}
+
+		v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+			return []byte(doc.ID), nil
+		}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "mismatch")
+
+		mismatch := &consensus.MismatchError{}
+		require.True(t, errors.As(err, &mismatch))
+		require.Equal(t, []string{"d1"}, mismatch.Agreed)
+		require.Equal(t, []string{"d2"}, mismatch.Disagreed)
+	})
+
+	t.Run("test deactivated or unknown did at every endpoint", func(t *testing.T) {
+		statuses := []int{http.StatusGone, http.StatusGone}
+
+		var endpoints []*models.Endpoint
+
+		for i := range statuses {
+			i := i
+
+			serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(statuses[i])
+			}))
+			defer serv.Close()
+
+			endpoints = append(endpoints, &models.Endpoint{URL: serv.URL, Domain: fmt.Sprintf("d%d", i+1)})
+		}
+
+		v := New(WithConsensusPolicy(consensus.Majority()))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+				return endpoints, nil
+			}}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrDIDDeactivated))
+		require.False(t, errors.Is(err, vdrapi.ErrNotFound))
+
+		statuses[0], statuses[1] = http.StatusNotFound, http.StatusNotFound
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, vdrapi.ErrNotFound))
+		require.False(t, errors.Is(err, ErrDIDDeactivated))
+
+		statuses[0] = http.StatusGone
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrDIDDeactivated))
+		require.False(t, errors.Is(err, vdrapi.ErrNotFound))
+	})
+
+	t.Run("test consensus policy", func(t *testing.T) {
+		v := New(WithConsensusPolicy(consensus.Majority()))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{
+					{URL: "forged", Domain: "d1"}, {URL: "url", Domain: "d2"}, {URL: "url", Domain: "d3"},
+				}, nil
+			}}
+
+		v.getHTTPVDRI = func(url string) (v vdri, err error) {
+			return &mockvdr.MockVDR{
+				ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+					id := "did:trustbloc:testnet:" + strings.TrimSuffix(url, "/identifiers")
+
+					return &did.DocResolution{DIDDocument: &did.Doc{ID: id}}, nil
+				}}, nil
+		}
+
+		v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+			return []byte(doc.ID), nil
+		}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		doc, err := v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:url", doc.DIDDocument.ID)
+
+		v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+			return nil, fmt.Errorf("canonicalize error")
+		}
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "canonicalize error")
+	})
+
+	t.Run("test endpoint timeout", func(t *testing.T) {
+		v := New(WithConsensusPolicy(consensus.Majority()), WithEndpointTimeout(50*time.Millisecond))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{
+					{URL: "slow", Domain: "d1"}, {URL: "url", Domain: "d2"}, {URL: "url", Domain: "d3"},
+				}, nil
+			}}
+
+		release := make(chan struct{})
+		defer close(release)
+
+		v.getHTTPVDRI = func(url string) (v vdri, err error) {
+			return &mockvdr.MockVDR{
+				ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+					if strings.HasPrefix(url, "slow") {
+						<-release
+					}
+
+					return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+				}}, nil
+		}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		doc, err := v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:123", doc.DIDDocument.ID)
+
+		v.consensusPolicy = consensus.AllMustAgree()
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "endpoint slow timed out")
+
+		mismatch := &consensus.MismatchError{}
+		require.True(t, errors.As(err, &mismatch))
+		require.Equal(t, []string{"d1"}, mismatch.Failed)
+	})
+
+	t.Run("test endpoint timeout aborts the request", func(t *testing.T) {
+		aborted := make(chan struct{})
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			close(aborted)
+		}))
+		defer serv.Close()
+
+		v := New(WithEndpointTimeout(50 * time.Millisecond))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: serv.URL, Domain: "d1"}}, nil
+			}}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+
+		mismatch := &consensus.MismatchError{}
+		require.True(t, errors.As(err, &mismatch))
+		require.Equal(t, []string{"d1"}, mismatch.Failed)
+
+		select {
+		case <-aborted:
+		case <-time.After(time.Second):
+			require.Fail(t, "request to endpoint not aborted")
+		}
+	})
+
+	t.Run("test read timeout", func(t *testing.T) {
+		v := New(WithEndpointTimeout(0), WithReadTimeout(time.Millisecond))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				time.Sleep(5 * time.Millisecond)
+
+				return []*models.Endpoint{{URL: "url", Domain: "d1"}}, nil
+			}}
+
+		release := make(chan struct{})
+		defer close(release)
+
+		v.getHTTPVDRI = func(url string) (v vdri, err error) {
+			return &mockvdr.MockVDR{
+				ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+					<-release
+
+					return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+				}}, nil
+		}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "endpoint url timed out")
+	})
+
+	t.Run("test context canceled", func(t *testing.T) {
+		v := New(WithEndpointTimeout(0), WithReadTimeout(0))
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) (endpoints []*models.Endpoint, err error) {
+				return []*models.Endpoint{{URL: "url", Domain: "d1"}}, nil
+			}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		release := make(chan struct{})
+		defer close(release)
+
+		v.getHTTPVDRI = func(url string) (v vdri, err error) {
+			return &mockvdr.MockVDR{
+				ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+					cancel()
+					<-release
+
+					return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+				}}, nil
+		}
+
+		v.validatedConsortium["testnet"] = validConsortium(time.Hour)
+
+		_, err := v.ReadWithContext(ctx, "did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+
+		_, err = v.ReadWithContext(ctx, "did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
 
 	t.Run("test success", func(t *testing.T) {
 		sigKey := ed25519SigningKey(t, keyJSON)
@@ -413,19 +724,31 @@ func TestVDRI_loadGenesisFiles(t *testing.T) {
 		require.NoError(t, err)
 
 		v := New(UseGenesisFile("url", "domain", []byte(confFile)))
-
-		err = v.loadGenesisFiles()
-		require.NoError(t, err)
+		require.NoError(t, v.genesisErr)
+		require.Nil(t, v.genesisFiles)
 	})
 
 	t.Run("fail: bad consortium data", func(t *testing.T) {
 		confFile := "this is not a consortium config jws"
 
 		v := New(UseGenesisFile("url", "domain", []byte(confFile)))
+		require.Error(t, v.genesisErr)
+		require.Contains(t, v.genesisErr.Error(), "error loading consortium genesis config")
+	})
+
+	t.Run("fail: duplicate genesis file", func(t *testing.T) {
+		conf := models.Consortium{Domain: "consortium.website"}
 
-		err := v.loadGenesisFiles()
+		confFile, err := signConfig(conf, []jose.SigningKey{*sigKey})
+		require.NoError(t, err)
+
+		v := New(UseGenesisFile("url", "domain", []byte(confFile)), UseGenesisFile("url", "domain", []byte(confFile)))
+		require.Error(t, v.genesisErr)
+		require.Contains(t, v.genesisErr.Error(), "genesis file already added")
+
+		_, err = v.Read("did:trustbloc:domain:123")
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "error loading consortium genesis config")
+		require.Contains(t, err.Error(), "invalid genesis file")
 	})
 
 	t.Run("fail: try to read using a vdri with a bad genesis file", func(t *testing.T) {
@@ -440,6 +763,62 @@ func TestVDRI_loadGenesisFiles(t *testing.T) {
 	})
 }
 
+func TestVDRI_ParallelRead(t *testing.T) {
+	sigKey := ed25519SigningKey(t, keyJSON)
+
+	mockDoc, err := did.ParseDocument([]byte(testDoc))
+	require.NoError(t, err)
+
+	consortium := dummyConsortium("testnet", "stakeholder.url")
+	cfd := signedConsortiumFileData(t, consortium, sigKey)
+	sfd := signedStakeholderFileData(t, dummyStakeholder("stakeholder.url"), sigKey)
+
+	v := New(EnableSignatureVerification(true), UseGenesisFile("testnet", "testnet", []byte(cfd.JWS.FullSerialize())))
+	require.NoError(t, v.genesisErr)
+
+	v.configService = &mockconfig.MockConfigService{
+		GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+			return cfd, nil
+		},
+		GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+			return sfd, nil
+		},
+	}
+
+	v.endpointService = &mockendpoint.MockEndpointService{
+		GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+			return []*models.Endpoint{{URL: "url", Domain: "stakeholder.url"}}, nil
+		}}
+
+	v.getHTTPVDRI = httpVdriFunc(&did.DocResolution{DIDDocument: mockDoc}, nil)
+
+	v.didConfigService = &mockdidconf.MockDIDConfigService{
+		VerifyStakeholderFunc: func(domain string, doc *did.Doc) error {
+			return nil
+		},
+	}
+
+	v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+		return []byte(doc.ID), nil
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			docResolution, err := v.Read("did:trustbloc:testnet:123")
+			require.NoError(t, err)
+			require.Equal(t, mockDoc.ID, docResolution.DIDDocument.ID)
+		}()
+	}
+
+	wg.Wait()
+}
+
 const (
 	keyJSON = `{
   "kty": "OKP",
@@ -526,6 +905,10 @@ func TestVDRI_ValidateConsortium(t *testing.T) {
 		_, err := v.ValidateConsortium(consortiumServer.URL)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "consortium invalid")
+
+		var unavailable *models.ConsortiumUnavailable
+		require.True(t, errors.As(err, &unavailable))
+		require.Equal(t, consortiumServer.URL, unavailable.Domain)
 	})
 
 	t.Run("failure - stakeholders don't sign consortium config", func(t *testing.T) {
@@ -575,6 +958,16 @@ func TestVDRI_ValidateConsortium(t *testing.T) {
 		_, err = v.ValidateConsortium(consortiumServer.URL)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to fetch stakeholders")
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Equal(t, 1, insufficient.Required)
+		require.Equal(t, 0, insufficient.Endorsed)
+		require.Len(t, insufficient.Stakeholders, 1)
+
+		var down *models.StakeholderDown
+		require.True(t, errors.As(insufficient.Stakeholders[0], &down))
+		require.Equal(t, stakeholderServer.URL, down.Domain)
 	})
 
 	t.Run("success - verify with one stakeholder", func(t *testing.T) {
@@ -687,6 +1080,15 @@ func TestVDRI_ValidateConsortium(t *testing.T) {
 		_, err = v.ValidateConsortium(consortiumServer.URL)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "can't resolve stakeholder DID")
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Equal(t, consortiumServer.URL, insufficient.Domain)
+		require.Len(t, insufficient.Stakeholders, 1)
+
+		var down *models.StakeholderDown
+		require.True(t, errors.As(insufficient.Stakeholders[0], &down))
+		require.Equal(t, stakeholderServer.URL, down.Domain)
 	})
 
 	t.Run("failure - verifying stakeholder", func(t *testing.T) {
@@ -755,6 +1157,14 @@ func TestVDRI_ValidateConsortium(t *testing.T) {
 		_, err = v.ValidateConsortium(consortiumServer.URL)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "stakeholder error")
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+		require.Len(t, insufficient.Stakeholders, 1)
+
+		var invalidSignature *models.InvalidStakeholderSignature
+		require.True(t, errors.As(insufficient.Stakeholders[0], &invalidSignature))
+		require.Equal(t, stakeholderServer.URL, invalidSignature.Domain)
 	})
 }
 
@@ -824,11 +1234,22 @@ func Test_verifyStakeholder(t *testing.T) {
 				},
 			}
 
-			err = v.verifyStakeholder(cfd, sfd)
+			report := &StakeholderReport{}
+
+			err = v.verifyStakeholder(context.Background(), cfd, sfd, report)
+			require.True(t, report.DIDResolved)
+			require.Equal(t, "foo", report.ResolvedVia)
+			require.True(t, report.DIDConfigurationValid)
+			require.Equal(t, test.consortiumKey == sigKey, report.ConsortiumSignatureVerified)
+			require.Equal(t, !test.isErr, report.SelfSignatureVerified)
 
 			if test.isErr {
 				require.Error(t, err)
 				require.Contains(t, err.Error(), test.errString)
+
+				var invalidSignature *models.InvalidStakeholderSignature
+				require.True(t, errors.As(err, &invalidSignature))
+				require.Equal(t, test.stakeholderDomain, invalidSignature.Domain)
 			} else {
 				require.NoError(t, err)
 			}
@@ -836,20 +1257,635 @@ func Test_verifyStakeholder(t *testing.T) {
 	}
 }
 
-func TestVDRI_Close(t *testing.T) {
-	v := New()
-	require.NoError(t, v.Close())
+func TestVDRI_ValidateConsortiumReport(t *testing.T) {
+	sigKey := ed25519SigningKey(t, keyJSON)
+
+	mockDoc, err := did.ParseDocument([]byte(testDoc))
+	require.NoError(t, err)
+
+	consortium := dummyConsortium("consortium.url", "stakeholder.url")
+	consortium.Policy.Cache.MaxAge = 60
+
+	cfd := signedConsortiumFileData(t, consortium, sigKey)
+	sfd := signedStakeholderFileData(t, dummyStakeholder("stakeholder.url"), sigKey)
+
+	newVDRI := func(stakeholderErr error) *VDRI {
+		v := New()
+
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return cfd, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return sfd, stakeholderErr
+			},
+		}
+
+		v.getHTTPVDRI = httpVdriFunc(&did.DocResolution{DIDDocument: mockDoc}, nil)
+
+		v.didConfigService = &mockdidconf.MockDIDConfigService{
+			VerifyStakeholderFunc: func(domain string, doc *did.Doc) error {
+				return nil
+			},
+		}
+
+		return v
+	}
+
+	t.Run("success", func(t *testing.T) {
+		report, err := newVDRI(nil).ValidateConsortiumReport("consortium.url")
+		require.NoError(t, err)
+		require.True(t, report.Valid)
+		require.Empty(t, report.Error)
+		require.Equal(t, "consortium.url", report.Domain)
+		require.Equal(t, 1, report.Required)
+		require.Equal(t, 1, report.Endorsed)
+		require.Equal(t, time.Minute, report.Lifetime)
+		require.Len(t, report.Stakeholders, 1)
+
+		s := report.Stakeholders[0]
+		require.Equal(t, "stakeholder.url", s.Domain)
+		require.True(t, s.ConfigFetched)
+		require.True(t, s.DIDResolved)
+		require.Equal(t, "foo", s.ResolvedVia)
+		require.True(t, s.DIDConfigurationValid)
+		require.True(t, s.ConsortiumSignatureVerified)
+		require.True(t, s.SelfSignatureVerified)
+		require.Empty(t, s.Error)
+	})
+
+	t.Run("failure - stakeholder config unavailable", func(t *testing.T) {
+		report, err := newVDRI(errors.New("stakeholder error")).ValidateConsortiumReport("consortium.url")
+		require.Error(t, err)
+		require.False(t, report.Valid)
+		require.Equal(t, err.Error(), report.Error)
+		require.Equal(t, 0, report.Endorsed)
+		require.Len(t, report.Stakeholders, 1)
+
+		s := report.Stakeholders[0]
+		require.Equal(t, "stakeholder.url", s.Domain)
+		require.False(t, s.ConfigFetched)
+		require.False(t, s.DIDResolved)
+		require.Contains(t, s.Error, "stakeholder error")
+	})
+
+	t.Run("failure - stakeholder DID not resolved", func(t *testing.T) {
+		v := newVDRI(nil)
+		v.getHTTPVDRI = httpVdriFunc(nil, errors.New("resolve error"))
+
+		report, err := v.ValidateConsortiumReport("consortium.url")
+		require.Error(t, err)
+		require.False(t, report.Valid)
+		require.Len(t, report.Stakeholders, 1)
+
+		s := report.Stakeholders[0]
+		require.True(t, s.ConfigFetched)
+		require.False(t, s.DIDResolved)
+		require.False(t, s.DIDConfigurationValid)
+		require.Contains(t, s.Error, "resolve error")
+	})
+
+	t.Run("failure - consortium unavailable", func(t *testing.T) {
+		v := newVDRI(nil)
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return nil, errors.New("consortium error")
+			},
+		}
+
+		report, err := v.ValidateConsortiumReport("consortium.url")
+		require.Error(t, err)
+		require.False(t, report.Valid)
+		require.Contains(t, report.Error, "consortium error")
+		require.Empty(t, report.Stakeholders)
+	})
 }
 
-func Test_canonicalizeDoc(t *testing.T) {
-	var docs = [][2]string{
-		{`{
-  "@context": ["https://w3id.org/did/v1"],
-  "publicKey": [{
-    "id": "did:example:123456789abcdefghi#keys-3",
-    "publicKeyBase58": "H3C2AVvLMv6gmMNam3uVAjZpfkcJCwDwnZn6z3wXmqPV",
-    "type": "Secp256k1VerificationKey2018",
-    "controller": "did:example:123456789abcdefghi"
+func TestVDRI_ConsortiumRevalidation(t *testing.T) {
+	sigKey := ed25519SigningKey(t, keyJSON)
+
+	mockDoc, err := did.ParseDocument([]byte(testDoc))
+	require.NoError(t, err)
+
+	consortium := dummyConsortium("testnet", "stakeholder.url")
+	consortium.Policy.Cache.MaxAge = 60
+
+	cfd := signedConsortiumFileData(t, consortium, sigKey)
+	sfd := signedStakeholderFileData(t, dummyStakeholder("stakeholder.url"), sigKey)
+
+	newVDRI := func(stakeholderErr *error) *VDRI {
+		v := New(EnableSignatureVerification(true))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return cfd, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return sfd, *stakeholderErr
+			},
+		}
+
+		v.endpointService = &mockendpoint.MockEndpointService{
+			GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+				return []*models.Endpoint{{URL: "url", Domain: "stakeholder.url"}}, nil
+			}}
+
+		v.getHTTPVDRI = httpVdriFunc(&did.DocResolution{DIDDocument: mockDoc}, nil)
+
+		v.didConfigService = &mockdidconf.MockDIDConfigService{
+			VerifyStakeholderFunc: func(domain string, doc *did.Doc) error {
+				return nil
+			},
+		}
+
+		v.canonicalize = func(doc *did.Doc) ([]byte, error) {
+			return []byte(doc.ID), nil
+		}
+
+		return v
+	}
+
+	t.Run("test validation expires after consortium cache lifetime", func(t *testing.T) {
+		var stakeholderErr error
+
+		v := newVDRI(&stakeholderErr)
+
+		start := time.Now()
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+
+		cv, ok := v.validatedConsortium["testnet"]
+		require.True(t, ok)
+		require.False(t, cv.expiry.Before(start.Add(time.Minute)))
+		require.False(t, cv.expiry.After(time.Now().Add(time.Minute)))
+
+		// the consortium loses endorsement, but its validation hasn't expired
+		stakeholderErr = errors.New("stakeholder error")
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+
+		// once the validation expires, the consortium is re-validated and stops resolving
+		v.validatedConsortium["testnet"] = validConsortium(-time.Second)
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid consortium")
+
+		var insufficient *models.InsufficientEndorsement
+		require.True(t, errors.As(err, &insufficient))
+
+		require.Error(t, v.validatedConsortium["testnet"].err)
+
+		// and resolves again once it's endorsed again
+		stakeholderErr = nil
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+	})
+
+	t.Run("test zero cache lifetime validates after the minimum lifetime", func(t *testing.T) {
+		var stakeholderErr error
+
+		v := newVDRI(&stakeholderErr)
+
+		consortium.Policy.Cache.MaxAge = 0
+		defer func() { consortium.Policy.Cache.MaxAge = 60 }()
+
+		start := time.Now()
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+
+		cv := v.validatedConsortium["testnet"]
+		require.False(t, cv.expiry.Before(start.Add(minValidationLifetime)))
+
+		stakeholderErr = errors.New("stakeholder error")
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+
+		v.validatedConsortium["testnet"] = validConsortium(-time.Second)
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid consortium")
+	})
+
+	t.Run("test consortium report reuses the last validation", func(t *testing.T) {
+		var stakeholderErr error
+
+		var validations int32
+
+		v := newVDRI(&stakeholderErr)
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				atomic.AddInt32(&validations, 1)
+
+				return cfd, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return sfd, stakeholderErr
+			},
+		}
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			report, e := v.ConsortiumReport("testnet")
+			require.NoError(t, e)
+			require.True(t, report.Valid)
+			require.Equal(t, time.Minute, report.Lifetime)
+		}
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&validations))
+
+		// once the validation expires, the report validates again, and the failure is reported until it expires
+		v.validatedConsortium["testnet"] = validConsortium(-time.Second)
+		stakeholderErr = errors.New("stakeholder error")
+
+		for i := 0; i < 3; i++ {
+			report, e := v.ConsortiumReport("testnet")
+			require.Error(t, e)
+			require.False(t, report.Valid)
+			require.Contains(t, report.Error, "stakeholder error")
+		}
+
+		require.Equal(t, int32(2), atomic.LoadInt32(&validations))
+
+		// a Read doesn't reuse the failure
+		stakeholderErr = nil
+
+		_, err = v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, int32(3), atomic.LoadInt32(&validations))
+
+		report, err := v.ConsortiumReport("testnet")
+		require.NoError(t, err)
+		require.True(t, report.Valid)
+		require.Equal(t, int32(3), atomic.LoadInt32(&validations))
+	})
+
+	t.Run("test expired failure is forgotten", func(t *testing.T) {
+		v := newVDRI(new(error))
+
+		cv := &consortiumValidation{}
+		v.validatedConsortium["testnet"] = cv
+
+		v.forgetConsortiumValidation("testnet", validConsortium(time.Hour))
+		require.Contains(t, v.validatedConsortium, "testnet")
+
+		v.forgetConsortiumValidation("testnet", cv)
+		require.NotContains(t, v.validatedConsortium, "testnet")
+	})
+
+	t.Run("test consortium report of a caller giving up", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		v := newVDRI(new(error))
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				<-release
+
+				return cfd, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return sfd, nil
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report, err := v.ConsortiumReportWithContext(ctx, "testnet")
+		require.True(t, errors.Is(err, context.Canceled))
+		require.False(t, report.Valid)
+		require.Equal(t, "testnet", report.Domain)
+	})
+
+	t.Run("test concurrent re-validation is shared", func(t *testing.T) {
+		var validations int32
+
+		release := make(chan struct{})
+
+		v := newVDRI(new(error))
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				atomic.AddInt32(&validations, 1)
+				<-release
+
+				return cfd, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return sfd, nil
+			},
+		}
+
+		v.validatedConsortium["testnet"] = validConsortium(-time.Second)
+
+		var wg sync.WaitGroup
+
+		errs := make(chan error, 10)
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				errs <- v.ensureConsortiumValidated(context.Background(), "testnet")
+			}()
+		}
+
+		// every caller waits for the validation in progress
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&validations) == 1 }, time.Second,
+			time.Millisecond)
+
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&validations))
+	})
+
+	t.Run("test caller giving up doesn't fail shared validation", func(t *testing.T) {
+		release := make(chan struct{})
+
+		v := newVDRI(new(error))
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				<-release
+
+				return cfd, nil
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				return sfd, nil
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := v.ensureConsortiumValidated(ctx, "testnet")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+
+		close(release)
+
+		require.NoError(t, v.ensureConsortiumValidated(context.Background(), "testnet"))
+	})
+
+	t.Run("test concurrent validation", func(t *testing.T) {
+		var stakeholderErr error
+
+		v := newVDRI(&stakeholderErr)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				if i%2 == 0 {
+					v.validatedLock.Lock()
+					v.validatedConsortium["testnet"] = validConsortium(-time.Second)
+					v.validatedLock.Unlock()
+				}
+
+				require.NoError(t, v.ensureConsortiumValidated(context.Background(), "testnet"))
+			}(i)
+		}
+
+		wg.Wait()
+	})
+}
+
+// validConsortium returns a successful consortium validation expiring after the given lifetime
+func validConsortium(lifetime time.Duration) *consortiumValidation {
+	cv := &consortiumValidation{done: make(chan struct{}), report: &ConsortiumReport{Domain: "testnet", Valid: true},
+		expiry: time.Now().Add(lifetime)}
+	close(cv.done)
+
+	return cv
+}
+
+func Test_verifyStakeholderKey(t *testing.T) {
+	mockDoc, err := did.ParseDocument([]byte(testDoc))
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		err := verifyStakeholderKey(dummyConsortium("consortium.url", "stakeholder.url"), "stakeholder.url", mockDoc)
+		require.NoError(t, err)
+	})
+
+	t.Run("failure - key id missing in DID doc", func(t *testing.T) {
+		consortium := dummyConsortium("consortium.url", "stakeholder.url")
+		consortium.Members[0].PublicKey.ID = "did:example:123456789abcdefghi#missing"
+
+		err := verifyStakeholderKey(consortium, "stakeholder.url", mockDoc)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, didconfiguration.ErrKeyNotFound))
+	})
+
+	t.Run("failure - JWK mismatch", func(t *testing.T) {
+		consortium := dummyConsortium("consortium.url", "stakeholder.url")
+		consortium.Members[0].PublicKey.ID = "did:example:123456789abcdefghi#key-2"
+
+		err := verifyStakeholderKey(consortium, "stakeholder.url", mockDoc)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, didconfiguration.ErrKeyMismatch))
+	})
+
+	t.Run("failure - stakeholder not in consortium", func(t *testing.T) {
+		err := verifyStakeholderKey(dummyConsortium("consortium.url", "stakeholder.url"), "other.url", mockDoc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not a member of the consortium")
+	})
+
+	t.Run("failure - nil consortium", func(t *testing.T) {
+		err := verifyStakeholderKey(nil, "stakeholder.url", mockDoc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "nil config")
+	})
+}
+
+func TestVDRI_TrustedStakeholder(t *testing.T) {
+	sigKey := ed25519SigningKey(t, keyJSON)
+
+	mockDoc, err := did.ParseDocument([]byte(testDoc))
+	require.NoError(t, err)
+
+	sfd := signedStakeholderFileData(t, dummyStakeholder("stakeholder.url"), sigKey)
+
+	newVDRI := func(sfd *models.StakeholderFileData) *VDRI {
+		v := New(WithTrustedStakeholder("stakeholder.url"), EnableSignatureVerification(true))
+
+		v.configService = &mockconfig.MockConfigService{
+			GetConsortiumFunc: func(u string, d string) (*models.ConsortiumFileData, error) {
+				return nil, fmt.Errorf("consortium should not be fetched")
+			},
+			GetStakeholderFunc: func(u string, d string) (*models.StakeholderFileData, error) {
+				require.Equal(t, "stakeholder.url", u)
+				require.Equal(t, "stakeholder.url", d)
+
+				return sfd, nil
+			},
+			GetSidetreeConfigFunc: func(s string) (*models.SidetreeConfig, error) {
+				return &models.SidetreeConfig{MultiHashAlgorithm: 18}, nil
+			},
+		}
+
+		v.getHTTPVDRI = func(url string) (vdri, error) {
+			return &mockvdr.MockVDR{
+				ReadFunc: func(didID string, opts ...resolve.Option) (*did.DocResolution, error) {
+					if didID == sfd.Config.DID {
+						return &did.DocResolution{DIDDocument: mockDoc}, nil
+					}
+
+					return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+				}}, nil
+		}
+
+		v.didConfigService = &mockdidconf.MockDIDConfigService{}
+
+		return v
+	}
+
+	t.Run("success - read", func(t *testing.T) {
+		v := newVDRI(sfd)
+
+		doc, err := v.Read("did:trustbloc:testnet:123")
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:testnet:123", doc.DIDDocument.ID)
+		require.Equal(t, sfd.JWS.FullSerialize(), v.validatedTrustedStakeholder)
+	})
+
+	t.Run("success - build", func(t *testing.T) {
+		v := newVDRI(sfd)
+
+		v.sidetreeClient = &mockSidetreeClient{createDIDValue: &did.DocResolution{DIDDocument: &did.Doc{ID: "did"}}}
+
+		docResolution, err := v.Build(nil, create.WithRecoveryPublicKey("key"))
+		require.NoError(t, err)
+		require.Equal(t, "did", docResolution.DIDDocument.ID)
+	})
+
+	t.Run("success - endpoints", func(t *testing.T) {
+		v := newVDRI(sfd)
+
+		endpoints, err := v.endpointService.GetEndpointsWithContext(context.Background(), "any.domain")
+		require.NoError(t, err)
+		require.Len(t, endpoints, 1)
+		require.Equal(t, "foo", endpoints[0].URL)
+		require.Equal(t, "stakeholder.url", endpoints[0].Domain)
+	})
+
+	t.Run("failure - can't fetch stakeholder", func(t *testing.T) {
+		v := newVDRI(sfd)
+		v.configService.(*mockconfig.MockConfigService).GetStakeholderFunc = func(u, d string) (
+			*models.StakeholderFileData, error) {
+			return nil, fmt.Errorf("stakeholder error")
+		}
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stakeholder error")
+	})
+
+	t.Run("failure - nil stakeholder", func(t *testing.T) {
+		v := newVDRI(&models.StakeholderFileData{})
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "trusted stakeholder has nil config")
+	})
+
+	t.Run("failure - domain mismatch", func(t *testing.T) {
+		v := newVDRI(signedStakeholderFileData(t, dummyStakeholder("other.url"), sigKey))
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match trusted stakeholder")
+	})
+
+	t.Run("failure - no endpoints", func(t *testing.T) {
+		stakeholder := dummyStakeholder("stakeholder.url")
+		stakeholder.Endpoints = nil
+
+		v := newVDRI(signedStakeholderFileData(t, stakeholder, sigKey))
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "has no endpoints")
+	})
+
+	t.Run("failure - can't resolve stakeholder DID", func(t *testing.T) {
+		v := newVDRI(sfd)
+		v.getHTTPVDRI = httpVdriFunc(nil, fmt.Errorf("resolve error"))
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can't resolve stakeholder DID")
+	})
+
+	t.Run("failure - did configuration", func(t *testing.T) {
+		v := newVDRI(sfd)
+		v.didConfigService = &mockdidconf.MockDIDConfigService{
+			VerifyStakeholderFunc: func(domain string, doc *did.Doc) error {
+				return fmt.Errorf("did configuration error")
+			}}
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did configuration error")
+	})
+
+	t.Run("failure - bad self-signature", func(t *testing.T) {
+		alternateKey := ed25519SigningKey(t, `{
+	"kty":"OKP",
+	"crv":"Ed25519",
+	"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+	"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+}`)
+
+		v := newVDRI(signedStakeholderFileData(t, dummyStakeholder("stakeholder.url"), alternateKey))
+
+		_, err := v.Read("did:trustbloc:testnet:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not sign itself")
+	})
+
+	t.Run("genesis files are not used", func(t *testing.T) {
+		v := New(WithTrustedStakeholder("stakeholder.url"), UseGenesisFile("url", "domain", []byte("not a jws")))
+
+		require.Nil(t, v.updateValidationService)
+		require.NoError(t, v.genesisErr)
+	})
+}
+
+func TestVDRI_Close(t *testing.T) {
+	v := New()
+	require.NoError(t, v.Close())
+}
+
+func Test_canonicalizeDoc(t *testing.T) {
+	var docs = [][2]string{
+		{`{
+  "@context": ["https://w3id.org/did/v1"],
+  "publicKey": [{
+    "id": "did:example:123456789abcdefghi#keys-3",
+    "publicKeyBase58": "H3C2AVvLMv6gmMNam3uVAjZpfkcJCwDwnZn6z3wXmqPV",
+    "type": "Secp256k1VerificationKey2018",
+    "controller": "did:example:123456789abcdefghi"
   }],
   "id": "did:example:123456789abcdefghi",
   "authentication": [
@@ -1053,6 +2089,14 @@ func TestOpts(t *testing.T) {
 		require.Equal(t, "tk1", v.authToken)
 	})
 
+	t.Run("test http client opts", func(t *testing.T) {
+		proxyURL, err := url.Parse("http://proxy.example:3128")
+		require.NoError(t, err)
+
+		v := New(WithHTTPHeader("X-Api-Key", "a"), WithUserAgent("resolver/1.0"), WithProxy(proxyURL))
+		require.Len(t, v.httpClientOpts, 3)
+	})
+
 	t.Run("test signature verification", func(t *testing.T) {
 		var opts []Option
 		opts = append(opts, EnableSignatureVerification(true))
@@ -1066,12 +2110,68 @@ func TestOpts(t *testing.T) {
 
 		require.Equal(t, true, v.enableSignatureVerification)
 	})
+
+	t.Run("test consensus policy", func(t *testing.T) {
+		policy, err := consensus.Quorum(2)
+		require.NoError(t, err)
+
+		v := New(WithConsensusPolicy(policy))
+
+		require.Equal(t, "quorum 2", v.consensusPolicy.String())
+	})
+
+	t.Run("test timeouts", func(t *testing.T) {
+		v := New()
+		require.Equal(t, defaultEndpointTimeout, v.endpointTimeout)
+		require.Equal(t, defaultReadTimeout, v.readTimeout)
+
+		v = New(WithEndpointTimeout(time.Second), WithReadTimeout(time.Minute))
+		require.Equal(t, time.Second, v.endpointTimeout)
+		require.Equal(t, time.Minute, v.readTimeout)
+	})
+
+	t.Run("test retry policy", func(t *testing.T) {
+		v := New()
+		require.Equal(t, retry.Default(), v.retryPolicy)
+
+		policy := &retry.Policy{MaxAttempts: 5}
+
+		v = New(WithRetryPolicy(policy))
+		require.Equal(t, policy, v.retryPolicy)
+
+		v = New(WithRetryPolicy(nil))
+		require.Nil(t, v.retryPolicy)
+	})
+
+	t.Run("test cache dir", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "cache")
+
+		v := New(WithCacheDir(dir), WithCacheDirMaxStale(time.Hour))
+		require.Equal(t, dir, v.cacheDir)
+		require.Equal(t, time.Hour, v.cacheDirMaxStale)
+		require.DirExists(t, dir)
+		require.NotNil(t, v.fileCache)
+
+		// an unusable cache directory falls back to caching in memory
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, ioutil.WriteFile(file, []byte("data"), 0600))
+
+		v = New(WithCacheDir(file))
+		require.NotNil(t, v.configService)
+	})
+
+	t.Run("test config cache policy", func(t *testing.T) {
+		v := New(WithStaleWhileRevalidate(time.Hour), WithConfigFailureCaching(time.Minute))
+		require.Equal(t, time.Hour, v.maxStaleConfig)
+		require.Equal(t, time.Minute, v.configFailureTTL)
+	})
 }
 
 type mockSidetreeClient struct {
 	createDIDValue *did.DocResolution
 }
 
-func (m *mockSidetreeClient) CreateDID(opts ...create.Option) (*did.DocResolution, error) {
+func (m *mockSidetreeClient) CreateDIDWithContext(_ context.Context, opts ...create.Option,
+) (*did.DocResolution, error) {
 	return m.createDIDValue, nil
 }